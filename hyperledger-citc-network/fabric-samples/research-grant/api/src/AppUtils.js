@@ -95,4 +95,55 @@ exports.prettyJSONString = (inputString) => {
 	else {
 		 return inputString;
 	}
-}
\ No newline at end of file
+}
+// HTTP status for each chaincode error code.
+const errorStatuses = {
+	NOT_FOUND: 404,
+	UNAUTHENTICATED: 401,
+	FORBIDDEN: 403,
+	INVALID_STATE: 409,
+	BUDGET_EXCEEDED: 422,
+	OUT_OF_PERIOD: 422,
+	DUPLICATE: 409,
+	VALIDATION: 400,
+	MIGRATION_REQUIRED: 409,
+	INTERNAL: 500
+};
+
+// chaincodeError extracts the JSON error the chaincode returned from an error
+// thrown by fabric-network: {code, message, details}. Errors raised before
+// the chaincode ran (connection, discovery, ...) come back as INTERNAL.
+const chaincodeError = (error) => {
+	let message = error.message.split('message=').pop();
+	let start = message.indexOf('{');
+	let end = message.lastIndexOf('}');
+	if (start >= 0 && end > start) {
+		try {
+			let parsed = JSON.parse(message.slice(start, end + 1));
+			if (parsed.code) {
+				return parsed;
+			}
+		} catch (e) {
+			// not a chaincode error
+		}
+	}
+	return { code: 'INTERNAL', message: message };
+};
+
+exports.chaincodeError = chaincodeError;
+
+// errorResponse is the body returned to clients for a failed transaction or query.
+exports.errorResponse = (error) => {
+	let contractError = chaincodeError(error);
+	return {
+		status: 'error',
+		code: contractError.code,
+		message: contractError.message,
+		details: contractError.details || {}
+	};
+};
+
+// errorStatus is the HTTP status for a failed transaction or query.
+exports.errorStatus = (error) => {
+	return errorStatuses[chaincodeError(error).code] || 500;
+};
@@ -55,7 +55,7 @@ exports.enrollAdmin = async (caClient, wallet, orgMspId) => {
 	}
 };
 
-exports.registerAndEnrollUser = async (caClient, wallet, orgMspId, userId, affiliation) => {
+exports.registerAndEnrollUser = async (caClient, wallet, orgMspId, userId, affiliation, role) => {
 	try {
 		// Check to see if we've already enrolled the user
 		const userIdentity = await wallet.get(userId);
@@ -78,10 +78,13 @@ exports.registerAndEnrollUser = async (caClient, wallet, orgMspId, userId, affil
 
 		// Register the user, enroll the user, and import the new identity into the wallet.
 		// if affiliation is specified by client, the affiliation value must be configured in CA
+		// the grant.role attribute goes into the certificate for the chaincode's role checks
+		const attrs = role ? [{ name: 'grant.role', value: role, ecert: true }] : [];
 		const secret = await caClient.register({
 			affiliation: affiliation,
 			enrollmentID: userId,
-			role: 'client'
+			role: 'client',
+			attrs: attrs
 		}, adminUser);
 		const enrollment = await caClient.enroll({
 			enrollmentID: userId,
@@ -5,8 +5,9 @@ app.use(morgan('combined'))
 const bodyparser = require("body-parser");
 require('dotenv').config();
 const { registerUser, userExist } = require("./registerUser");
-const {initiateGrant,assignGrant,acceptGrant,rejectGrant,revokeGrant,updateGrant,requestReimbursement,acceptReimbursement,rejectReimbursement,redeemTokens,acceptRedeem,rejectRedeem,addAwardee,addSubawardee,addProgress,deleteGrant} = require('./tx')
-const {GetGrant,GetAllGrants,GetWallet,GetAllGrantsUser,GetAllApprovedGrants,GetGrantsByStatus,GetRemainingAmount,GetGrantBenefits,GetPayments,GetPaymentByAwardee,GetProgress,MyWallet,GetPaymentByStatus,GetPaymentByStatusForAllGrants,GetMSPIDs} =require('./query')
+const {initiateGrant,assignGrant,acceptGrant,rejectGrant,revokeGrant,updateGrant,requestReimbursement,acceptReimbursement,rejectReimbursement,setApprovalPolicy,requestExtension,approveExtension,denyExtension,proposeAmendment,countersignAmendment,declineAmendment,setRebudgetThreshold,rebudgetRequest,approveRebudget,denyRebudget,updateGrantEndorsement,redeemTokens,acceptRedeem,rejectRedeem,addAwardee,addSubawardee,addProgress,deleteGrant} = require('./tx')
+const {GetGrant,GetAllGrants,GetWallet,GetAllGrantsUser,GetAllApprovedGrants,GetGrantsByStatus,GetRemainingAmount,GetGrantBenefits,GetPayments,GetPaymentByAwardee,GetProgress,GetFunders,GetGrantEndorsement,GetAmendments,GetBudgetAsOf,GetPendingApprovals,MyWallet,QueryPayments,GetMSPIDs,ReadAwardeePrivateDetails,GetGrantHistory,GetPaymentHistory,GetAllGrantsWithPagination,GetAllGrantsUserWithPagination,GetAllApprovedGrantsWithPagination,GetGrantsByStatusWithPagination,QueryPaymentsWithPagination,QueryGrants} =require('./query')
+const { errorResponse, errorStatus } = require('./AppUtils')
 const PORT=process.env.PORT
 
 var cors = require('cors')
@@ -23,7 +24,8 @@ app.post("/register", async (req, res) => {
     try {
         let org = req.body.org[0].toUpperCase() + req.body.org.slice(1);
         let userId = req.body.userId;
-        let result = await registerUser({ OrgMSP: org, userId: userId });
+        let role = req.body.role;
+        let result = await registerUser({ OrgMSP: org, userId: userId, role: role });
         res.send(result);
 
     } catch (error) {
@@ -182,6 +184,7 @@ app.post("/rejectReimbursement", async (req, res) => {
             "userId": req.body.userId,
             "grant_id": req.body.grant_id,
             "payment_id": req.body.payment_id,
+            "code": req.body.code,
             "message": req.body.message
         }
 
@@ -192,6 +195,199 @@ app.post("/rejectReimbursement", async (req, res) => {
     }
 })
 
+app.post("/setApprovalPolicy", async (req, res) => {
+    try {
+        let payload = {
+            "org": req.body.org[0].toUpperCase() + req.body.org.slice(1),
+            "userId": req.body.userId,
+            "grant_id": req.body.grant_id,
+            "policy": req.body.policy
+        }
+
+        let result = await setApprovalPolicy(payload);
+        res.send(result)
+    } catch (error) {
+        res.status(500).send(error)
+    }
+})
+
+app.post("/requestExtension", async (req, res) => {
+    try {
+        let payload = {
+            "org": req.body.org[0].toUpperCase() + req.body.org.slice(1),
+            "userId": req.body.userId,
+            "data": req.body.data
+        }
+
+        let result = await requestExtension(payload);
+        res.send(result)
+    } catch (error) {
+        res.status(500).send(error)
+    }
+})
+
+app.post("/approveExtension", async (req, res) => {
+    try {
+        let payload = {
+            "org": req.body.org[0].toUpperCase() + req.body.org.slice(1),
+            "userId": req.body.userId,
+            "grant_id": req.body.grant_id,
+            "number": req.body.number,
+            "notes": req.body.notes
+        }
+
+        let result = await approveExtension(payload);
+        res.send(result)
+    } catch (error) {
+        res.status(500).send(error)
+    }
+})
+
+app.post("/denyExtension", async (req, res) => {
+    try {
+        let payload = {
+            "org": req.body.org[0].toUpperCase() + req.body.org.slice(1),
+            "userId": req.body.userId,
+            "grant_id": req.body.grant_id,
+            "number": req.body.number,
+            "reason": req.body.reason
+        }
+
+        let result = await denyExtension(payload);
+        res.send(result)
+    } catch (error) {
+        res.status(500).send(error)
+    }
+})
+
+app.post("/proposeAmendment", async (req, res) => {
+    try {
+        let payload = {
+            "org": req.body.org[0].toUpperCase() + req.body.org.slice(1),
+            "userId": req.body.userId,
+            "data": req.body.data
+        }
+
+        let result = await proposeAmendment(payload);
+        res.send(result)
+    } catch (error) {
+        res.status(500).send(error)
+    }
+})
+
+app.post("/countersignAmendment", async (req, res) => {
+    try {
+        let payload = {
+            "org": req.body.org[0].toUpperCase() + req.body.org.slice(1),
+            "userId": req.body.userId,
+            "grant_id": req.body.grant_id,
+            "number": req.body.number
+        }
+
+        let result = await countersignAmendment(payload);
+        res.send(result)
+    } catch (error) {
+        res.status(500).send(error)
+    }
+})
+
+app.post("/declineAmendment", async (req, res) => {
+    try {
+        let payload = {
+            "org": req.body.org[0].toUpperCase() + req.body.org.slice(1),
+            "userId": req.body.userId,
+            "grant_id": req.body.grant_id,
+            "number": req.body.number,
+            "reason": req.body.reason
+        }
+
+        let result = await declineAmendment(payload);
+        res.send(result)
+    } catch (error) {
+        res.status(500).send(error)
+    }
+})
+
+app.post("/setRebudgetThreshold", async (req, res) => {
+    try {
+        let payload = {
+            "org": req.body.org[0].toUpperCase() + req.body.org.slice(1),
+            "userId": req.body.userId,
+            "grant_id": req.body.grant_id,
+            "threshold": req.body.threshold
+        }
+
+        let result = await setRebudgetThreshold(payload);
+        res.send(result)
+    } catch (error) {
+        res.status(500).send(error)
+    }
+})
+
+app.post("/rebudgetRequest", async (req, res) => {
+    try {
+        let payload = {
+            "org": req.body.org[0].toUpperCase() + req.body.org.slice(1),
+            "userId": req.body.userId,
+            "data": req.body.data
+        }
+
+        let result = await rebudgetRequest(payload);
+        res.send(result)
+    } catch (error) {
+        res.status(500).send(error)
+    }
+})
+
+app.post("/approveRebudget", async (req, res) => {
+    try {
+        let payload = {
+            "org": req.body.org[0].toUpperCase() + req.body.org.slice(1),
+            "userId": req.body.userId,
+            "grant_id": req.body.grant_id,
+            "number": req.body.number,
+            "notes": req.body.notes
+        }
+
+        let result = await approveRebudget(payload);
+        res.send(result)
+    } catch (error) {
+        res.status(500).send(error)
+    }
+})
+
+app.post("/denyRebudget", async (req, res) => {
+    try {
+        let payload = {
+            "org": req.body.org[0].toUpperCase() + req.body.org.slice(1),
+            "userId": req.body.userId,
+            "grant_id": req.body.grant_id,
+            "number": req.body.number,
+            "reason": req.body.reason
+        }
+
+        let result = await denyRebudget(payload);
+        res.send(result)
+    } catch (error) {
+        res.status(500).send(error)
+    }
+})
+
+app.post("/updateGrantEndorsement", async (req, res) => {
+    try {
+        let payload = {
+            "org": req.body.org[0].toUpperCase() + req.body.org.slice(1),
+            "userId": req.body.userId,
+            "grant_id": req.body.grant_id
+        }
+
+        let result = await updateGrantEndorsement(payload);
+        res.send(result)
+    } catch (error) {
+        res.status(500).send(error)
+    }
+})
+
 app.post("/redeemTokens", async (req, res) => {
     try {
 
@@ -235,6 +431,7 @@ app.post("/rejectRedeem", async (req, res) => {
             "userId": req.body.userId,
             "grant_id": req.body.grant_id,
             "payment_id": req.body.payment_id,
+            "code": req.body.code,
             "message": req.body.message
         }
 
@@ -323,7 +520,7 @@ app.get('/getWallet', async (req, res) => {
         let result = await GetWallet(payload);
         res.json(result)
     } catch (error) {
-        res.send(error)
+        res.status(errorStatus(error)).send(errorResponse(error))
     }
 });
 
@@ -340,7 +537,7 @@ app.get('/myWallet', async (req, res) => {
         let result = await MyWallet(payload);
         res.json(result)
     } catch (error) {
-        res.send(error)
+        res.status(errorStatus(error)).send(errorResponse(error))
     }
 });
 
@@ -357,7 +554,7 @@ app.get('/getGrant', async (req, res) => {
         let result = await GetGrant(payload);
         res.json(result)
     } catch (error) {
-        res.send(error)
+        res.status(errorStatus(error)).send(errorResponse(error))
     }
 });
 
@@ -375,7 +572,7 @@ app.get('/getAllGrants', async (req, res) => {
         let result = await GetAllGrants(payload);
         res.json(result)
     } catch (error) {
-        res.send(error)
+        res.status(errorStatus(error)).send(errorResponse(error))
     }
 });
 
@@ -391,7 +588,7 @@ app.get('/getAllGrantsUser', async (req, res) => {
         let result = await GetAllGrantsUser(payload);
         res.json(result)
     } catch (error) {
-        res.send(error)
+        res.status(errorStatus(error)).send(errorResponse(error))
     }
 });
 
@@ -407,7 +604,7 @@ app.get('/getAllApprovedGrants', async (req, res) => {
         let result = await GetAllApprovedGrants(payload);
         res.json(result)
     } catch (error) {
-        res.send(error)
+        res.status(errorStatus(error)).send(errorResponse(error))
     }
 });
 
@@ -424,26 +621,7 @@ app.get('/getGrantsByStatus', async (req, res) => {
         let result = await GetGrantsByStatus(payload);
         res.json(result)
     } catch (error) {
-        res.send(error)
-    }
-});
-
-app.get('/getPaymentByStatus', async (req, res) => {
-   
-    try {
-
-
-        let payload = {
-            "org": req.body.org[0].toUpperCase() + req.body.org.slice(1),
-            "userId": req.body.userId,
-            "status": req.body.status
-        }
-
-        let result = await GetPaymentByStatus(payload);
-        res.json(result)
-        
-    } catch (error) {
-        res.send(error)
+        res.status(errorStatus(error)).send(errorResponse(error))
     }
 });
 
@@ -463,24 +641,30 @@ app.get('/getPaymentByAwardee', async (req, res) => {
         res.json(result)
         
     } catch (error) {
-        res.send(error)
+        res.status(errorStatus(error)).send(errorResponse(error))
     }
 });
 
-app.get('/getPaymentByStatusForAllGrants', async (req, res) => {
+app.get('/queryPayments', async (req, res) => {
     try {
 
 
         let payload = {
             "org": req.body.org[0].toUpperCase() + req.body.org.slice(1),
             "userId": req.body.userId,
-            "status": req.body.status
+            "filter": {
+                "statuses": [].concat(req.body.statuses || []),
+                "awardee_id": req.body.awardeeId || "",
+                "from": req.body.from || "",
+                "to": req.body.to || "",
+                "grant_ids": [].concat(req.body.grantIds || [])
+            }
         }
 
-        let result = await GetPaymentByStatusForAllGrants(payload);
+        let result = await QueryPayments(payload);
         res.json(result)
     } catch (error) {
-        res.send(error)
+        res.status(errorStatus(error)).send(errorResponse(error))
     }
 });
 
@@ -497,7 +681,7 @@ app.get('/getRemainingAmount', async (req, res) => {
         let result = await GetRemainingAmount(payload);
         res.json(result)
     } catch (error) {
-        res.send(error)
+        res.status(errorStatus(error)).send(errorResponse(error))
     }
 });
 
@@ -514,7 +698,7 @@ app.get('/getGrantBenefits', async (req, res) => {
         let result = await GetGrantBenefits(payload);
         res.json(result)
     } catch (error) {
-        res.send(error)
+        res.status(errorStatus(error)).send(errorResponse(error))
     }
 });
 
@@ -531,7 +715,7 @@ app.get('/getPayments', async (req, res) => {
         let result = await GetPayments(payload);
         res.json(result)
     } catch (error) {
-        res.send(error)
+        res.status(errorStatus(error)).send(errorResponse(error))
     }
 });
 
@@ -548,7 +732,252 @@ app.get('/getProgress', async (req, res) => {
         let result = await GetProgress(payload);
         res.json(result)
     } catch (error) {
-        res.send(error)
+        res.status(errorStatus(error)).send(errorResponse(error))
+    }
+});
+
+app.get('/getFunders', async (req, res) => {
+    try {
+        let payload = {
+            "org": req.query.org[0].toUpperCase() + req.query.org.slice(1),
+            "userId": req.query.userId,
+            "grantId": req.query.grantId
+        }
+
+        let result = await GetFunders(payload);
+        res.json(result)
+    } catch (error) {
+        res.status(errorStatus(error)).send(errorResponse(error))
+    }
+});
+
+app.get('/getGrantEndorsement', async (req, res) => {
+    try {
+        let payload = {
+            "org": req.query.org[0].toUpperCase() + req.query.org.slice(1),
+            "userId": req.query.userId,
+            "grantId": req.query.grantId
+        }
+
+        let result = await GetGrantEndorsement(payload);
+        res.json(result)
+    } catch (error) {
+        res.status(errorStatus(error)).send(errorResponse(error))
+    }
+});
+
+app.get('/getAmendments', async (req, res) => {
+    try {
+        let payload = {
+            "org": req.query.org[0].toUpperCase() + req.query.org.slice(1),
+            "userId": req.query.userId,
+            "grantId": req.query.grantId
+        }
+
+        let result = await GetAmendments(payload);
+        res.json(result)
+    } catch (error) {
+        res.status(errorStatus(error)).send(errorResponse(error))
+    }
+});
+
+app.get('/getBudgetAsOf', async (req, res) => {
+    try {
+        let payload = {
+            "org": req.query.org[0].toUpperCase() + req.query.org.slice(1),
+            "userId": req.query.userId,
+            "grantId": req.query.grantId,
+            "version": req.query.version
+        }
+
+        let result = await GetBudgetAsOf(payload);
+        res.json(result)
+    } catch (error) {
+        res.status(errorStatus(error)).send(errorResponse(error))
+    }
+});
+
+app.get('/getPendingApprovals', async (req, res) => {
+    try {
+        let payload = {
+            "org": req.query.org[0].toUpperCase() + req.query.org.slice(1),
+            "userId": req.query.userId
+        }
+
+        let result = await GetPendingApprovals(payload);
+        res.json(result)
+    } catch (error) {
+        res.status(errorStatus(error)).send(errorResponse(error))
+    }
+});
+
+app.get('/readAwardeePrivateDetails', async (req, res) => {
+    try {
+
+
+        let payload = {
+            "org": req.query.org[0].toUpperCase() + req.query.org.slice(1),
+            "userId": req.query.userId,
+            "grantId": req.query.grantId,
+            "awardeeId": req.query.awardeeId
+        }
+
+        let result = await ReadAwardeePrivateDetails(payload);
+        res.json(result)
+    } catch (error) {
+        res.status(errorStatus(error)).send(errorResponse(error))
+    }
+});
+
+app.get('/getGrantHistory', async (req, res) => {
+    try {
+
+
+        let payload = {
+            "org": req.query.org[0].toUpperCase() + req.query.org.slice(1),
+            "userId": req.query.userId,
+            "grantId": req.query.grantId
+        }
+
+        let result = await GetGrantHistory(payload);
+        res.json(result)
+    } catch (error) {
+        res.status(errorStatus(error)).send(errorResponse(error))
+    }
+});
+
+app.get('/getPaymentHistory', async (req, res) => {
+    try {
+
+
+        let payload = {
+            "org": req.query.org[0].toUpperCase() + req.query.org.slice(1),
+            "userId": req.query.userId,
+            "grantId": req.query.grantId,
+            "paymentId": req.query.paymentId
+        }
+
+        let result = await GetPaymentHistory(payload);
+        res.json(result)
+    } catch (error) {
+        res.status(errorStatus(error)).send(errorResponse(error))
+    }
+});
+
+app.get('/getAllGrantsWithPagination', async (req, res) => {
+    try {
+
+
+        let payload = {
+            "org": req.query.org[0].toUpperCase() + req.query.org.slice(1),
+            "userId": req.query.userId,
+            "pageSize": req.query.pageSize,
+            "bookmark": req.query.bookmark || ""
+        }
+
+        let result = await GetAllGrantsWithPagination(payload);
+        res.json(result)
+    } catch (error) {
+        res.status(errorStatus(error)).send(errorResponse(error))
+    }
+});
+
+app.get('/getAllGrantsUserWithPagination', async (req, res) => {
+    try {
+
+
+        let payload = {
+            "org": req.query.org[0].toUpperCase() + req.query.org.slice(1),
+            "userId": req.query.userId,
+            "pageSize": req.query.pageSize,
+            "bookmark": req.query.bookmark || ""
+        }
+
+        let result = await GetAllGrantsUserWithPagination(payload);
+        res.json(result)
+    } catch (error) {
+        res.status(errorStatus(error)).send(errorResponse(error))
+    }
+});
+
+app.get('/getAllApprovedGrantsWithPagination', async (req, res) => {
+    try {
+
+
+        let payload = {
+            "org": req.query.org[0].toUpperCase() + req.query.org.slice(1),
+            "userId": req.query.userId,
+            "pageSize": req.query.pageSize,
+            "bookmark": req.query.bookmark || ""
+        }
+
+        let result = await GetAllApprovedGrantsWithPagination(payload);
+        res.json(result)
+    } catch (error) {
+        res.status(errorStatus(error)).send(errorResponse(error))
+    }
+});
+
+app.get('/getGrantsByStatusWithPagination', async (req, res) => {
+    try {
+
+
+        let payload = {
+            "org": req.query.org[0].toUpperCase() + req.query.org.slice(1),
+            "userId": req.query.userId,
+            "status": req.query.status,
+            "pageSize": req.query.pageSize,
+            "bookmark": req.query.bookmark || ""
+        }
+
+        let result = await GetGrantsByStatusWithPagination(payload);
+        res.json(result)
+    } catch (error) {
+        res.status(errorStatus(error)).send(errorResponse(error))
+    }
+});
+
+app.get('/queryPaymentsWithPagination', async (req, res) => {
+    try {
+
+
+        let payload = {
+            "org": req.query.org[0].toUpperCase() + req.query.org.slice(1),
+            "userId": req.query.userId,
+            "filter": {
+                "statuses": [].concat(req.query.statuses || []),
+                "awardee_id": req.query.awardeeId || "",
+                "from": req.query.from || "",
+                "to": req.query.to || "",
+                "grant_ids": [].concat(req.query.grantIds || [])
+            },
+            "pageSize": req.query.pageSize,
+            "bookmark": req.query.bookmark || ""
+        }
+
+        let result = await QueryPaymentsWithPagination(payload);
+        res.json(result)
+    } catch (error) {
+        res.status(errorStatus(error)).send(errorResponse(error))
+    }
+});
+
+app.get('/queryGrants', async (req, res) => {
+    try {
+
+
+        let payload = {
+            "org": req.query.org[0].toUpperCase() + req.query.org.slice(1),
+            "userId": req.query.userId,
+            "selector": req.query.selector,
+            "pageSize": req.query.pageSize,
+            "bookmark": req.query.bookmark || ""
+        }
+
+        let result = await QueryGrants(payload);
+        res.json(result)
+    } catch (error) {
+        res.status(errorStatus(error)).send(errorResponse(error))
     }
 });
 
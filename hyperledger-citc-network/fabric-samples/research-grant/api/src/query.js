@@ -178,7 +178,31 @@ exports.GetGrantsByStatus = async (request) => {
     return JSON.parse(result);
 }
 
-exports.GetPaymentByStatusForAllGrants = async (request) => {
+exports.GetRemainingAmount = async (request) => {
+    let org = request.org;
+    const walletPath = path.join(__dirname,`wallet/${org}`)
+    const ccp = getCCP(org);
+    const wallet = await buildWallet(Wallets, walletPath);
+
+    const gateway = new Gateway();
+
+    await gateway.connect(ccp, {
+        wallet,
+        identity: request.userId,
+        discovery: { enabled: true, asLocalhost: true } // using asLocalhost as this gateway is using a fabric network deployed locally
+    });
+
+    // Build a network instance based on the channel where the smart contract is deployed
+    const network = await gateway.getNetwork(channelName);
+
+    // Get the contract from the network.
+    const contract = network.getContract(chaincodeName);
+    let result = await contract.evaluateTransaction("GetRemainingAmount", request.grant_id);
+    return JSON.parse(result);
+}
+
+
+exports.GetGrantBenefits = async (request) => {
     let org = request.org;
     const walletPath = path.join(__dirname,`wallet/${org}`)
     const ccp = getCCP(org);
@@ -199,15 +223,40 @@ exports.GetPaymentByStatusForAllGrants = async (request) => {
     // Get the contract from the network.
     const contract = network.getContract(chaincodeName);
 
-    let result = await contract.evaluateTransaction("GetPaymentByStatusForAllGrants", request.status);
+    let result = await contract.evaluateTransaction("GetGrantBenefits", request.grantId);
     return JSON.parse(result);
 }
 
+exports.GetPayments = async (request) => {
+    let org = request.org;
+    const walletPath = path.join(__dirname,`wallet/${org}`)
+    const ccp = getCCP(org);
 
-exports.GetRemainingAmount = async (request) => {
+    const wallet = await buildWallet(Wallets, walletPath);
+
+    const gateway = new Gateway();
+
+    await gateway.connect(ccp, {
+        wallet,
+        identity: request.userId,
+        discovery: { enabled: true, asLocalhost: true } // using asLocalhost as this gateway is using a fabric network deployed locally
+    });
+
+    // Build a network instance based on the channel where the smart contract is deployed
+    const network = await gateway.getNetwork(channelName);
+
+    // Get the contract from the network.
+    const contract = network.getContract(chaincodeName);
+
+    let result = await contract.evaluateTransaction("GetPayments", request.grantId);
+    return JSON.parse(result);
+}
+
+exports.GetPaymentByAwardee = async (request) => {
     let org = request.org;
     const walletPath = path.join(__dirname,`wallet/${org}`)
     const ccp = getCCP(org);
+
     const wallet = await buildWallet(Wallets, walletPath);
 
     const gateway = new Gateway();
@@ -223,12 +272,38 @@ exports.GetRemainingAmount = async (request) => {
 
     // Get the contract from the network.
     const contract = network.getContract(chaincodeName);
-    let result = await contract.evaluateTransaction("GetRemainingAmount", request.grant_id);
+    let result = await contract.evaluateTransaction("GetPaymentByAwardee", request.grant_id, request.awardee_id);
     return JSON.parse(result);
+    
 }
 
+exports.QueryPayments = async (request) => {
+    let org = request.org;
+    const walletPath = path.join(__dirname,`wallet/${org}`)
+    const ccp = getCCP(org);
+
+    const wallet = await buildWallet(Wallets, walletPath);
 
-exports.GetGrantBenefits = async (request) => {
+    const gateway = new Gateway();
+
+    await gateway.connect(ccp, {
+        wallet,
+        identity: request.userId,
+        discovery: { enabled: true, asLocalhost: true } // using asLocalhost as this gateway is using a fabric network deployed locally
+    });
+
+    // Build a network instance based on the channel where the smart contract is deployed
+    const network = await gateway.getNetwork(channelName);
+
+    // Get the contract from the network.
+    const contract = network.getContract(chaincodeName);
+    let filter = JSON.stringify(request.filter)
+    let result = await contract.evaluateTransaction("QueryPayments", filter);
+    return JSON.parse(result);
+    
+}
+
+exports.GetProgress = async (request) => {
     let org = request.org;
     const walletPath = path.join(__dirname,`wallet/${org}`)
     const ccp = getCCP(org);
@@ -249,11 +324,11 @@ exports.GetGrantBenefits = async (request) => {
     // Get the contract from the network.
     const contract = network.getContract(chaincodeName);
 
-    let result = await contract.evaluateTransaction("GetGrantBenefits", request.grantId);
+    let result = await contract.evaluateTransaction("GetProgress", request.grantId);
     return JSON.parse(result);
 }
 
-exports.GetPayments = async (request) => {
+exports.GetFunders = async (request) => {
     let org = request.org;
     const walletPath = path.join(__dirname,`wallet/${org}`)
     const ccp = getCCP(org);
@@ -274,11 +349,11 @@ exports.GetPayments = async (request) => {
     // Get the contract from the network.
     const contract = network.getContract(chaincodeName);
 
-    let result = await contract.evaluateTransaction("GetPayments", request.grantId);
+    let result = await contract.evaluateTransaction("GetFunders", request.grantId);
     return JSON.parse(result);
 }
 
-exports.GetPaymentByStatus = async (request) => {
+exports.GetGrantEndorsement = async (request) => {
     let org = request.org;
     const walletPath = path.join(__dirname,`wallet/${org}`)
     const ccp = getCCP(org);
@@ -298,13 +373,12 @@ exports.GetPaymentByStatus = async (request) => {
 
     // Get the contract from the network.
     const contract = network.getContract(chaincodeName);
-    let status = JSON.stringify(request.status)
-    let result = await contract.evaluateTransaction("GetPaymentByStatus", status);
+
+    let result = await contract.evaluateTransaction("GetGrantEndorsement", request.grantId);
     return JSON.parse(result);
-    
 }
 
-exports.GetPaymentByAwardee = async (request) => {
+exports.GetAmendments = async (request) => {
     let org = request.org;
     const walletPath = path.join(__dirname,`wallet/${org}`)
     const ccp = getCCP(org);
@@ -324,12 +398,12 @@ exports.GetPaymentByAwardee = async (request) => {
 
     // Get the contract from the network.
     const contract = network.getContract(chaincodeName);
-    let result = await contract.evaluateTransaction("GetPaymentByAwardee", request.grant_id, request.awardee_id);
+
+    let result = await contract.evaluateTransaction("GetAmendments", request.grantId);
     return JSON.parse(result);
-    
 }
 
-exports.GetPaymentByStatusForAllGrants = async (request) => {
+exports.GetBudgetAsOf = async (request) => {
     let org = request.org;
     const walletPath = path.join(__dirname,`wallet/${org}`)
     const ccp = getCCP(org);
@@ -349,13 +423,12 @@ exports.GetPaymentByStatusForAllGrants = async (request) => {
 
     // Get the contract from the network.
     const contract = network.getContract(chaincodeName);
-    let status = JSON.stringify(request.status)
-    let result = await contract.evaluateTransaction("GetPaymentByStatusForAllGrants", status);
+
+    let result = await contract.evaluateTransaction("GetBudgetAsOf", request.grantId, String(request.version));
     return JSON.parse(result);
-    
 }
 
-exports.GetProgress = async (request) => {
+exports.GetPendingApprovals = async (request) => {
     let org = request.org;
     const walletPath = path.join(__dirname,`wallet/${org}`)
     const ccp = getCCP(org);
@@ -376,7 +449,7 @@ exports.GetProgress = async (request) => {
     // Get the contract from the network.
     const contract = network.getContract(chaincodeName);
 
-    let result = await contract.evaluateTransaction("GetProgress", request.grantId);
+    let result = await contract.evaluateTransaction("GetPendingApprovals");
     return JSON.parse(result);
 }
 
@@ -401,4 +474,229 @@ exports.GetMSPIDs = async (request) => {
     const channel = network.getChannel()
     const result = channel.getMspids()
     return result;
-}
\ No newline at end of file
+}
+
+exports.ReadAwardeePrivateDetails = async (request) => {
+    let org = request.org;
+    const walletPath = path.join(__dirname,`wallet/${org}`)
+    const ccp = getCCP(org);
+
+    const wallet = await buildWallet(Wallets, walletPath);
+
+    const gateway = new Gateway();
+
+    await gateway.connect(ccp, {
+        wallet,
+        identity: request.userId,
+        discovery: { enabled: true, asLocalhost: true } // using asLocalhost as this gateway is using a fabric network deployed locally
+    });
+
+    // Build a network instance based on the channel where the smart contract is deployed
+    const network = await gateway.getNetwork(channelName);
+
+    // Get the contract from the network.
+    const contract = network.getContract(chaincodeName);
+
+    let result = await contract.evaluateTransaction("ReadAwardeePrivateDetails", request.grantId, request.awardeeId);
+    return JSON.parse(result);
+}
+
+exports.GetGrantHistory = async (request) => {
+    let org = request.org;
+    const walletPath = path.join(__dirname,`wallet/${org}`)
+    const ccp = getCCP(org);
+
+    const wallet = await buildWallet(Wallets, walletPath);
+
+    const gateway = new Gateway();
+
+    await gateway.connect(ccp, {
+        wallet,
+        identity: request.userId,
+        discovery: { enabled: true, asLocalhost: true } // using asLocalhost as this gateway is using a fabric network deployed locally
+    });
+
+    // Build a network instance based on the channel where the smart contract is deployed
+    const network = await gateway.getNetwork(channelName);
+
+    // Get the contract from the network.
+    const contract = network.getContract(chaincodeName);
+
+    let result = await contract.evaluateTransaction("GetGrantHistory", request.grantId);
+    return JSON.parse(result);
+}
+
+exports.GetPaymentHistory = async (request) => {
+    let org = request.org;
+    const walletPath = path.join(__dirname,`wallet/${org}`)
+    const ccp = getCCP(org);
+
+    const wallet = await buildWallet(Wallets, walletPath);
+
+    const gateway = new Gateway();
+
+    await gateway.connect(ccp, {
+        wallet,
+        identity: request.userId,
+        discovery: { enabled: true, asLocalhost: true } // using asLocalhost as this gateway is using a fabric network deployed locally
+    });
+
+    // Build a network instance based on the channel where the smart contract is deployed
+    const network = await gateway.getNetwork(channelName);
+
+    // Get the contract from the network.
+    const contract = network.getContract(chaincodeName);
+
+    let result = await contract.evaluateTransaction("GetPaymentHistory", request.grantId, request.paymentId);
+    return JSON.parse(result);
+}
+
+exports.GetAllGrantsWithPagination = async (request) => {
+    let org = request.org;
+    const walletPath = path.join(__dirname,`wallet/${org}`)
+    const ccp = getCCP(org);
+
+    const wallet = await buildWallet(Wallets, walletPath);
+
+    const gateway = new Gateway();
+
+    await gateway.connect(ccp, {
+        wallet,
+        identity: request.userId,
+        discovery: { enabled: true, asLocalhost: true } // using asLocalhost as this gateway is using a fabric network deployed locally
+    });
+
+    // Build a network instance based on the channel where the smart contract is deployed
+    const network = await gateway.getNetwork(channelName);
+
+    // Get the contract from the network.
+    const contract = network.getContract(chaincodeName);
+
+    let result = await contract.evaluateTransaction("GetAllGrantsWithPagination", request.pageSize, request.bookmark);
+    return JSON.parse(result);
+}
+
+exports.GetAllGrantsUserWithPagination = async (request) => {
+    let org = request.org;
+    const walletPath = path.join(__dirname,`wallet/${org}`)
+    const ccp = getCCP(org);
+
+    const wallet = await buildWallet(Wallets, walletPath);
+
+    const gateway = new Gateway();
+
+    await gateway.connect(ccp, {
+        wallet,
+        identity: request.userId,
+        discovery: { enabled: true, asLocalhost: true } // using asLocalhost as this gateway is using a fabric network deployed locally
+    });
+
+    // Build a network instance based on the channel where the smart contract is deployed
+    const network = await gateway.getNetwork(channelName);
+
+    // Get the contract from the network.
+    const contract = network.getContract(chaincodeName);
+
+    let result = await contract.evaluateTransaction("GetAllGrantsUserWithPagination", request.pageSize, request.bookmark);
+    return JSON.parse(result);
+}
+
+exports.GetAllApprovedGrantsWithPagination = async (request) => {
+    let org = request.org;
+    const walletPath = path.join(__dirname,`wallet/${org}`)
+    const ccp = getCCP(org);
+
+    const wallet = await buildWallet(Wallets, walletPath);
+
+    const gateway = new Gateway();
+
+    await gateway.connect(ccp, {
+        wallet,
+        identity: request.userId,
+        discovery: { enabled: true, asLocalhost: true } // using asLocalhost as this gateway is using a fabric network deployed locally
+    });
+
+    // Build a network instance based on the channel where the smart contract is deployed
+    const network = await gateway.getNetwork(channelName);
+
+    // Get the contract from the network.
+    const contract = network.getContract(chaincodeName);
+
+    let result = await contract.evaluateTransaction("GetAllApprovedGrantsWithPagination", request.pageSize, request.bookmark);
+    return JSON.parse(result);
+}
+
+exports.GetGrantsByStatusWithPagination = async (request) => {
+    let org = request.org;
+    const walletPath = path.join(__dirname,`wallet/${org}`)
+    const ccp = getCCP(org);
+
+    const wallet = await buildWallet(Wallets, walletPath);
+
+    const gateway = new Gateway();
+
+    await gateway.connect(ccp, {
+        wallet,
+        identity: request.userId,
+        discovery: { enabled: true, asLocalhost: true } // using asLocalhost as this gateway is using a fabric network deployed locally
+    });
+
+    // Build a network instance based on the channel where the smart contract is deployed
+    const network = await gateway.getNetwork(channelName);
+
+    // Get the contract from the network.
+    const contract = network.getContract(chaincodeName);
+
+    let result = await contract.evaluateTransaction("GetGrantsByStatusWithPagination", request.status, request.pageSize, request.bookmark);
+    return JSON.parse(result);
+}
+
+exports.QueryPaymentsWithPagination = async (request) => {
+    let org = request.org;
+    const walletPath = path.join(__dirname,`wallet/${org}`)
+    const ccp = getCCP(org);
+
+    const wallet = await buildWallet(Wallets, walletPath);
+
+    const gateway = new Gateway();
+
+    await gateway.connect(ccp, {
+        wallet,
+        identity: request.userId,
+        discovery: { enabled: true, asLocalhost: true } // using asLocalhost as this gateway is using a fabric network deployed locally
+    });
+
+    // Build a network instance based on the channel where the smart contract is deployed
+    const network = await gateway.getNetwork(channelName);
+
+    // Get the contract from the network.
+    const contract = network.getContract(chaincodeName);
+    let filter = JSON.stringify(request.filter)
+    let result = await contract.evaluateTransaction("QueryPaymentsWithPagination", filter, request.pageSize, request.bookmark);
+    return JSON.parse(result);
+}
+
+exports.QueryGrants = async (request) => {
+    let org = request.org;
+    const walletPath = path.join(__dirname,`wallet/${org}`)
+    const ccp = getCCP(org);
+
+    const wallet = await buildWallet(Wallets, walletPath);
+
+    const gateway = new Gateway();
+
+    await gateway.connect(ccp, {
+        wallet,
+        identity: request.userId,
+        discovery: { enabled: true, asLocalhost: true } // using asLocalhost as this gateway is using a fabric network deployed locally
+    });
+
+    // Build a network instance based on the channel where the smart contract is deployed
+    const network = await gateway.getNetwork(channelName);
+
+    // Get the contract from the network.
+    const contract = network.getContract(chaincodeName);
+
+    let result = await contract.evaluateTransaction("QueryGrants", request.selector, request.pageSize, request.bookmark);
+    return JSON.parse(result);
+}
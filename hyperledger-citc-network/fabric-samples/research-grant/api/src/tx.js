@@ -1,12 +1,24 @@
 const { getCCP } = require("./buildCCP");
 const { Wallets, Gateway } = require('fabric-network');
 const path = require("path");
-const {buildWallet} =require('./AppUtils');
+const crypto = require("crypto");
+const {buildWallet, errorResponse} =require('./AppUtils');
 
 const chaincodeName = process.env.chaincodeName;
 const channelName = process.env.channelName
 
 let gateway
+
+// grantTransaction creates a transaction on a grant endorsed by the orgs its
+// key-level endorsement policy names, which discovery alone doesn't know about.
+const grantTransaction = async (contract, name, grantId) => {
+    let transaction = contract.createTransaction(name);
+    let endorsement = JSON.parse(await contract.evaluateTransaction('GetGrantEndorsement', grantId));
+    if (endorsement.orgs.length > 0) {
+        transaction.setEndorsingOrganizations(...endorsement.orgs);
+    }
+    return transaction;
+}
 /*
 {
     org:Org1MSP,
@@ -60,11 +72,7 @@ exports.initiateGrant = async (request) => {
     
         } catch (error) {
             console.log(`   Successfully caught the error: \n    ${error}`);
-            const response = {
-                status: 'error',
-                message: error.message.split('message=').pop()
-            }
-            return (response)
+            return errorResponse(error)
             
         } 
     } finally {
@@ -99,11 +107,12 @@ exports.assignGrant = async (request) => {
         const contract = network.getContract(chaincodeName);
     
         try {
-            let statefulTxn = contract.createTransaction('AssignGrant');
             let data=request.data;
+            let statefulTxn = await grantTransaction(contract, 'AssignGrant', data.grant_id);
             let tmapData = Buffer.from(JSON.stringify(data));
             statefulTxn.setTransient({
-                assign_grant: tmapData
+                assign_grant: tmapData,
+                salt: crypto.randomBytes(32)
             });
             let result = await statefulTxn.submit();
             const response = {
@@ -113,11 +122,7 @@ exports.assignGrant = async (request) => {
     
         } catch (error) {
             console.log(`   Successfully caught the error: \n    ${error}`);
-            const response = {
-                status: 'error',
-                message: error.message.split('message=').pop()
-            }
-            return (response)
+            return errorResponse(error)
             
         } 
     } finally {
@@ -150,7 +155,7 @@ exports.acceptGrant = async (request) => {
     
         try {
             let id=request.id;
-            let result = await contract.submitTransaction('AcceptGrant',id);
+            let result = await (await grantTransaction(contract, 'AcceptGrant', id)).submit(id);
             const response = {
                 status: result.toString()
             }
@@ -158,11 +163,7 @@ exports.acceptGrant = async (request) => {
     
         } catch (error) {
             console.log(`   Successfully caught the error: \n    ${error}`);
-            const response = {
-                status: 'error',
-                message: error.message.split('message=').pop()
-            }
-            return (response)
+            return errorResponse(error)
             
         } 
     } finally {
@@ -195,7 +196,7 @@ exports.rejectGrant = async (request) => {
     
         try {
             let id=request.id;
-            let result = await contract.submitTransaction('RejectGrant',id);
+            let result = await (await grantTransaction(contract, 'RejectGrant', id)).submit(id);
             const response = {
                 status: result.toString()
             }
@@ -203,11 +204,7 @@ exports.rejectGrant = async (request) => {
     
         } catch (error) {
             console.log(`   Successfully caught the error: \n    ${error}`);
-            const response = {
-                status: 'error',
-                message: error.message.split('message=').pop()
-            }
-            return (response)
+            return errorResponse(error)
             
         } 
     } finally {
@@ -243,7 +240,7 @@ exports.revokeGrant = async (request) => {
         try {
             let id=request.id;
             grantorId=request.userId;
-            let result = await contract.submitTransaction('RevokeGrant',id, grantorId);
+            let result = await (await grantTransaction(contract, 'RevokeGrant', id)).submit(id, grantorId);
             const response = {
                 status: result.toString()
             }
@@ -251,11 +248,7 @@ exports.revokeGrant = async (request) => {
     
         } catch (error) {
             console.log(`   Successfully caught the error: \n    ${error}`);
-            const response = {
-                status: 'error',
-                message: error.message.split('message=').pop()
-            }
-            return (response)
+            return errorResponse(error)
             
         } 
     } finally {
@@ -289,8 +282,8 @@ exports.updateGrant = async (request) => {
         const contract = network.getContract(chaincodeName);
     
         try {
-            let statefulTxn = contract.createTransaction('UpdateGrant');
             let data=request.data;
+            let statefulTxn = await grantTransaction(contract, 'UpdateGrant', data.ID);
             let tmapData = Buffer.from(JSON.stringify(data));
             statefulTxn.setTransient({
                 update_grant: tmapData
@@ -303,11 +296,7 @@ exports.updateGrant = async (request) => {
     
         } catch (error) {
             console.log(`   Successfully caught the error: \n    ${error}`);
-            const response = {
-                status: 'error',
-                message: error.message.split('message=').pop()
-            }
-            return (response)
+            return errorResponse(error)
             
         } 
     } finally {
@@ -339,8 +328,8 @@ exports.requestReimbursement = async (request) => {
         const contract = network.getContract(chaincodeName);
     
         try {
-            let statefulTxn = contract.createTransaction('RequestReimbursement');
             let data=request.data;
+            let statefulTxn = await grantTransaction(contract, 'RequestReimbursement', data.grant_id);
             let tmapData = Buffer.from(JSON.stringify(data));
             statefulTxn.setTransient({
                 request_reimbursement: tmapData
@@ -353,11 +342,7 @@ exports.requestReimbursement = async (request) => {
     
         } catch (error) {
             console.log(`   Successfully caught the error: \n    ${error}`);
-            const response = {
-                status: 'error',
-                message: error.message.split('message=').pop()
-            }
-            return (response)
+            return errorResponse(error)
             
         } 
     } finally {
@@ -391,7 +376,7 @@ exports.acceptReimbursement = async (request) => {
         try {
             let grant_id=request.grant_id;
             let payment_id=request.payment_id;
-            let result = await contract.submitTransaction('AcceptReimbursement',grant_id, payment_id);
+            let result = await (await grantTransaction(contract, 'AcceptReimbursement', grant_id)).submit(grant_id, payment_id);
             const response = {
                 status: result.toString()
             }
@@ -399,11 +384,7 @@ exports.acceptReimbursement = async (request) => {
     
         } catch (error) {
             console.log(`   Successfully caught the error: \n    ${error}`);
-            const response = {
-                status: 'error',
-                message: error.message.split('message=').pop()
-            }
-            return (response)
+            return errorResponse(error)
             
         } 
     } finally {
@@ -437,8 +418,9 @@ exports.rejectReimbursement = async (request) => {
         try {
             let grant_id=request.grant_id;
             let payment_id=request.payment_id;
+            let code=request.code;
             let message=request.message;
-            let result = await contract.submitTransaction('RejectReimbursement',grant_id, payment_id,message);
+            let result = await (await grantTransaction(contract, 'RejectReimbursement', grant_id)).submit(grant_id, payment_id, code, message);
             const response = {
                 status: result.toString()
             }
@@ -446,11 +428,50 @@ exports.rejectReimbursement = async (request) => {
     
         } catch (error) {
             console.log(`   Successfully caught the error: \n    ${error}`);
+            return errorResponse(error)
+            
+        } 
+    } finally {
+        // Disconnect from the gateway peer when all work for this client identity is complete
+        gateway.disconnect();
+    }   
+}
+
+exports.setApprovalPolicy = async (request) => {
+    try{
+        let org = request.org;
+        const walletPath = path.join(__dirname,`wallet/${org}`)
+        const ccp = getCCP(org);
+    
+        const wallet = await buildWallet(Wallets, walletPath);
+    
+        gateway = new Gateway();
+    
+        await gateway.connect(ccp, {
+            wallet,
+            identity: request.userId,
+            discovery: { enabled: true, asLocalhost: true } // using asLocalhost as this gateway is using a fabric network deployed locally
+        });
+    
+        // Build a network instance based on the channel where the smart contract is deployed
+        const network = await gateway.getNetwork(channelName);
+    
+        // Get the contract from the network.
+        const contract = network.getContract(chaincodeName);
+    
+        try {
+            let grant_id=request.grant_id;
+            // an absent policy removes the grant's approval policy
+            let policy=request.policy ? JSON.stringify(request.policy) : "";
+            let result = await (await grantTransaction(contract, 'SetApprovalPolicy', grant_id)).submit(grant_id, policy);
             const response = {
-                status: 'error',
-                message: error.message.split('message=').pop()
+                status: result.toString()
             }
-            return (response)
+            return (response);
+    
+        } catch (error) {
+            console.log(`   Successfully caught the error: \n    ${error}`);
+            return errorResponse(error)
             
         } 
     } finally {
@@ -459,7 +480,53 @@ exports.rejectReimbursement = async (request) => {
     }   
 }
 
-exports.redeemTokens = async (request) => {
+exports.requestExtension = async (request) => {
+    try{
+        let org = request.org;
+        const walletPath = path.join(__dirname,`wallet/${org}`)
+        const ccp = getCCP(org);
+    
+        const wallet = await buildWallet(Wallets, walletPath);
+    
+        gateway = new Gateway();
+    
+        await gateway.connect(ccp, {
+            wallet,
+            identity: request.userId,
+            discovery: { enabled: true, asLocalhost: true } // using asLocalhost as this gateway is using a fabric network deployed locally
+        });
+    
+        // Build a network instance based on the channel where the smart contract is deployed
+        const network = await gateway.getNetwork(channelName);
+    
+        // Get the contract from the network.
+        const contract = network.getContract(chaincodeName);
+    
+        try {
+            let data=request.data;
+            let statefulTxn = await grantTransaction(contract, 'RequestExtension', data.grant_id);
+            let tmapData = Buffer.from(JSON.stringify(data));
+            statefulTxn.setTransient({
+                extension_request: tmapData
+            });
+            let result = await statefulTxn.submit();
+            const response = {
+                status: result.toString()
+            }
+            return (response);
+    
+        } catch (error) {
+            console.log(`   Successfully caught the error: \n    ${error}`);
+            return errorResponse(error)
+            
+        } 
+    } finally {
+        // Disconnect from the gateway peer when all work for this client identity is complete
+        gateway.disconnect();
+    }   
+}
+
+exports.approveExtension = async (request) => {
     try{
         let org = request.org;
         const walletPath = path.join(__dirname,`wallet/${org}`)
@@ -483,8 +550,9 @@ exports.redeemTokens = async (request) => {
     
         try {
             let grant_id=request.grant_id;
-            let payment_id=request.payment_id;
-            let result = await contract.submitTransaction('RedeemTokens',grant_id, payment_id);
+            let number=String(request.number);
+            let notes=request.notes || "";
+            let result = await (await grantTransaction(contract, 'ApproveExtension', grant_id)).submit(grant_id, number, notes);
             const response = {
                 status: result.toString()
             }
@@ -492,11 +560,50 @@ exports.redeemTokens = async (request) => {
     
         } catch (error) {
             console.log(`   Successfully caught the error: \n    ${error}`);
+            return errorResponse(error)
+            
+        } 
+    } finally {
+        // Disconnect from the gateway peer when all work for this client identity is complete
+        gateway.disconnect();
+    }   
+}
+
+exports.denyExtension = async (request) => {
+    try{
+        let org = request.org;
+        const walletPath = path.join(__dirname,`wallet/${org}`)
+        const ccp = getCCP(org);
+    
+        const wallet = await buildWallet(Wallets, walletPath);
+    
+        gateway = new Gateway();
+    
+        await gateway.connect(ccp, {
+            wallet,
+            identity: request.userId,
+            discovery: { enabled: true, asLocalhost: true } // using asLocalhost as this gateway is using a fabric network deployed locally
+        });
+    
+        // Build a network instance based on the channel where the smart contract is deployed
+        const network = await gateway.getNetwork(channelName);
+    
+        // Get the contract from the network.
+        const contract = network.getContract(chaincodeName);
+    
+        try {
+            let grant_id=request.grant_id;
+            let number=String(request.number);
+            let reason=request.reason || "";
+            let result = await (await grantTransaction(contract, 'DenyExtension', grant_id)).submit(grant_id, number, reason);
             const response = {
-                status: 'error',
-                message: error.message.split('message=').pop()
+                status: result.toString()
             }
-            return (response)
+            return (response);
+    
+        } catch (error) {
+            console.log(`   Successfully caught the error: \n    ${error}`);
+            return errorResponse(error)
             
         } 
     } finally {
@@ -505,7 +612,353 @@ exports.redeemTokens = async (request) => {
     }   
 }
 
-exports.acceptRedeem = async (request) => {
+exports.proposeAmendment = async (request) => {
+    try{
+        let org = request.org;
+        const walletPath = path.join(__dirname,`wallet/${org}`)
+        const ccp = getCCP(org);
+    
+        const wallet = await buildWallet(Wallets, walletPath);
+    
+        gateway = new Gateway();
+    
+        await gateway.connect(ccp, {
+            wallet,
+            identity: request.userId,
+            discovery: { enabled: true, asLocalhost: true } // using asLocalhost as this gateway is using a fabric network deployed locally
+        });
+    
+        // Build a network instance based on the channel where the smart contract is deployed
+        const network = await gateway.getNetwork(channelName);
+    
+        // Get the contract from the network.
+        const contract = network.getContract(chaincodeName);
+    
+        try {
+            let data=request.data;
+            let statefulTxn = await grantTransaction(contract, 'ProposeAmendment', data.grant_id);
+            let tmapData = Buffer.from(JSON.stringify(data));
+            statefulTxn.setTransient({
+                amendment: tmapData
+            });
+            let result = await statefulTxn.submit();
+            const response = {
+                status: result.toString()
+            }
+            return (response);
+    
+        } catch (error) {
+            console.log(`   Successfully caught the error: \n    ${error}`);
+            return errorResponse(error)
+            
+        } 
+    } finally {
+        // Disconnect from the gateway peer when all work for this client identity is complete
+        gateway.disconnect();
+    }   
+}
+
+exports.countersignAmendment = async (request) => {
+    try{
+        let org = request.org;
+        const walletPath = path.join(__dirname,`wallet/${org}`)
+        const ccp = getCCP(org);
+    
+        const wallet = await buildWallet(Wallets, walletPath);
+    
+        gateway = new Gateway();
+    
+        await gateway.connect(ccp, {
+            wallet,
+            identity: request.userId,
+            discovery: { enabled: true, asLocalhost: true } // using asLocalhost as this gateway is using a fabric network deployed locally
+        });
+    
+        // Build a network instance based on the channel where the smart contract is deployed
+        const network = await gateway.getNetwork(channelName);
+    
+        // Get the contract from the network.
+        const contract = network.getContract(chaincodeName);
+    
+        try {
+            let grant_id=request.grant_id;
+            let number=String(request.number);
+            let result = await (await grantTransaction(contract, 'CountersignAmendment', grant_id)).submit(grant_id, number);
+            const response = {
+                status: result.toString()
+            }
+            return (response);
+    
+        } catch (error) {
+            console.log(`   Successfully caught the error: \n    ${error}`);
+            return errorResponse(error)
+            
+        } 
+    } finally {
+        // Disconnect from the gateway peer when all work for this client identity is complete
+        gateway.disconnect();
+    }   
+}
+
+exports.declineAmendment = async (request) => {
+    try{
+        let org = request.org;
+        const walletPath = path.join(__dirname,`wallet/${org}`)
+        const ccp = getCCP(org);
+    
+        const wallet = await buildWallet(Wallets, walletPath);
+    
+        gateway = new Gateway();
+    
+        await gateway.connect(ccp, {
+            wallet,
+            identity: request.userId,
+            discovery: { enabled: true, asLocalhost: true } // using asLocalhost as this gateway is using a fabric network deployed locally
+        });
+    
+        // Build a network instance based on the channel where the smart contract is deployed
+        const network = await gateway.getNetwork(channelName);
+    
+        // Get the contract from the network.
+        const contract = network.getContract(chaincodeName);
+    
+        try {
+            let grant_id=request.grant_id;
+            let number=String(request.number);
+            let reason=request.reason || "";
+            let result = await (await grantTransaction(contract, 'DeclineAmendment', grant_id)).submit(grant_id, number, reason);
+            const response = {
+                status: result.toString()
+            }
+            return (response);
+    
+        } catch (error) {
+            console.log(`   Successfully caught the error: \n    ${error}`);
+            return errorResponse(error)
+            
+        } 
+    } finally {
+        // Disconnect from the gateway peer when all work for this client identity is complete
+        gateway.disconnect();
+    }   
+}
+
+exports.setRebudgetThreshold = async (request) => {
+    try{
+        let org = request.org;
+        const walletPath = path.join(__dirname,`wallet/${org}`)
+        const ccp = getCCP(org);
+    
+        const wallet = await buildWallet(Wallets, walletPath);
+    
+        gateway = new Gateway();
+    
+        await gateway.connect(ccp, {
+            wallet,
+            identity: request.userId,
+            discovery: { enabled: true, asLocalhost: true } // using asLocalhost as this gateway is using a fabric network deployed locally
+        });
+    
+        // Build a network instance based on the channel where the smart contract is deployed
+        const network = await gateway.getNetwork(channelName);
+    
+        // Get the contract from the network.
+        const contract = network.getContract(chaincodeName);
+    
+        try {
+            let grant_id=request.grant_id;
+            let threshold=String(request.threshold);
+            let result = await (await grantTransaction(contract, 'SetRebudgetThreshold', grant_id)).submit(grant_id, threshold);
+            const response = {
+                status: result.toString()
+            }
+            return (response);
+    
+        } catch (error) {
+            console.log(`   Successfully caught the error: \n    ${error}`);
+            return errorResponse(error)
+            
+        } 
+    } finally {
+        // Disconnect from the gateway peer when all work for this client identity is complete
+        gateway.disconnect();
+    }   
+}
+
+exports.rebudgetRequest = async (request) => {
+    try{
+        let org = request.org;
+        const walletPath = path.join(__dirname,`wallet/${org}`)
+        const ccp = getCCP(org);
+    
+        const wallet = await buildWallet(Wallets, walletPath);
+    
+        gateway = new Gateway();
+    
+        await gateway.connect(ccp, {
+            wallet,
+            identity: request.userId,
+            discovery: { enabled: true, asLocalhost: true } // using asLocalhost as this gateway is using a fabric network deployed locally
+        });
+    
+        // Build a network instance based on the channel where the smart contract is deployed
+        const network = await gateway.getNetwork(channelName);
+    
+        // Get the contract from the network.
+        const contract = network.getContract(chaincodeName);
+    
+        try {
+            let data=request.data;
+            let statefulTxn = await grantTransaction(contract, 'RebudgetRequest', data.grant_id);
+            let tmapData = Buffer.from(JSON.stringify(data));
+            statefulTxn.setTransient({
+                rebudget_request: tmapData
+            });
+            let result = await statefulTxn.submit();
+            const response = {
+                status: result.toString()
+            }
+            return (response);
+    
+        } catch (error) {
+            console.log(`   Successfully caught the error: \n    ${error}`);
+            return errorResponse(error)
+            
+        } 
+    } finally {
+        // Disconnect from the gateway peer when all work for this client identity is complete
+        gateway.disconnect();
+    }   
+}
+
+exports.approveRebudget = async (request) => {
+    try{
+        let org = request.org;
+        const walletPath = path.join(__dirname,`wallet/${org}`)
+        const ccp = getCCP(org);
+    
+        const wallet = await buildWallet(Wallets, walletPath);
+    
+        gateway = new Gateway();
+    
+        await gateway.connect(ccp, {
+            wallet,
+            identity: request.userId,
+            discovery: { enabled: true, asLocalhost: true } // using asLocalhost as this gateway is using a fabric network deployed locally
+        });
+    
+        // Build a network instance based on the channel where the smart contract is deployed
+        const network = await gateway.getNetwork(channelName);
+    
+        // Get the contract from the network.
+        const contract = network.getContract(chaincodeName);
+    
+        try {
+            let grant_id=request.grant_id;
+            let number=String(request.number);
+            let notes=request.notes || "";
+            let result = await (await grantTransaction(contract, 'ApproveRebudget', grant_id)).submit(grant_id, number, notes);
+            const response = {
+                status: result.toString()
+            }
+            return (response);
+    
+        } catch (error) {
+            console.log(`   Successfully caught the error: \n    ${error}`);
+            return errorResponse(error)
+            
+        } 
+    } finally {
+        // Disconnect from the gateway peer when all work for this client identity is complete
+        gateway.disconnect();
+    }   
+}
+
+exports.denyRebudget = async (request) => {
+    try{
+        let org = request.org;
+        const walletPath = path.join(__dirname,`wallet/${org}`)
+        const ccp = getCCP(org);
+    
+        const wallet = await buildWallet(Wallets, walletPath);
+    
+        gateway = new Gateway();
+    
+        await gateway.connect(ccp, {
+            wallet,
+            identity: request.userId,
+            discovery: { enabled: true, asLocalhost: true } // using asLocalhost as this gateway is using a fabric network deployed locally
+        });
+    
+        // Build a network instance based on the channel where the smart contract is deployed
+        const network = await gateway.getNetwork(channelName);
+    
+        // Get the contract from the network.
+        const contract = network.getContract(chaincodeName);
+    
+        try {
+            let grant_id=request.grant_id;
+            let number=String(request.number);
+            let reason=request.reason || "";
+            let result = await (await grantTransaction(contract, 'DenyRebudget', grant_id)).submit(grant_id, number, reason);
+            const response = {
+                status: result.toString()
+            }
+            return (response);
+    
+        } catch (error) {
+            console.log(`   Successfully caught the error: \n    ${error}`);
+            return errorResponse(error)
+            
+        } 
+    } finally {
+        // Disconnect from the gateway peer when all work for this client identity is complete
+        gateway.disconnect();
+    }   
+}
+
+exports.updateGrantEndorsement = async (request) => {
+    try{
+        let org = request.org;
+        const walletPath = path.join(__dirname,`wallet/${org}`)
+        const ccp = getCCP(org);
+    
+        const wallet = await buildWallet(Wallets, walletPath);
+    
+        gateway = new Gateway();
+    
+        await gateway.connect(ccp, {
+            wallet,
+            identity: request.userId,
+            discovery: { enabled: true, asLocalhost: true } // using asLocalhost as this gateway is using a fabric network deployed locally
+        });
+    
+        // Build a network instance based on the channel where the smart contract is deployed
+        const network = await gateway.getNetwork(channelName);
+    
+        // Get the contract from the network.
+        const contract = network.getContract(chaincodeName);
+    
+        try {
+            let grant_id=request.grant_id;
+            let result = await (await grantTransaction(contract, 'UpdateGrantEndorsement', grant_id)).submit(grant_id);
+            const response = {
+                status: result.toString()
+            }
+            return (response);
+    
+        } catch (error) {
+            console.log(`   Successfully caught the error: \n    ${error}`);
+            return errorResponse(error)
+            
+        } 
+    } finally {
+        // Disconnect from the gateway peer when all work for this client identity is complete
+        gateway.disconnect();
+    }   
+}
+
+exports.redeemTokens = async (request) => {
     try{
         let org = request.org;
         const walletPath = path.join(__dirname,`wallet/${org}`)
@@ -530,7 +983,7 @@ exports.acceptRedeem = async (request) => {
         try {
             let grant_id=request.grant_id;
             let payment_id=request.payment_id;
-            let result = await contract.submitTransaction('AcceptRedeem',grant_id, payment_id);
+            let result = await (await grantTransaction(contract, 'RedeemTokens', grant_id)).submit(grant_id, payment_id);
             const response = {
                 status: result.toString()
             }
@@ -538,11 +991,49 @@ exports.acceptRedeem = async (request) => {
     
         } catch (error) {
             console.log(`   Successfully caught the error: \n    ${error}`);
+            return errorResponse(error)
+            
+        } 
+    } finally {
+        // Disconnect from the gateway peer when all work for this client identity is complete
+        gateway.disconnect();
+    }   
+}
+
+exports.acceptRedeem = async (request) => {
+    try{
+        let org = request.org;
+        const walletPath = path.join(__dirname,`wallet/${org}`)
+        const ccp = getCCP(org);
+    
+        const wallet = await buildWallet(Wallets, walletPath);
+    
+        gateway = new Gateway();
+    
+        await gateway.connect(ccp, {
+            wallet,
+            identity: request.userId,
+            discovery: { enabled: true, asLocalhost: true } // using asLocalhost as this gateway is using a fabric network deployed locally
+        });
+    
+        // Build a network instance based on the channel where the smart contract is deployed
+        const network = await gateway.getNetwork(channelName);
+    
+        // Get the contract from the network.
+        const contract = network.getContract(chaincodeName);
+    
+        try {
+            let grant_id=request.grant_id;
+            let payment_id=request.payment_id;
+            let result = await (await grantTransaction(contract, 'AcceptRedeem', grant_id)).submit(grant_id, payment_id);
             const response = {
-                status: 'error',
-                message: error.message.split('message=').pop()
+                status: result.toString()
             }
-            return (response)
+            return (response);
+    
+        } catch (error) {
+            console.log(`   Successfully caught the error: \n    ${error}`);
+            return errorResponse(error)
             
         } 
     } finally {
@@ -576,8 +1067,9 @@ exports.rejectRedeem = async (request) => {
         try {
             let grant_id=request.grant_id;
             let payment_id=request.payment_id;
+            let code=request.code;
             let message=request.message;
-            let result = await contract.submitTransaction('rejectRedeem',grant_id, payment_id, message);
+            let result = await (await grantTransaction(contract, 'RejectRedeem', grant_id)).submit(grant_id, payment_id, code, message);
             const response = {
                 status: result.toString()
             }
@@ -585,11 +1077,7 @@ exports.rejectRedeem = async (request) => {
     
         } catch (error) {
             console.log(`   Successfully caught the error: \n    ${error}`);
-            const response = {
-                status: 'error',
-                message: error.message.split('message=').pop()
-            }
-            return (response)
+            return errorResponse(error)
             
         } 
     } finally {
@@ -621,11 +1109,12 @@ exports.addSubawardee = async (request) => {
         const contract = network.getContract(chaincodeName);
     
         try {
-            let statefulTxn = contract.createTransaction('AddSubawardee');
             let data=request.data;
+            let statefulTxn = await grantTransaction(contract, 'AddSubawardee', data.grant_id);
             let tmapData = Buffer.from(JSON.stringify(data));
             statefulTxn.setTransient({
-                add_subawardee: tmapData
+                add_subawardee: tmapData,
+                salt: crypto.randomBytes(32)
             });
             let result = await statefulTxn.submit();
             const response = {
@@ -635,11 +1124,7 @@ exports.addSubawardee = async (request) => {
     
         } catch (error) {
             console.log(`   Successfully caught the error: \n    ${error}`);
-            const response = {
-                status: 'error',
-                message: error.message.split('message=').pop()
-            }
-            return (response)
+            return errorResponse(error)
             
         } 
     } finally {
@@ -673,11 +1158,12 @@ exports.addAwardee = async (request) => {
         const contract = network.getContract(chaincodeName);
     
         try {
-            let statefulTxn = contract.createTransaction('AddAwardee');
             let data=request.data;
+            let statefulTxn = await grantTransaction(contract, 'AddAwardee', data.grant_id);
             let tmapData = Buffer.from(JSON.stringify(data));
             statefulTxn.setTransient({
-                add_awardee: tmapData
+                add_awardee: tmapData,
+                salt: crypto.randomBytes(32)
             });
             let result = await statefulTxn.submit();
             const response = {
@@ -687,11 +1173,7 @@ exports.addAwardee = async (request) => {
     
         } catch (error) {
             console.log(`   Successfully caught the error: \n    ${error}`);
-            const response = {
-                status: 'error',
-                message: error.message.split('message=').pop()
-            }
-            return (response)
+            return errorResponse(error)
             
         } 
     } finally {
@@ -723,8 +1205,8 @@ exports.addProgress = async (request) => {
         const contract = network.getContract(chaincodeName);
     
         try {
-            let statefulTxn = contract.createTransaction('AddProgress');
             let data=request.data;
+            let statefulTxn = await grantTransaction(contract, 'AddProgress', data.grant_id);
             let tmapData = Buffer.from(JSON.stringify(data));
             statefulTxn.setTransient({
                 add_progress: tmapData
@@ -737,11 +1219,7 @@ exports.addProgress = async (request) => {
     
         } catch (error) {
             console.log(`   Successfully caught the error: \n    ${error}`);
-            const response = {
-                status: 'error',
-                message: error.message.split('message=').pop()
-            }
-            return (response)
+            return errorResponse(error)
             
         } 
     } finally {
@@ -773,7 +1251,7 @@ exports.deleteGrant = async (request) => {
         const contract = network.getContract(chaincodeName);
     
         try {
-            let transaction = contract.createTransaction('DeleteGrant');
+            let transaction = await grantTransaction(contract, 'DeleteGrant', request.grantId);
 			let result =  await transaction.submit(request.grantId);
             const response = {
                 status: result.toString()
@@ -782,11 +1260,7 @@ exports.deleteGrant = async (request) => {
     
         } catch (error) {
             console.log(`   Successfully caught the error: \n    ${error}`);
-            const response = {
-                status: 'error',
-                message: error.message.split('message=').pop()
-            }
-            return (response)
+            return errorResponse(error)
             
         } 
     } finally {
@@ -0,0 +1,147 @@
+package chaincode
+
+import (
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// grantAccess is how much of a grant a caller may read. The grant's grantor,
+// funders, awardees and subawardees, matched by org as well as ID, read it in
+// full, and so do members of the orgs funding it with a program_officer,
+// finance or admin role. Members of the auditor org read every grant, but
+// only a summary: amounts, statuses and dates without the free-text notes and
+// contact details. Users of other orgs with the auditor role read the same
+// summary of the grants their own org takes part in; a role their org's CA
+// issued grants nothing beyond it. Anyone else can't read it.
+type grantAccess int
+
+const (
+	accessNone grantAccess = iota
+	accessSummary
+	accessFull
+)
+
+func grantAccessOf(caller *Caller, grant *Grant) grantAccess {
+	if isParticipant(grant, caller) {
+		return accessFull
+	}
+	if caller.Org == orgGrantor && fundsGrant(grant, caller.MSPID) {
+		switch caller.Role {
+		case RoleProgramOfficer, RoleFinance, RoleAdmin:
+			return accessFull
+		}
+	}
+	if caller.Org == orgAuditor {
+		return accessSummary
+	}
+	if caller.Role == RoleAuditor && orgTakesPart(grant, caller.MSPID) {
+		return accessSummary
+	}
+	return accessNone
+}
+
+// orgTakesPart reports whether the org with the given MSP ID funds the grant
+// or has an awardee or subawardee on it.
+func orgTakesPart(grant *Grant, mspID string) bool {
+	if fundsGrant(grant, mspID) {
+		return true
+	}
+	for _, awardee := range grant.Awardee {
+		if userMSP(awardee.ID) == mspID {
+			return true
+		}
+	}
+	return false
+}
+
+// readGrantAs reads a grant for the caller and reports the access they have to it.
+func readGrantAs(ctx contractapi.TransactionContextInterface, caller *Caller, id string) (*Grant, grantAccess, error) {
+	grant, err := readGrant(ctx, id)
+	if err != nil {
+		return nil, accessNone, err
+	}
+
+	access := grantAccessOf(caller, grant)
+	if access == accessNone {
+		return nil, accessNone, readForbidden(caller, grant.ID)
+	}
+	return grant, access, nil
+}
+
+// readForbidden is the error for a caller who may not read the grant.
+func readForbidden(caller *Caller, grantID string) *ContractError {
+	return errForbidden("User %s from org %v is not authorized to read the Grant %s", caller.ID, caller.MSPID, grantID).
+		with("grant_id", grantID)
+}
+
+// redactGrant turns a grant into its summary view. The result must never be
+// written back to the ledger.
+func redactGrant(grant *Grant) {
+	grant.Notes = ""
+	grant.Redacted = true
+
+	if grant.Awardee != nil {
+		awardees := make([]Awardee, len(grant.Awardee))
+		for i, awardee := range grant.Awardee {
+			awardee.Account_Number = ""
+			awardee.Contact = ""
+			awardee.Private_Hash = ""
+			awardees[i] = awardee
+		}
+		grant.Awardee = awardees
+	}
+
+	grant.Progress = redactProgress(grant.Progress)
+	grant.Amendments = redactAmendments(grant.Amendments)
+	grant.Payment = redactPayments(grant.Payment)
+}
+
+func redactProgress(progress []Progress) []Progress {
+	if progress == nil {
+		return nil
+	}
+	redacted := make([]Progress, len(progress))
+	for i, entry := range progress {
+		entry.Notes = ""
+		redacted[i] = entry
+	}
+	return redacted
+}
+
+// redactPayment turns a payment into its summary view, keeping the rejection
+// code but not its free-text reason.
+func redactPayment(payment *Payment) {
+	payment.Notes = ""
+	payment.Redacted = true
+	if payment.Rejection != nil {
+		rejection := *payment.Rejection
+		rejection.Reason = ""
+		payment.Rejection = &rejection
+	}
+}
+
+func redactPayments(payments []Payment) []Payment {
+	if payments == nil {
+		return nil
+	}
+	redacted := make([]Payment, len(payments))
+	for i, payment := range payments {
+		redactPayment(&payment)
+		redacted[i] = payment
+	}
+	return redacted
+}
+
+// viewGrant redacts the grant unless the caller has full access.
+func viewGrant(grant *Grant, access grantAccess) {
+	if access != accessFull {
+		redactGrant(grant)
+	}
+}
+
+// viewPayments returns the payments redacted unless the caller has full access.
+func viewPayments(payments []Payment, access grantAccess) []Payment {
+	if access != accessFull {
+		return redactPayments(payments)
+	}
+	return payments
+}
@@ -0,0 +1,120 @@
+package chaincode
+
+import (
+	"testing"
+
+	"chaincode-go/chaincodetest"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+func TestGrantAccess(t *testing.T) {
+	l := newLedger(t)
+	grant := newGrant("G1")
+	grant["notes"] = "site visit pending"
+	l.must(l.initiate(grant))
+	l.must(l.assign("G1", testAwardee("bob", "Main", AwardeeMSP)))
+
+	tests := []struct {
+		name     string
+		identity *chaincodetest.Identity
+		access   grantAccess
+	}{
+		{"grantor", grantor, accessFull},
+		{"awardee", awardee, accessFull},
+		{"auditor org", auditor, accessSummary},
+		{"other grantor", chaincodetest.NewIdentity(GrantorMSP, "eve"), accessNone},
+		{"subawardee org", subawardee, accessNone},
+		{"grantor finance", chaincodetest.NewIdentity(GrantorMSP, "gina").WithAttribute(roleAttribute, string(RoleFinance)), accessFull},
+		{"grantor user without role", chaincodetest.NewIdentity(GrantorMSP, "gina"), accessNone},
+		{"auditor role in the awardee org", chaincodetest.NewIdentity(AwardeeMSP, "ivan").WithAttribute(roleAttribute, string(RoleAuditor)), accessSummary},
+		{"auditor role in an org off the grant", chaincodetest.NewIdentity(SubawardeeMSP, "judy").WithAttribute(roleAttribute, string(RoleAuditor)), accessNone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			grant, err := l.read(tt.identity, "G1")
+			switch tt.access {
+			case accessNone:
+				wantCode(t, err, CodeForbidden)
+			case accessSummary:
+				noError(t, err)
+				if !grant.Redacted || grant.Notes != "" || grant.Awardee[0].Private_Hash != "" {
+					t.Fatalf("got an unredacted grant %+v", grant)
+				}
+				if grant.Amount != "1000.00" || grant.Awardee[0].ID != "AwardeeMSP/bob" {
+					t.Fatalf("the summary dropped amounts or participants: %+v", grant)
+				}
+			case accessFull:
+				noError(t, err)
+				if grant.Redacted || grant.Notes == "" {
+					t.Fatalf("got a redacted grant %+v", grant)
+				}
+			}
+		})
+	}
+}
+
+func TestAuditorSeesRedactedPayments(t *testing.T) {
+	l := newLedger(t)
+	l.activeGrant("G1")
+	_, err := l.reimburse(awardee, "G1", "P1", "AwardeeMSP/bob", item("Personnel", "100"))
+	l.must(err)
+	l.must(l.run(grantor, nil, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.RejectReimbursement(ctx, "G1", "P1", string(RejectionMissingDocumentation), "no receipts for the March invoice")
+		return err
+	}))
+
+	var payments []Payment
+	l.must(l.run(auditor, nil, func(ctx contractapi.TransactionContextInterface) error {
+		var err error
+		payments, err = l.contract.GetPayments(ctx, "G1")
+		return err
+	}))
+	if len(payments) != 1 || !payments[0].Redacted || payments[0].Rejection.Reason != "" || payments[0].Rejection.Code != RejectionMissingDocumentation {
+		t.Fatalf("auditor got %+v, want the payment redacted", payments)
+	}
+
+	var changes []PaymentStatusChange
+	l.must(l.run(auditor, nil, func(ctx contractapi.TransactionContextInterface) error {
+		var err error
+		changes, err = l.contract.GetPaymentHistory(ctx, "G1", "P1")
+		return err
+	}))
+	last := changes[len(changes)-1]
+	if last.Status != PaymentRejected || last.Rejection.Reason != "" {
+		t.Fatalf("auditor got history entry %+v, want the reason redacted", last)
+	}
+
+	err = l.run(chaincodetest.NewIdentity(GrantorMSP, "eve"), nil, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.GetPayments(ctx, "G1")
+		return err
+	})
+	wantCode(t, err, CodeForbidden)
+}
+
+func TestParticipantsMatchByOrg(t *testing.T) {
+	grant := &Grant{
+		Grantor:    "Grantor",
+		Grantor_ID: "GrantorMSP/alice",
+		Funder:     []Funder{{MSP: "AgencyMSP", Funder_ID: "AgencyMSP/erin"}},
+		Awardee:    []Awardee{{ID: "AwardeeMSP/bob"}},
+	}
+	tests := []struct {
+		id          string
+		msp         string
+		participant bool
+	}{
+		{id: "GrantorMSP/alice", msp: GrantorMSP, participant: true},
+		{id: "GrantorMSP/alice", msp: "AgencyMSP"},
+		{id: "AgencyMSP/erin", msp: "AgencyMSP", participant: true},
+		{id: "AgencyMSP/erin", msp: GrantorMSP},
+		{id: "AwardeeMSP/bob", msp: AwardeeMSP, participant: true},
+		{id: "AwardeeMSP/bob", msp: SubawardeeMSP},
+	}
+	for _, test := range tests {
+		caller := &Caller{ID: test.id, MSPID: test.msp}
+		if got := isParticipant(grant, caller); got != test.participant {
+			t.Errorf("%s from %s: participant %v, want %v", test.id, test.msp, got, test.participant)
+		}
+	}
+}
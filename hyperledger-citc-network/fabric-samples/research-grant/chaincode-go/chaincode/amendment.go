@@ -0,0 +1,163 @@
+package chaincode
+
+import (
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Amendments change the terms of a grant after it was awarded. The grant
+// keeps every amendment ever requested in Amendments, in order, whether it was
+// approved or not; Number is its position in the list, starting at 1.
+
+// AmendmentType is the kind of change an amendment makes.
+type AmendmentType string
+
+const (
+	// AmendmentExtension moves the end of the period of performance without
+	// changing the budget, a no-cost extension.
+	AmendmentExtension AmendmentType = "NoCostExtension"
+	// AmendmentBudget replaces the budget with a new version.
+	AmendmentBudget AmendmentType = "BudgetRevision"
+	// AmendmentRebudget moves money between benefit lines, keeping the amount.
+	AmendmentRebudget AmendmentType = "Rebudget"
+)
+
+// AmendmentStatus is where an amendment stands.
+type AmendmentStatus string
+
+const (
+	AmendmentRequested AmendmentStatus = "Requested"
+	AmendmentApproved  AmendmentStatus = "Approved"
+	AmendmentDenied    AmendmentStatus = "Denied"
+)
+
+// Amendment records a requested change to a grant and its decision.
+// Effective_Date is when an approved amendment took effect. For an extension,
+// End_Date is the requested end date and Previous_End_Date the one it
+// replaces. Budget revisions and rebudgets carry the new Budget and the
+// Previous_Budget it replaces; a budget revision is approved by the main
+// awardee's countersignature. A rebudget lists its transfers and the total
+// they move, and is Auto_Approved if it was within the rebudget threshold.
+type Amendment struct {
+	Number            int             `json:"number"`
+	Type              AmendmentType   `json:"type"`
+	Status            AmendmentStatus `json:"status"`
+	Reason            string          `json:"reason"`
+	Requested_By      string          `json:"requested_by"`
+	Requested_At      string          `json:"requested_at"`
+	Effective_Date    string          `json:"effective_date,omitempty" metadata:"effective_date,optional"`
+	Previous_End_Date string          `json:"previous_end_date,omitempty" metadata:"previous_end_date,optional"`
+	End_Date          string          `json:"end_date,omitempty" metadata:"end_date,optional"`
+	Previous_Budget   *Budget         `json:"previous_budget,omitempty" metadata:"previous_budget,optional"`
+	Budget            *Budget         `json:"budget,omitempty" metadata:"budget,optional"`
+	Transfer          []Transfer      `json:"transfer,omitempty" metadata:"transfer,optional"`
+	Moved             Money           `json:"moved,omitempty" metadata:"moved,optional"`
+	Auto_Approved     bool            `json:"auto_approved,omitempty" metadata:"auto_approved,optional"`
+	Decided_By        string          `json:"decided_by,omitempty" metadata:"decided_by,optional"`
+	Decided_At        string          `json:"decided_at,omitempty" metadata:"decided_at,optional"`
+	Decision_Notes    string          `json:"decision_notes,omitempty" metadata:"decision_notes,optional"`
+}
+
+// addAmendment appends an amendment to the grant, numbering it.
+func addAmendment(grant *Grant, amendment Amendment) *Amendment {
+	amendment.Number = len(grant.Amendments) + 1
+	grant.Amendments = append(grant.Amendments, amendment)
+	return &grant.Amendments[len(grant.Amendments)-1]
+}
+
+// findAmendment returns the amendment of the grant with the number.
+func findAmendment(grant *Grant, number int) (*Amendment, error) {
+	if number < 1 || number > len(grant.Amendments) {
+		return nil, errNotFound("Amendment %d doesn't exist in the Grant %s", number, grant.ID).
+			with("grant_id", grant.ID).
+			with("amendment", number)
+	}
+	return &grant.Amendments[number-1], nil
+}
+
+// pendingAmendment returns the amendment of the type awaiting a decision, or nil.
+func pendingAmendment(grant *Grant, amendmentType AmendmentType) *Amendment {
+	for i := range grant.Amendments {
+		amendment := &grant.Amendments[i]
+		if amendment.Type == amendmentType && amendment.Status == AmendmentRequested {
+			return amendment
+		}
+	}
+	return nil
+}
+
+// pendingBudgetChange returns the budget revision or rebudget awaiting a
+// decision, or nil. A grant has at most one at a time.
+func pendingBudgetChange(grant *Grant) *Amendment {
+	if pending := pendingAmendment(grant, AmendmentBudget); pending != nil {
+		return pending
+	}
+	return pendingAmendment(grant, AmendmentRebudget)
+}
+
+// budgetChangePending is the error for a budget change requested while another awaits a decision.
+func budgetChangePending(grant *Grant, pending *Amendment) *ContractError {
+	return errDuplicate("Grant %s already has amendment %d changing its budget awaiting a decision", grant.ID, pending.Number).
+		with("grant_id", grant.ID).
+		with("amendment", pending.Number).
+		with("type", pending.Type)
+}
+
+// checkAmendmentRequested fails unless the amendment is of the type and awaits a decision.
+func checkAmendmentRequested(grant *Grant, amendment *Amendment, amendmentType AmendmentType) error {
+	if amendment.Type != amendmentType {
+		return errValidation("Amendment %d of the Grant %s is a %s, not a %s", amendment.Number, grant.ID, amendment.Type, amendmentType).
+			with("grant_id", grant.ID).
+			with("amendment", amendment.Number).
+			with("type", amendment.Type)
+	}
+	if amendment.Status != AmendmentRequested {
+		return errInvalidState("Amendment %d of the Grant %s was already %s", amendment.Number, grant.ID, amendment.Status).
+			with("grant_id", grant.ID).
+			with("amendment", amendment.Number).
+			with("status", amendment.Status).
+			with("allowed", []AmendmentStatus{AmendmentRequested})
+	}
+	return nil
+}
+
+// decideAmendment records the decision on an amendment.
+func decideAmendment(amendment *Amendment, status AmendmentStatus, caller *Caller, at string, notes string) {
+	amendment.Status = status
+	amendment.Decided_By = caller.ID
+	amendment.Decided_At = at
+	amendment.Decision_Notes = notes
+}
+
+func redactAmendments(amendments []Amendment) []Amendment {
+	if amendments == nil {
+		return nil
+	}
+	redacted := make([]Amendment, len(amendments))
+	for i, amendment := range amendments {
+		amendment.Reason = ""
+		amendment.Decision_Notes = ""
+		redacted[i] = amendment
+	}
+	return redacted
+}
+
+// GetAmendments returns every amendment of the grant, oldest first.
+func (s *SmartContract) GetAmendments(ctx contractapi.TransactionContextInterface, grant_id string) ([]Amendment, error) {
+	caller, err := authorize(ctx, "GetAmendments")
+	if err != nil {
+		return nil, err
+	}
+
+	grant, access, err := readGrantAs(ctx, caller, grant_id)
+	if err != nil {
+		return nil, err
+	}
+
+	if grant.Amendments == nil {
+		return []Amendment{}, nil
+	}
+	if access != accessFull {
+		return redactAmendments(grant.Amendments), nil
+	}
+	return grant.Amendments, nil
+}
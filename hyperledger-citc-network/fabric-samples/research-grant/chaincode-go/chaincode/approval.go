@@ -0,0 +1,360 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// ApprovalPolicy decides how many approvals a reimbursement of a grant needs
+// before it is Accepted. It applies to reimbursements whose Total is above
+// Threshold; smaller ones, and every reimbursement of a grant without a
+// policy, are accepted by a single approval as before.
+//
+// A reimbursement under the policy needs Approvals approvals from distinct
+// identities. If Approvers is set only those identities may approve, making
+// the policy Approvals-of-len(Approvers). Each of Roles must be held by a
+// different approver, e.g. ["program_officer", "finance"] for one approval
+// from each; admins stand in for any role. Under a policy with Roles only
+// users with a role attribute may approve.
+type ApprovalPolicy struct {
+	Threshold Money    `json:"threshold"`
+	Approvals int      `json:"approvals"`
+	Approvers []string `json:"approvers,omitempty" metadata:"approvers,optional"`
+	Roles     []Role   `json:"roles,omitempty" metadata:"roles,optional"`
+}
+
+// Approval records one approval of a reimbursement.
+type Approval struct {
+	By   string `json:"by"`
+	MSP  string `json:"msp"`
+	Role Role   `json:"role,omitempty" metadata:"role,optional"`
+	At   string `json:"at"`
+}
+
+// PendingApproval is a reimbursement waiting for the caller's approval, with
+// the number of approvals and the roles it still needs.
+type PendingApproval struct {
+	Grant_ID         string  `json:"grant_id"`
+	Payment          Payment `json:"payment"`
+	Approvals_Needed int     `json:"approvals_needed"`
+	Roles_Needed     []Role  `json:"roles_needed,omitempty" metadata:"roles_needed,optional"`
+}
+
+// checkApprovalPolicy validates a policy for a grant in currency, rewriting
+// its threshold in canonical form. Approvers given by common name alone are
+// qualified with grantorMSP.
+func checkApprovalPolicy(policy *ApprovalPolicy, currency string, grantorMSP string) error {
+	threshold, err := policy.Threshold.minor(currency)
+	if err != nil {
+		return invalidAmount(err, "invalid approval threshold")
+	}
+	if threshold < 0 {
+		return errValidation("approval threshold must not be negative")
+	}
+	policy.Threshold = moneyFromMinor(threshold, currency)
+
+	if policy.Approvals < 1 {
+		return errValidation("an approval policy needs at least 1 approval")
+	}
+
+	seen := map[string]bool{}
+	for i, approver := range policy.Approvers {
+		if approver == "" {
+			return errValidation("approvers must be non-empty identities")
+		}
+		if !strings.Contains(approver, "/") {
+			approver = userID(grantorMSP, approver)
+			policy.Approvers[i] = approver
+		}
+		if seen[approver] {
+			return errDuplicate("approver %s is listed more than once", approver).with("approver", approver)
+		}
+		seen[approver] = true
+	}
+	if len(policy.Approvers) != 0 && policy.Approvals > len(policy.Approvers) {
+		return errValidation("%d approvals can't be collected from %d approvers", policy.Approvals, len(policy.Approvers))
+	}
+
+	for _, role := range policy.Roles {
+		_, err := parseRole(string(role))
+		if err != nil {
+			return err
+		}
+	}
+	if len(policy.Roles) > policy.Approvals {
+		return errValidation("approvals must be at least the %d required roles", len(policy.Roles))
+	}
+	return nil
+}
+
+// paymentPolicy returns the approval policy the payment falls under, or nil
+// if a single approval accepts it.
+func paymentPolicy(grant *Grant, payment *Payment) (*ApprovalPolicy, error) {
+	policy := grant.Approval_Policy
+	if policy == nil {
+		return nil, nil
+	}
+
+	currency := grantCurrency(grant)
+	threshold, err := policy.Threshold.minor(currency)
+	if err != nil {
+		return nil, storedAmountInvalid(err, "Grant %s has an invalid approval threshold", grant.ID)
+	}
+	total, err := payment.Total.minor(currency)
+	if err != nil {
+		return nil, storedAmountInvalid(err, "Payment %s has an invalid amount", payment.ID)
+	}
+	if total <= threshold {
+		return nil, nil
+	}
+	return policy, nil
+}
+
+// checkApprover checks that the caller may approve or reject the payment. A
+// single-approval payment is decided by its funder as before. Under a policy
+// the listed approvers decide, or without a list the funder and the finance
+// and program officer users of the funder's org.
+func checkApprover(caller *Caller, funder *Funder, payment *Payment, policy *ApprovalPolicy, action string) error {
+	if policy == nil {
+		return checkPaymentFunder(caller, funder, payment, action, RoleFinance)
+	}
+	if len(policy.Approvers) == 0 {
+		return checkPaymentFunder(caller, funder, payment, action, RoleFinance, RoleProgramOfficer)
+	}
+	for _, approver := range policy.Approvers {
+		if caller.ID == approver {
+			return nil
+		}
+	}
+	return errForbidden("User %s is not an approver of the Payment %s", caller.ID, payment.ID).with("payment_id", payment.ID).with("approvers", policy.Approvers)
+}
+
+// checkApprovalRole rejects approvals that can't count towards the roles of
+// the policy. An approval records the caller's role attribute, so one from a
+// caller without it covers no role; were every approver without one, the
+// payment would wait for its roles forever.
+func checkApprovalRole(caller *Caller, payment *Payment, policy *ApprovalPolicy) error {
+	if policy == nil || len(policy.Roles) == 0 || caller.Role != "" {
+		return nil
+	}
+	return errForbidden("User %s has no %s attribute, which approvals of the Payment %s need to cover the roles %v", caller.ID, roleAttribute, payment.ID, policy.Roles).
+		with("payment_id", payment.ID).
+		with("roles", policy.Roles)
+}
+
+// hasApproved reports whether userId already approved the payment.
+func hasApproved(payment *Payment, userId string) bool {
+	for _, approval := range payment.Approvals {
+		if approval.By == userId {
+			return true
+		}
+	}
+	return false
+}
+
+// approvalsNeeded returns how many more approvals the payment needs under the
+// policy and which of the policy roles no approval covers yet.
+func approvalsNeeded(policy *ApprovalPolicy, approvals []Approval) (int, []Role) {
+	required := 1
+	if policy != nil {
+		required = policy.Approvals
+	}
+	needed := required - len(approvals)
+	if needed < 0 {
+		needed = 0
+	}
+	if policy == nil || len(policy.Roles) == 0 {
+		return needed, nil
+	}
+
+	// An approval with exactly the role covers it first; admins cover the rest
+	used := make([]bool, len(approvals))
+	var uncovered []Role
+	for _, role := range policy.Roles {
+		covered := false
+		for i, approval := range approvals {
+			if !used[i] && approval.Role == role {
+				used[i] = true
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			uncovered = append(uncovered, role)
+		}
+	}
+	var missing []Role
+	for _, role := range uncovered {
+		covered := false
+		for i, approval := range approvals {
+			if !used[i] && approval.Role == RoleAdmin {
+				used[i] = true
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			missing = append(missing, role)
+		}
+	}
+	if needed < len(missing) {
+		needed = len(missing)
+	}
+	return needed, missing
+}
+
+// approvePayment records the caller's approval of a requested payment and
+// moves it to Accepted once its policy is satisfied. It reports whether it did.
+func approvePayment(ctx contractapi.TransactionContextInterface, caller *Caller, payment *Payment, policy *ApprovalPolicy) (bool, error) {
+	err := checkPaymentTransition(payment, PaymentAccepted)
+	if err != nil {
+		return false, err
+	}
+	if hasApproved(payment, caller.ID) {
+		return false, errDuplicate("User %s has already approved the Payment %s", caller.ID, payment.ID).with("payment_id", payment.ID)
+	}
+	err = checkApprovalRole(caller, payment, policy)
+	if err != nil {
+		return false, err
+	}
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return false, err
+	}
+	payment.Approvals = append(payment.Approvals, Approval{
+		By:   caller.ID,
+		MSP:  caller.MSPID,
+		Role: caller.Role,
+		At:   now,
+	})
+
+	needed, _ := approvalsNeeded(policy, payment.Approvals)
+	if needed > 0 {
+		return false, nil
+	}
+	err = transitionPayment(ctx, payment, PaymentAccepted)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// SetApprovalPolicy sets the approval policy of a grant from policyJSON, a
+// JSON ApprovalPolicy, or removes it if policyJSON is empty. Reimbursements
+// awaiting approval are decided by the policy in effect when they are next
+// approved - Grantor
+func (s *SmartContract) SetApprovalPolicy(ctx contractapi.TransactionContextInterface, grant_id string, policyJSON string) (bool, error) {
+	caller, err := authorize(ctx, "SetApprovalPolicy")
+	if err != nil {
+		return false, err
+	}
+
+	grant, err := readGrant(ctx, grant_id)
+	if err != nil {
+		return false, err
+	}
+	err = checkGrantOwner(caller, grant, "set the approval policy of")
+	if err != nil {
+		return false, err
+	}
+	err = checkGrantStatus(grant, GrantDraft, GrantAssigned, GrantActive, GrantSuspended)
+	if err != nil {
+		return false, err
+	}
+
+	var policy *ApprovalPolicy
+	if policyJSON != "" {
+		policy = &ApprovalPolicy{}
+		err = json.Unmarshal([]byte(policyJSON), policy)
+		if err != nil {
+			return false, errValidation("failed to unmarshal JSON: %v", err)
+		}
+		err = checkApprovalPolicy(policy, grantCurrency(grant), caller.MSPID)
+		if err != nil {
+			return false, err
+		}
+	}
+	grant.Approval_Policy = policy
+
+	err = putGrant(ctx, grant)
+	if err != nil {
+		return false, err
+	}
+
+	err = emitGrantEvent(ctx, caller, EventApprovalPolicySet, grant, grant.Status)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// GetPendingApprovals returns the requested reimbursements the user may
+// approve and hasn't approved yet, across every grant, in grant order.
+func (s *SmartContract) GetPendingApprovals(ctx contractapi.TransactionContextInterface) ([]PendingApproval, error) {
+	caller, err := authorize(ctx, "GetPendingApprovals")
+	if err != nil {
+		return nil, err
+	}
+	config, err := getOrgConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey("grant", []string{})
+	if err != nil {
+		return nil, errInternal("failed to query the world state: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	pending := []PendingApproval{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, errInternal("failed to read from world state: %v", err)
+		}
+
+		var grant Grant
+		err = json.Unmarshal(queryResponse.Value, &grant)
+		if err != nil {
+			return nil, errInternal("failed to unmarshal JSON: %v", err)
+		}
+		if len(grant.Payment) != 0 {
+			continue
+		}
+
+		payments, err := getGrantPayments(ctx, grant.ID)
+		if err != nil {
+			return nil, err
+		}
+		funders := grantFunders(&grant, config)
+		for _, payment := range payments {
+			if payment.Status != PaymentRequested || hasApproved(&payment, caller.ID) {
+				continue
+			}
+			funder := findFunder(funders, paymentFunderID(&grant, &payment))
+			if funder == nil {
+				continue
+			}
+			policy, err := paymentPolicy(&grant, &payment)
+			if err != nil {
+				return nil, err
+			}
+			if checkApprover(caller, funder, &payment, policy, "approve") != nil || checkApprovalRole(caller, &payment, policy) != nil {
+				continue
+			}
+
+			needed, roles := approvalsNeeded(policy, payment.Approvals)
+			pending = append(pending, PendingApproval{
+				Grant_ID:         grant.ID,
+				Payment:          payment,
+				Approvals_Needed: needed,
+				Roles_Needed:     roles,
+			})
+		}
+	}
+
+	return pending, nil
+}
@@ -0,0 +1,164 @@
+package chaincode
+
+import (
+	"testing"
+
+	"chaincode-go/chaincodetest"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+var (
+	officer = chaincodetest.NewIdentity(GrantorMSP, "gina").WithAttribute(roleAttribute, string(RoleProgramOfficer))
+	finance = chaincodetest.NewIdentity(GrantorMSP, "hank").WithAttribute(roleAttribute, string(RoleFinance))
+)
+
+func (l *ledger) setApprovalPolicy(grantID string, policy string) error {
+	l.t.Helper()
+	return l.run(grantor, nil, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.SetApprovalPolicy(ctx, grantID, policy)
+		return err
+	})
+}
+
+func TestApprovalPolicyValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy string
+		code   ErrorCode
+	}{
+		{name: "no approvals", policy: `{"threshold":"100","approvals":0}`, code: CodeValidation},
+		{name: "negative threshold", policy: `{"threshold":"-1","approvals":1}`, code: CodeValidation},
+		{name: "too many decimals", policy: `{"threshold":"1.001","approvals":1}`, code: CodeValidation},
+		{name: "listed twice", policy: `{"threshold":"100","approvals":1,"approvers":["alice","GrantorMSP/alice"]}`, code: CodeDuplicate},
+		{name: "more approvals than approvers", policy: `{"threshold":"100","approvals":3,"approvers":["alice","gina"]}`, code: CodeValidation},
+		{name: "unknown role", policy: `{"threshold":"100","approvals":1,"roles":["treasurer"]}`, code: CodeValidation},
+		{name: "more roles than approvals", policy: `{"threshold":"100","approvals":1,"roles":["finance","program_officer"]}`, code: CodeValidation},
+		{name: "valid", policy: `{"threshold":"100","approvals":2,"approvers":["alice","gina"],"roles":["finance"]}`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			l := newLedger(t)
+			l.activeGrant("G1")
+			err := l.setApprovalPolicy("G1", test.policy)
+			if test.code == "" {
+				noError(t, err)
+				return
+			}
+			wantCode(t, err, test.code)
+		})
+	}
+}
+
+func TestApprovalPolicyThreshold(t *testing.T) {
+	l := newLedger(t)
+	l.activeGrant("G1")
+	l.must(l.setApprovalPolicy("G1", `{"threshold":"100","approvals":2}`))
+
+	_, err := l.reimburse(awardee, "G1", "small", "AwardeeMSP/bob", item("Personnel", "100"))
+	l.must(err)
+	l.must(l.accept(grantor, "G1", "small"))
+	if status := l.payment("G1", "small").Status; status != PaymentAccepted {
+		t.Fatalf("payment at the threshold is %s after one approval", status)
+	}
+
+	_, err = l.reimburse(awardee, "G1", "large", "AwardeeMSP/bob", item("Personnel", "100.01"))
+	l.must(err)
+	l.must(l.accept(grantor, "G1", "large"))
+	if status := l.payment("G1", "large").Status; status != PaymentRequested {
+		t.Fatalf("payment above the threshold is %s after one approval", status)
+	}
+	wantCode(t, l.accept(grantor, "G1", "large"), CodeDuplicate)
+
+	var pending []PendingApproval
+	l.must(l.run(finance, nil, func(ctx contractapi.TransactionContextInterface) error {
+		var err error
+		pending, err = l.contract.GetPendingApprovals(ctx)
+		return err
+	}))
+	if len(pending) != 1 || pending[0].Payment.ID != "large" || pending[0].Approvals_Needed != 1 {
+		t.Fatalf("got pending approvals %+v", pending)
+	}
+
+	l.must(l.accept(finance, "G1", "large"))
+	payment := l.payment("G1", "large")
+	if payment.Status != PaymentAccepted || len(payment.Approvals) != 2 || payment.Approvals[0].By != "GrantorMSP/alice" || payment.Approvals[1].By != "GrantorMSP/hank" {
+		t.Fatalf("got payment %+v", payment)
+	}
+}
+
+func TestApprovalPolicyApprovers(t *testing.T) {
+	l := newLedger(t)
+	l.activeGrant("G1")
+	l.must(l.setApprovalPolicy("G1", `{"threshold":"0","approvals":2,"approvers":["alice","gina"]}`))
+
+	_, err := l.reimburse(awardee, "G1", "p1", "AwardeeMSP/bob", item("Personnel", "10"))
+	l.must(err)
+
+	wantCode(t, l.accept(finance, "G1", "p1"), CodeForbidden)
+	// gina of another grantor org is not the listed approver
+	l.withAgency()
+	impostor := chaincodetest.NewIdentity("AgencyMSP", "gina").WithAttribute(roleAttribute, string(RoleProgramOfficer))
+	wantCode(t, l.accept(impostor, "G1", "p1"), CodeForbidden)
+
+	l.must(l.accept(officer, "G1", "p1"))
+	l.must(l.accept(grantor, "G1", "p1"))
+	if status := l.payment("G1", "p1").Status; status != PaymentAccepted {
+		t.Fatalf("payment is %s after both approvers", status)
+	}
+}
+
+func TestApprovalPolicyRoles(t *testing.T) {
+	l := newLedger(t)
+	l.activeGrant("G1")
+	l.must(l.setApprovalPolicy("G1", `{"threshold":"0","approvals":2,"roles":["program_officer","finance"]}`))
+
+	_, err := l.reimburse(awardee, "G1", "p1", "AwardeeMSP/bob", item("Personnel", "10"))
+	l.must(err)
+
+	// Two finance approvals don't cover the program officer
+	l.must(l.accept(finance, "G1", "p1"))
+	l.must(l.accept(chaincodetest.NewIdentity(GrantorMSP, "ivy").WithAttribute(roleAttribute, string(RoleFinance)), "G1", "p1"))
+	if status := l.payment("G1", "p1").Status; status != PaymentRequested {
+		t.Fatalf("payment is %s without a program officer", status)
+	}
+
+	l.must(l.accept(officer, "G1", "p1"))
+	if status := l.payment("G1", "p1").Status; status != PaymentAccepted {
+		t.Fatalf("payment is %s after every role approved", status)
+	}
+}
+
+func TestApprovalPolicyRolesNeedARole(t *testing.T) {
+	l := newLedger(t)
+	l.activeGrant("G1")
+	// Without required roles a roleless approver passes the permission table
+	l.must(l.run(grantor, nil, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.SetOrgConfig(ctx, `{"grantor_msp":"GrantorMSP","awardee_msp":"AwardeeMSP","subawardee_msp":"SubawardeeMSP","auditor_msp":"AuditorMSP","require_roles":false}`)
+		return err
+	}))
+	l.must(l.setApprovalPolicy("G1", `{"threshold":"0","approvals":1,"approvers":["ivy","gina"],"roles":["program_officer"]}`))
+
+	_, err := l.reimburse(awardee, "G1", "p1", "AwardeeMSP/bob", item("Personnel", "10"))
+	l.must(err)
+
+	wantCode(t, l.accept(chaincodetest.NewIdentity(GrantorMSP, "ivy"), "G1", "p1"), CodeForbidden)
+	l.must(l.accept(officer, "G1", "p1"))
+	if status := l.payment("G1", "p1").Status; status != PaymentAccepted {
+		t.Fatalf("payment is %s after the program officer approved", status)
+	}
+}
+
+func TestRemovedApprovalPolicy(t *testing.T) {
+	l := newLedger(t)
+	l.activeGrant("G1")
+	l.must(l.setApprovalPolicy("G1", `{"threshold":"0","approvals":2}`))
+	l.must(l.setApprovalPolicy("G1", ""))
+
+	_, err := l.reimburse(awardee, "G1", "p1", "AwardeeMSP/bob", item("Personnel", "10"))
+	l.must(err)
+	l.must(l.accept(grantor, "G1", "p1"))
+	if status := l.payment("G1", "p1").Status; status != PaymentAccepted {
+		t.Fatalf("payment is %s after one approval without a policy", status)
+	}
+}
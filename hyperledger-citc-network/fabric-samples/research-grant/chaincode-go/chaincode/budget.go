@@ -0,0 +1,393 @@
+package chaincode
+
+import (
+	"sort"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// The budget of a grant - its Amount, Benefit lines and Funder shares - is
+// edited in place with UpdateGrant until the awardee accepts the grant. From
+// then on it only changes by a budget amendment: the grantor proposes the new
+// budget with a reason, and it takes effect once the main awardee
+// countersigns it. Each amendment that takes effect, and each approved
+// rebudget, makes a new version of the budget, recorded with the one it
+// replaced. Version 0 is the budget the awardee accepted.
+//
+// No budget may leave a benefit line, or a funder's share of one, below what
+// the payments still drawing on the grant have committed of it.
+
+// Budget is one version of a grant's budget.
+type Budget struct {
+	Version int       `json:"version"`
+	Amount  Money     `json:"amount"`
+	Benefit []Benefit `json:"benefit"`
+	Funder  []Funder  `json:"funder,omitempty" metadata:"funder,optional"`
+}
+
+// budgetOf returns a copy of the grant's current budget, without the totals
+// derived from payments.
+func budgetOf(grant *Grant) *Budget {
+	budget := &Budget{
+		Version: grant.Budget_Version,
+		Amount:  grant.Amount,
+		Benefit: append([]Benefit{}, grant.Benefit...),
+	}
+	for _, funder := range grant.Funder {
+		funder.Benefit = append([]Benefit{}, funder.Benefit...)
+		funder.Paid_Amount = ""
+		funder.Cashed_Out = ""
+		budget.Funder = append(budget.Funder, funder)
+	}
+	return budget
+}
+
+// checkNewBudget validates a new budget for the grant given as the Amount,
+// Benefit and Funder of input, and returns it in canonical form. Funder
+// shares are kept unless new ones are given, and must fit the new budget.
+func checkNewBudget(ctx contractapi.TransactionContextInterface, grant *Grant, input *Grant) (*Budget, error) {
+	input.Currency = grantCurrency(grant)
+	err := checkBudget(input)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(input.Funder) == 0 {
+		input.Funder = grant.Funder
+	}
+	config, err := getOrgConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = checkFunders(input, config)
+	if err != nil {
+		return nil, err
+	}
+	err = checkFundersKept(ctx, grant, input.Funder)
+	if err != nil {
+		return nil, err
+	}
+	err = checkCommittedSpend(ctx, grant, input)
+	if err != nil {
+		return nil, err
+	}
+
+	budget := budgetOf(input)
+	budget.Version = grant.Budget_Version
+	return budget, nil
+}
+
+// checkCommittedSpend fails if the new budget in input leaves a benefit line,
+// or a funder's share of one, below what the grant's payments have committed of it.
+func checkCommittedSpend(ctx contractapi.TransactionContextInterface, grant *Grant, input *Grant) error {
+	err := checkPaymentsMigrated(grant)
+	if err != nil {
+		return err
+	}
+	payments, err := getGrantPayments(ctx, grant.ID)
+	if err != nil {
+		return err
+	}
+
+	currency := grantCurrency(grant)
+	spent := map[string]int64{}
+	funderSpent := map[string]map[string]int64{}
+	for _, payment := range payments {
+		if !payment.Status.committed() {
+			continue
+		}
+		funderID := paymentFunderID(grant, &payment)
+		if funderSpent[funderID] == nil {
+			funderSpent[funderID] = map[string]int64{}
+		}
+		for _, item := range payment.Item {
+			units, err := item.Amount.minor(currency)
+			if err != nil {
+				return storedAmountInvalid(err, "Payment %s has an invalid amount", payment.ID)
+			}
+			spent[item.Benefit] += units
+			funderSpent[funderID][item.Benefit] += units
+		}
+	}
+
+	err = checkLinesCover(grant, input.Benefit, spent, "")
+	if err != nil {
+		return err
+	}
+	for _, funder := range input.Funder {
+		err = checkLinesCover(grant, funder.Benefit, funderSpent[funder.Funder_ID], funder.Funder_ID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkLinesCover fails if one of the benefit lines is below what is spent
+// of it. Lines that are spent but missing count as zero. funderID names the
+// funder whose share the lines are, if any.
+func checkLinesCover(grant *Grant, lines []Benefit, spent map[string]int64, funderID string) error {
+	currency := grantCurrency(grant)
+	amounts := map[string]int64{}
+	for _, line := range lines {
+		units, err := line.Amount.minor(currency)
+		if err != nil {
+			return invalidAmount(err, "invalid amount for %s benefit", line.Benefit)
+		}
+		amounts[line.Benefit] += units
+	}
+
+	spentLines := make([]string, 0, len(spent))
+	for line := range spent {
+		spentLines = append(spentLines, line)
+	}
+	sort.Strings(spentLines)
+	for _, line := range spentLines {
+		if amounts[line] >= spent[line] {
+			continue
+		}
+		var err *ContractError
+		if funderID == "" {
+			err = errBudgetExceeded("%s benefit can't be set to %s, %s of it is already committed", line, moneyFromMinor(amounts[line], currency), moneyFromMinor(spent[line], currency))
+		} else {
+			err = errBudgetExceeded("the share of funder %s for %s benefit can't be set to %s, %s of it is already committed", funderID, line, moneyFromMinor(amounts[line], currency), moneyFromMinor(spent[line], currency)).
+				with("funder_id", funderID)
+		}
+		return err.
+			with("grant_id", grant.ID).
+			with("benefit", line).
+			with("amount", moneyFromMinor(amounts[line], currency)).
+			with("committed", moneyFromMinor(spent[line], currency))
+	}
+	return nil
+}
+
+// putGrantBudget replaces the budget of the grant and writes it, keeping its
+// indexes and endorsement policy in line with its funders.
+func putGrantBudget(ctx contractapi.TransactionContextInterface, grant *Grant, budget *Budget) error {
+	err := deleteGrantIndexes(ctx, grant)
+	if err != nil {
+		return err
+	}
+	grant.Amount = budget.Amount
+	grant.Benefit = budget.Benefit
+	grant.Funder = budget.Funder
+	grant.Budget_Version = budget.Version
+
+	err = putGrant(ctx, grant)
+	if err != nil {
+		return err
+	}
+	err = putGrantIndexes(ctx, grant)
+	if err != nil {
+		return err
+	}
+	return setGrantEndorsement(ctx, grant, true)
+}
+
+// budgetVersions returns every version of the grant's budget, oldest first.
+func budgetVersions(grant *Grant) []*Budget {
+	var versions []*Budget
+	for i := range grant.Amendments {
+		amendment := &grant.Amendments[i]
+		if amendment.Budget == nil || amendment.Status != AmendmentApproved {
+			continue
+		}
+		if len(versions) == 0 {
+			versions = append(versions, amendment.Previous_Budget)
+		}
+		versions = append(versions, amendment.Budget)
+	}
+	if len(versions) == 0 {
+		versions = append(versions, budgetOf(grant))
+	}
+	return versions
+}
+
+// ProposeAmendment proposes a new budget for an accepted grant. The amendment
+// transient input holds the grant ID, the reason and the new amount, benefit
+// lines and, optionally, funder shares. The budget takes effect once the main
+// awardee countersigns it. It returns the amendment number - Grantor
+func (s *SmartContract) ProposeAmendment(ctx contractapi.TransactionContextInterface) (int, error) {
+	caller, err := authorize(ctx, "ProposeAmendment")
+	if err != nil {
+		return 0, err
+	}
+
+	transientMap, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return 0, errInternal("error getting transient: %v", err)
+	}
+	var input amendmentTransientInput
+	err = readTransientInput(transientMap, "amendment", &input)
+	if err != nil {
+		return 0, err
+	}
+
+	grant, err := readGrant(ctx, input.Grant_ID)
+	if err != nil {
+		return 0, err
+	}
+	err = checkGrantOwner(caller, grant, "amend")
+	if err != nil {
+		return 0, err
+	}
+	err = checkGrantStatus(grant, GrantActive, GrantSuspended)
+	if err != nil {
+		return 0, err
+	}
+	if pending := pendingBudgetChange(grant); pending != nil {
+		return 0, budgetChangePending(grant, pending)
+	}
+
+	budget, err := checkNewBudget(ctx, grant, &Grant{ID: grant.ID, Amount: input.Amount, Benefit: input.Benefit, Funder: input.Funder})
+	if err != nil {
+		return 0, err
+	}
+	budget.Version = grant.Budget_Version + 1
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return 0, err
+	}
+	amendment := addAmendment(grant, Amendment{
+		Type:            AmendmentBudget,
+		Status:          AmendmentRequested,
+		Reason:          input.Reason,
+		Requested_By:    caller.ID,
+		Requested_At:    now,
+		Previous_Budget: budgetOf(grant),
+		Budget:          budget,
+	})
+
+	err = putGrant(ctx, grant)
+	if err != nil {
+		return 0, err
+	}
+
+	err = emitAmendmentEvent(ctx, caller, EventAmendmentProposed, grant, amendment)
+	if err != nil {
+		return 0, err
+	}
+	return amendment.Number, nil
+}
+
+// CountersignAmendment countersigns the proposed budget amendment with the
+// number, putting its budget into effect as the next version - Main Awardee
+func (s *SmartContract) CountersignAmendment(ctx contractapi.TransactionContextInterface, grant_id string, number int) (bool, error) {
+	caller, grant, amendment, err := readProposedAmendment(ctx, "CountersignAmendment", grant_id, number)
+	if err != nil {
+		return false, err
+	}
+
+	// Payments made since the proposal must still be covered
+	budget := *amendment.Budget
+	input := &Grant{ID: grant.ID, Amount: budget.Amount, Benefit: append([]Benefit{}, budget.Benefit...), Funder: budget.Funder}
+	_, err = checkNewBudget(ctx, grant, input)
+	if err != nil {
+		return false, err
+	}
+
+	now, err := txTimestamp(ctx)
+	if err != nil {
+		return false, err
+	}
+	at := now.Format(time.RFC3339)
+	amendment.Previous_Budget = budgetOf(grant)
+	budget.Version = grant.Budget_Version + 1
+	amendment.Budget = &budget
+	decideAmendment(amendment, AmendmentApproved, caller, at, "")
+	amendment.Effective_Date = at
+
+	err = putGrantBudget(ctx, grant, &budget)
+	if err != nil {
+		return false, err
+	}
+
+	err = emitAmendmentEvent(ctx, caller, EventAmendmentCountersigned, grant, amendment)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// DeclineAmendment declines the proposed budget amendment with the number;
+// the grant keeps its budget - Main Awardee
+func (s *SmartContract) DeclineAmendment(ctx contractapi.TransactionContextInterface, grant_id string, number int, reason string) (bool, error) {
+	caller, grant, amendment, err := readProposedAmendment(ctx, "DeclineAmendment", grant_id, number)
+	if err != nil {
+		return false, err
+	}
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return false, err
+	}
+	decideAmendment(amendment, AmendmentDenied, caller, now, reason)
+
+	err = putGrant(ctx, grant)
+	if err != nil {
+		return false, err
+	}
+
+	err = emitAmendmentEvent(ctx, caller, EventAmendmentDeclined, grant, amendment)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// readProposedAmendment authorizes the main awardee of the grant to decide
+// the budget amendment with the number and returns it.
+func readProposedAmendment(ctx contractapi.TransactionContextInterface, function string, grantID string, number int) (*Caller, *Grant, *Amendment, error) {
+	caller, err := authorize(ctx, function)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	grant, err := readGrant(ctx, grantID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if !checkAwardee(grant.Awardee, caller.ID) {
+		return nil, nil, nil, awardeeNotAssigned(caller.ID, grant)
+	}
+	err = checkGrantStatus(grant, GrantActive, GrantSuspended)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	amendment, err := findAmendment(grant, number)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	err = checkAmendmentRequested(grant, amendment, AmendmentBudget)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return caller, grant, amendment, nil
+}
+
+// GetBudgetAsOf returns version of the grant's budget. Version 0 is the
+// budget the awardee accepted.
+func (s *SmartContract) GetBudgetAsOf(ctx contractapi.TransactionContextInterface, grant_id string, version int) (*Budget, error) {
+	caller, err := authorize(ctx, "GetBudgetAsOf")
+	if err != nil {
+		return nil, err
+	}
+
+	grant, _, err := readGrantAs(ctx, caller, grant_id)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := budgetVersions(grant)
+	if version < 0 || version >= len(versions) {
+		return nil, errNotFound("Grant %s has no budget version %d", grant.ID, version).
+			with("grant_id", grant.ID).
+			with("version", version).
+			with("latest", len(versions)-1)
+	}
+	return versions[version], nil
+}
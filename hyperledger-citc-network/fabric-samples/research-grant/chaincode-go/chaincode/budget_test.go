@@ -0,0 +1,135 @@
+package chaincode
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// proposeBudget has alice propose a budget of Personnel and Equipment.
+func (l *ledger) proposeBudget(grantID string, personnel string, equipment string, amount string) (int, error) {
+	l.t.Helper()
+	input := map[string]interface{}{
+		"grant_id": grantID,
+		"reason":   "supplement for a second site",
+		"amount":   amount,
+		"benefit":  []map[string]interface{}{item("Personnel", personnel), item("Equipment", equipment)},
+	}
+	var number int
+	err := l.run(grantor, map[string]interface{}{"amendment": input}, func(ctx contractapi.TransactionContextInterface) error {
+		var err error
+		number, err = l.contract.ProposeAmendment(ctx)
+		return err
+	})
+	return number, err
+}
+
+func (l *ledger) countersign(grantID string, number int) error {
+	l.t.Helper()
+	return l.run(awardee, nil, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.CountersignAmendment(ctx, grantID, number)
+		return err
+	})
+}
+
+func (l *ledger) budgetAsOf(grantID string, version int) (*Budget, error) {
+	l.t.Helper()
+	var budget *Budget
+	err := l.run(awardee, nil, func(ctx contractapi.TransactionContextInterface) error {
+		var err error
+		budget, err = l.contract.GetBudgetAsOf(ctx, grantID, version)
+		return err
+	})
+	return budget, err
+}
+
+func TestBudgetAmendment(t *testing.T) {
+	l := newLedger(t)
+	l.activeGrant("G1")
+
+	number, err := l.proposeBudget("G1", "900", "600", "1500")
+	l.must(err)
+	_, err = l.proposeBudget("G1", "600", "400", "1000")
+	wantCode(t, err, CodeDuplicate)
+
+	// Nothing changes until the awardee countersigns
+	_, err = l.reimburse(awardee, "G1", "P1", "AwardeeMSP/bob", item("Personnel", "700"))
+	wantCode(t, err, CodeBudgetExceeded)
+
+	l.must(l.countersign("G1", number))
+	wantCode(t, l.countersign("G1", number), CodeInvalidState)
+	_, err = l.reimburse(awardee, "G1", "P1", "AwardeeMSP/bob", item("Personnel", "700"))
+	l.must(err)
+
+	grant, err := l.read(grantor, "G1")
+	l.must(err)
+	if grant.Amount != "1500.00" || grant.Budget_Version != 1 {
+		t.Fatalf("got amount %s at version %d", grant.Amount, grant.Budget_Version)
+	}
+
+	original, err := l.budgetAsOf("G1", 0)
+	l.must(err)
+	if original.Amount != "1000.00" || original.Benefit[0].Amount != "600.00" {
+		t.Fatalf("got version 0 %+v", original)
+	}
+	amended, err := l.budgetAsOf("G1", 1)
+	l.must(err)
+	if amended.Version != 1 || amended.Amount != "1500.00" {
+		t.Fatalf("got version 1 %+v", amended)
+	}
+	_, err = l.budgetAsOf("G1", 2)
+	wantCode(t, err, CodeNotFound)
+}
+
+func TestBudgetAmendmentKeepsCommittedSpend(t *testing.T) {
+	l := newLedger(t)
+	l.activeGrant("G1")
+	_, err := l.reimburse(awardee, "G1", "P1", "AwardeeMSP/bob", item("Personnel", "500"))
+	l.must(err)
+
+	_, err = l.proposeBudget("G1", "499.99", "400", "899.99")
+	wantCode(t, err, CodeBudgetExceeded)
+	_, err = l.proposeBudget("G1", "600", "400", "999")
+	wantCode(t, err, CodeValidation)
+
+	// A payment made after the proposal is checked on countersigning
+	number, err := l.proposeBudget("G1", "500", "400", "900")
+	l.must(err)
+	_, err = l.reimburse(awardee, "G1", "P2", "AwardeeMSP/bob", item("Personnel", "50"))
+	l.must(err)
+	wantCode(t, l.countersign("G1", number), CodeBudgetExceeded)
+}
+
+func TestDeclinedBudgetAmendment(t *testing.T) {
+	l := newLedger(t)
+	l.activeGrant("G1")
+
+	number, err := l.proposeBudget("G1", "900", "600", "1500")
+	l.must(err)
+	l.must(l.run(awardee, nil, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.DeclineAmendment(ctx, "G1", number, "we can't staff a second site")
+		return err
+	}))
+
+	grant, err := l.read(grantor, "G1")
+	l.must(err)
+	if grant.Amount != "1000.00" || grant.Amendments[0].Status != AmendmentDenied {
+		t.Fatalf("got amount %s and amendment %+v", grant.Amount, grant.Amendments[0])
+	}
+
+	// The summary view leaves out the reasons
+	summary, err := l.read(auditor, "G1")
+	l.must(err)
+	if summary.Amendments[0].Reason != "" || summary.Amendments[0].Decision_Notes != "" {
+		t.Fatalf("auditor got amendment %+v", summary.Amendments[0])
+	}
+}
+
+func TestBudgetIsAmendedOnlyOnceAccepted(t *testing.T) {
+	l := newLedger(t)
+	l.must(l.initiate(newGrant("G1")))
+	l.must(l.assign("G1", testAwardee("bob", "Main", AwardeeMSP)))
+
+	_, err := l.proposeBudget("G1", "900", "600", "1500")
+	wantCode(t, err, CodeInvalidState)
+}
@@ -0,0 +1,242 @@
+package chaincode
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/hyperledger/fabric-chaincode-go/pkg/statebased"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Grant and payment keys carry a key-level endorsement policy, so the
+// chaincode-level policy alone can't change them. The policy needs a peer of
+// every org taking part in the grant: the orgs funding it and, once it is
+// assigned, the orgs of its awardees and subawardees. It is set when the
+// grant is created and whenever its participants change.
+//
+// The policy names peer identities, which requires NodeOUs on the channel's MSPs.
+const endorsementRole = statebased.RoleTypePeer
+
+// GrantEndorsement describes the endorsement policy of a grant. Orgs is the
+// policy set on the grant key, empty if it has none; Expected is the policy
+// its current participants call for. UpdateGrantEndorsement brings the two in
+// line when they differ.
+type GrantEndorsement struct {
+	Grant_ID string   `json:"grant_id"`
+	Orgs     []string `json:"orgs"`
+	Expected []string `json:"expected"`
+	In_Sync  bool     `json:"in_sync"`
+}
+
+// awardeeMSP returns the MSP ID of the org an awardee belongs to. Awardees
+// record their org by name, so it is matched against the configured orgs;
+// unknown names fall back to the awardee or subawardee org by awardee type.
+func awardeeMSP(awardee *Awardee, config *OrgConfig) string {
+	mspIDs := append(config.grantorMSPs(), config.Awardee_MSP, config.Subawardee_MSP)
+	for _, mspID := range mspIDs {
+		if strings.EqualFold(orgName(mspID), awardee.Organization) {
+			return mspID
+		}
+	}
+	if awardee.Awardee_Type == "Sub" {
+		return config.Subawardee_MSP
+	}
+	return config.Awardee_MSP
+}
+
+// grantEndorsementOrgs returns the sorted MSP IDs whose peers must endorse
+// changes to the grant.
+func grantEndorsementOrgs(grant *Grant, config *OrgConfig) []string {
+	seen := map[string]bool{}
+	orgs := []string{}
+	add := func(mspID string) {
+		if mspID != "" && !seen[mspID] {
+			seen[mspID] = true
+			orgs = append(orgs, mspID)
+		}
+	}
+
+	for _, funder := range grantFunders(grant, config) {
+		add(funder.MSP)
+	}
+	for i := range grant.Awardee {
+		add(awardeeMSP(&grant.Awardee[i], config))
+	}
+	sort.Strings(orgs)
+	return orgs
+}
+
+// setKeyEndorsement requires a peer of each of orgs to endorse changes to key.
+func setKeyEndorsement(ctx contractapi.TransactionContextInterface, key string, orgs []string) error {
+	ep, err := statebased.NewStateEP(nil)
+	if err != nil {
+		return errInternal("failed to create endorsement policy: %v", err)
+	}
+	err = ep.AddOrgs(endorsementRole, orgs...)
+	if err != nil {
+		return errInternal("failed to add orgs to endorsement policy: %v", err)
+	}
+	policy, err := ep.Policy()
+	if err != nil {
+		return errInternal("failed to create endorsement policy: %v", err)
+	}
+	err = ctx.GetStub().SetStateValidationParameter(key, policy)
+	if err != nil {
+		return errInternal("failed to set endorsement policy of %s: %v", key, err)
+	}
+	return nil
+}
+
+// keyEndorsementOrgs returns the sorted orgs the endorsement policy of key
+// requires, or an empty list if the key has no policy of its own.
+func keyEndorsementOrgs(ctx contractapi.TransactionContextInterface, key string) ([]string, error) {
+	policy, err := ctx.GetStub().GetStateValidationParameter(key)
+	if err != nil {
+		return nil, errInternal("failed to read endorsement policy of %s: %v", key, err)
+	}
+	if len(policy) == 0 {
+		return []string{}, nil
+	}
+
+	ep, err := statebased.NewStateEP(policy)
+	if err != nil {
+		return nil, errInternal("failed to parse endorsement policy of %s: %v", key, err)
+	}
+	orgs := ep.ListOrgs()
+	sort.Strings(orgs)
+	return orgs, nil
+}
+
+// setGrantEndorsement sets the endorsement policy of the grant key, and with
+// payments that of each of its payment records, from the grant's participants.
+func setGrantEndorsement(ctx contractapi.TransactionContextInterface, grant *Grant, payments bool) error {
+	config, err := getOrgConfig(ctx)
+	if err != nil {
+		return err
+	}
+	orgs := grantEndorsementOrgs(grant, config)
+
+	grantKey, err := ctx.GetStub().CreateCompositeKey("grant", []string{grant.ID})
+	if err != nil {
+		return errInternal("failed to create grant key: %v", err)
+	}
+	err = setKeyEndorsement(ctx, grantKey, orgs)
+	if err != nil {
+		return err
+	}
+	if !payments {
+		return nil
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(paymentObjectType, []string{grant.ID})
+	if err != nil {
+		return errInternal("failed to query the world state: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return errInternal("failed to read from world state: %v", err)
+		}
+		err = setKeyEndorsement(ctx, queryResponse.Key, orgs)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setPaymentEndorsement gives a new payment record the endorsement policy of its grant.
+func setPaymentEndorsement(ctx contractapi.TransactionContextInterface, grant *Grant, payment *Payment) error {
+	config, err := getOrgConfig(ctx)
+	if err != nil {
+		return err
+	}
+	paymentKey, err := ctx.GetStub().CreateCompositeKey(paymentObjectType, []string{grant.ID, payment.ID})
+	if err != nil {
+		return errInternal("failed to create payment key: %v", err)
+	}
+	return setKeyEndorsement(ctx, paymentKey, grantEndorsementOrgs(grant, config))
+}
+
+// grantEndorsement compares the policy on the grant key with the one its participants call for.
+func grantEndorsement(ctx contractapi.TransactionContextInterface, grant *Grant) (*GrantEndorsement, error) {
+	config, err := getOrgConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	grantKey, err := ctx.GetStub().CreateCompositeKey("grant", []string{grant.ID})
+	if err != nil {
+		return nil, errInternal("failed to create grant key: %v", err)
+	}
+	orgs, err := keyEndorsementOrgs(ctx, grantKey)
+	if err != nil {
+		return nil, err
+	}
+
+	endorsement := GrantEndorsement{
+		Grant_ID: grant.ID,
+		Orgs:     orgs,
+		Expected: grantEndorsementOrgs(grant, config),
+	}
+	endorsement.In_Sync = strings.Join(endorsement.Orgs, ",") == strings.Join(endorsement.Expected, ",")
+	return &endorsement, nil
+}
+
+// GetGrantEndorsement returns the endorsement policy of the grant and the one
+// its participants call for. Clients use Orgs to pick the endorsing peers of
+// transactions on the grant.
+func (s *SmartContract) GetGrantEndorsement(ctx contractapi.TransactionContextInterface, grant_id string) (*GrantEndorsement, error) {
+	caller, err := authorize(ctx, "GetGrantEndorsement")
+	if err != nil {
+		return nil, err
+	}
+
+	grant, _, err := readGrantAs(ctx, caller, grant_id)
+	if err != nil {
+		return nil, err
+	}
+	return grantEndorsement(ctx, grant)
+}
+
+// UpdateGrantEndorsement resets the endorsement policy of the grant and its
+// payments to the one its participants call for, e.g. for grants created
+// before key-level policies or after SetOrgConfig moved an org - Grantor
+func (s *SmartContract) UpdateGrantEndorsement(ctx contractapi.TransactionContextInterface, grant_id string) (*GrantEndorsement, error) {
+	caller, err := authorize(ctx, "UpdateGrantEndorsement")
+	if err != nil {
+		return nil, err
+	}
+
+	grant, err := readGrant(ctx, grant_id)
+	if err != nil {
+		return nil, err
+	}
+	err = checkGrantOwner(caller, grant, "update the endorsement policy of")
+	if err != nil {
+		return nil, err
+	}
+
+	err = setGrantEndorsement(ctx, grant, true)
+	if err != nil {
+		return nil, err
+	}
+
+	err = emitGrantEvent(ctx, caller, EventEndorsementUpdated, grant, grant.Status)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := getOrgConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	orgs := grantEndorsementOrgs(grant, config)
+	return &GrantEndorsement{
+		Grant_ID: grant.ID,
+		Orgs:     orgs,
+		Expected: orgs,
+		In_Sync:  true,
+	}, nil
+}
@@ -0,0 +1,80 @@
+package chaincode
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+func (l *ledger) endorsement(grantID string) *GrantEndorsement {
+	l.t.Helper()
+	var endorsement *GrantEndorsement
+	l.must(l.run(grantor, nil, func(ctx contractapi.TransactionContextInterface) error {
+		var err error
+		endorsement, err = l.contract.GetGrantEndorsement(ctx, grantID)
+		return err
+	}))
+	return endorsement
+}
+
+func TestGrantEndorsementFollowsParticipants(t *testing.T) {
+	l := newLedger(t)
+	l.withAgency()
+	l.must(l.initiate(coFundedGrant("G1")))
+	if got := l.endorsement("G1"); !got.In_Sync || !reflect.DeepEqual(got.Orgs, []string{"AgencyMSP", GrantorMSP}) {
+		t.Fatalf("draft grant has endorsement %+v", got)
+	}
+
+	l.must(l.assign("G1", testAwardee("bob", "Main", AwardeeMSP)))
+	l.must(l.run(awardee, nil, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.AcceptGrant(ctx, "G1")
+		return err
+	}))
+	_, err := l.reimburse(awardee, "G1", "P1", "AwardeeMSP/bob", item("Equipment", "10"))
+	l.must(err)
+	l.addSubawardee("G1")
+
+	want := []string{"AgencyMSP", AwardeeMSP, GrantorMSP, SubawardeeMSP}
+	if got := l.endorsement("G1"); !got.In_Sync || !reflect.DeepEqual(got.Orgs, want) {
+		t.Fatalf("got endorsement %+v, want %v", got, want)
+	}
+
+	var paymentOrgs []string
+	l.must(l.run(grantor, nil, func(ctx contractapi.TransactionContextInterface) error {
+		key, err := ctx.GetStub().CreateCompositeKey(paymentObjectType, []string{"G1", "P1"})
+		if err != nil {
+			return err
+		}
+		paymentOrgs, err = keyEndorsementOrgs(ctx, key)
+		return err
+	}))
+	if !reflect.DeepEqual(paymentOrgs, want) {
+		t.Fatalf("payment has endorsement %v, want %v", paymentOrgs, want)
+	}
+}
+
+func TestUpdateGrantEndorsement(t *testing.T) {
+	l := newLedger(t)
+	l.activeGrant("G1")
+
+	// A grant created before key-level policies has none
+	l.must(l.stub.Run(nil, func() error {
+		key, err := l.stub.CreateCompositeKey("grant", []string{"G1"})
+		if err != nil {
+			return err
+		}
+		return l.stub.SetStateValidationParameter(key, nil)
+	}))
+	if got := l.endorsement("G1"); got.In_Sync || len(got.Orgs) != 0 {
+		t.Fatalf("got endorsement %+v, want none", got)
+	}
+
+	l.must(l.run(grantor, nil, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.UpdateGrantEndorsement(ctx, "G1")
+		return err
+	}))
+	if got := l.endorsement("G1"); !got.In_Sync || !reflect.DeepEqual(got.Orgs, []string{AwardeeMSP, GrantorMSP}) {
+		t.Fatalf("got endorsement %+v after the update", got)
+	}
+}
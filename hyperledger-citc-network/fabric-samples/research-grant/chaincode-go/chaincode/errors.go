@@ -0,0 +1,146 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ErrorCode classifies a ContractError so clients can react to it without
+// parsing the message.
+type ErrorCode string
+
+const (
+	// CodeNotFound: a grant, payment, awardee or other record doesn't exist.
+	CodeNotFound ErrorCode = "NOT_FOUND"
+	// CodeUnauthenticated: the identity that submitted the transaction can't be resolved.
+	CodeUnauthenticated ErrorCode = "UNAUTHENTICATED"
+	// CodeForbidden: the caller may not call the function or act on the record.
+	CodeForbidden ErrorCode = "FORBIDDEN"
+	// CodeInvalidState: the grant or payment isn't in a status that allows the action.
+	CodeInvalidState ErrorCode = "INVALID_STATE"
+	// CodeBudgetExceeded: the amount requested exceeds what is left of a budget.
+	CodeBudgetExceeded ErrorCode = "BUDGET_EXCEEDED"
+	// CodeOutOfPeriod: the activity falls outside the grant's period of performance.
+	CodeOutOfPeriod ErrorCode = "OUT_OF_PERIOD"
+	// CodeDuplicate: a record with the same identity already exists.
+	CodeDuplicate ErrorCode = "DUPLICATE"
+	// CodeValidation: an argument or input is malformed or breaks a rule.
+	CodeValidation ErrorCode = "VALIDATION"
+	// CodeMigrationRequired: stored records must be migrated before the action.
+	CodeMigrationRequired ErrorCode = "MIGRATION_REQUIRED"
+	// CodeInternal: the ledger or a stored record failed unexpectedly.
+	CodeInternal ErrorCode = "INTERNAL"
+)
+
+// ContractError is the error every SmartContract function returns. Fabric
+// only passes the error message on to clients, so Error serializes the whole
+// error as JSON, e.g.
+//
+//	{"code":"NOT_FOUND","message":"Grant g1 does not exist","details":{"grant_id":"g1"}}
+//
+// Details carry the values a client needs to act on the error, such as the
+// remaining balance or the statuses that would have been allowed.
+type ContractError struct {
+	Code    ErrorCode              `json:"code"`
+	Message string                 `json:"message"`
+	Details map[string]interface{} `json:"details,omitempty"`
+
+	// err is the underlying error, kept for errors.Is and errors.As.
+	err error
+}
+
+func (e *ContractError) Error() string {
+	errorJSON, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Sprintf(`{"code":%q,"message":%q}`, e.Code, e.Message)
+	}
+	return string(errorJSON)
+}
+
+func (e *ContractError) Unwrap() error {
+	return e.err
+}
+
+// with adds a detail to the error.
+func (e *ContractError) with(key string, value interface{}) *ContractError {
+	if e.Details == nil {
+		e.Details = map[string]interface{}{}
+	}
+	e.Details[key] = value
+	return e
+}
+
+// wrapping keeps err as the underlying error.
+func (e *ContractError) wrapping(err error) *ContractError {
+	e.err = err
+	return e
+}
+
+func newContractError(code ErrorCode, format string, args ...interface{}) *ContractError {
+	return &ContractError{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+func errNotFound(format string, args ...interface{}) *ContractError {
+	return newContractError(CodeNotFound, format, args...)
+}
+
+func errForbidden(format string, args ...interface{}) *ContractError {
+	return newContractError(CodeForbidden, format, args...)
+}
+
+func errInvalidState(format string, args ...interface{}) *ContractError {
+	return newContractError(CodeInvalidState, format, args...)
+}
+
+func errBudgetExceeded(format string, args ...interface{}) *ContractError {
+	return newContractError(CodeBudgetExceeded, format, args...)
+}
+
+func errDuplicate(format string, args ...interface{}) *ContractError {
+	return newContractError(CodeDuplicate, format, args...)
+}
+
+func errValidation(format string, args ...interface{}) *ContractError {
+	return newContractError(CodeValidation, format, args...)
+}
+
+func errMigrationRequired(format string, args ...interface{}) *ContractError {
+	return newContractError(CodeMigrationRequired, format, args...)
+}
+
+func errInternal(format string, args ...interface{}) *ContractError {
+	return newContractError(CodeInternal, format, args...)
+}
+
+// grantNotFound is the error for a grant ID that has no grant.
+func grantNotFound(grantID string) *ContractError {
+	return errNotFound("Grant %s does not exist", grantID).with("grant_id", grantID)
+}
+
+// awardeeNotAssigned is the error for a user who isn't an awardee of the grant.
+func awardeeNotAssigned(awardeeID string, grant *Grant) *ContractError {
+	return errForbidden("Awardee %s is not assigned in the Grant %s", awardeeID, grant.ID).
+		with("grant_id", grant.ID).
+		with("awardee_id", awardeeID)
+}
+
+// invalidAmount is the error for an amount that doesn't parse or doesn't fit
+// the currency, e.g. "invalid amount for Travel benefit".
+func invalidAmount(err error, format string, args ...interface{}) *ContractError {
+	return errValidation("%s: %v", fmt.Sprintf(format, args...), err).wrapping(err)
+}
+
+// storedAmountInvalid is the error for a stored amount that predates decimal
+// amounts, e.g. "Payment p1 has an invalid amount".
+func storedAmountInvalid(err error, format string, args ...interface{}) *ContractError {
+	return errMigrationRequired("%s, run MigrateMoney first: %v", fmt.Sprintf(format, args...), err).
+		with("migration", "MigrateMoney").
+		wrapping(err)
+}
+
+// paymentNotFound is the error for a payment ID the grant has no payment for.
+func paymentNotFound(grantID string, paymentID string) *ContractError {
+	return errNotFound("Payment %s doesn't exist in the Grant %s", paymentID, grantID).
+		with("grant_id", grantID).
+		with("payment_id", paymentID)
+}
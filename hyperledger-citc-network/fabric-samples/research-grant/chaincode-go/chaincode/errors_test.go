@@ -0,0 +1,41 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestContractErrorIsJSON(t *testing.T) {
+	cause := errors.New("ledger unavailable")
+	err := errInternal("failed to read the Grant g1").with("grant_id", "g1").wrapping(cause)
+
+	var decoded struct {
+		Code    ErrorCode              `json:"code"`
+		Message string                 `json:"message"`
+		Details map[string]interface{} `json:"details"`
+	}
+	if jsonErr := json.Unmarshal([]byte(err.Error()), &decoded); jsonErr != nil {
+		t.Fatalf("error %q is not JSON: %v", err, jsonErr)
+	}
+	if decoded.Code != CodeInternal || decoded.Message != "failed to read the Grant g1" || decoded.Details["grant_id"] != "g1" {
+		t.Fatalf("got %+v", decoded)
+	}
+	if !errors.Is(err, cause) {
+		t.Fatalf("the underlying error was lost")
+	}
+}
+
+func TestBudgetErrorCarriesTheRemainingAmount(t *testing.T) {
+	l := newLedger(t)
+	l.activeGrant("G1")
+	_, err := l.reimburse(awardee, "G1", "P1", "AwardeeMSP/bob", item("Equipment", "150"))
+	noError(t, err)
+
+	_, err = l.reimburse(awardee, "G1", "P2", "AwardeeMSP/bob", item("Equipment", "300"))
+	wantCode(t, err, CodeBudgetExceeded)
+	details := err.(*ContractError).Details
+	if details["benefit"] != "Equipment" || details["requested"] != Money("300.00") || details["remaining"] != Money("250.00") {
+		t.Fatalf("got details %v", details)
+	}
+}
@@ -0,0 +1,258 @@
+package chaincode
+
+import (
+	"encoding/json"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// EventVersion is the version of the event payloads below. It is bumped
+// whenever a field is removed or changes meaning; added fields keep it.
+const EventVersion = 1
+
+// Names of the chaincode events. Every mutating transaction emits exactly one
+// of them; grant events carry a GrantEvent payload, reimbursement and redeem
+// events a PaymentEvent and migrations a MigrationEvent.
+const (
+	EventGrantInitiated  = "GrantInitiated"
+	EventGrantAssigned   = "GrantAssigned"
+	EventGrantAccepted   = "GrantAccepted"
+	EventGrantRejected   = "GrantRejected"
+	EventGrantRevoked    = "GrantRevoked"
+	EventGrantSuspended  = "GrantSuspended"
+	EventGrantReinstated = "GrantReinstated"
+	EventGrantClosed     = "GrantClosed"
+	EventGrantUpdated    = "GrantUpdated"
+	EventGrantDeleted    = "GrantDeleted"
+	EventAwardeeAdded    = "AwardeeAdded"
+	EventSubawardeeAdded = "SubawardeeAdded"
+	EventProgressAdded   = "ProgressAdded"
+
+	EventExtensionRequested = "ExtensionRequested"
+	EventExtensionApproved  = "ExtensionApproved"
+	EventExtensionDenied    = "ExtensionDenied"
+
+	EventAmendmentProposed      = "AmendmentProposed"
+	EventAmendmentCountersigned = "AmendmentCountersigned"
+	EventAmendmentDeclined      = "AmendmentDeclined"
+
+	EventRebudgetRequested    = "RebudgetRequested"
+	EventRebudgetApproved     = "RebudgetApproved"
+	EventRebudgetDenied       = "RebudgetDenied"
+	EventRebudgetThresholdSet = "RebudgetThresholdSet"
+
+	EventApprovalPolicySet  = "ApprovalPolicySet"
+	EventEndorsementUpdated = "EndorsementUpdated"
+
+	EventReimbursementRequested = "ReimbursementRequested"
+	EventReimbursementApproved  = "ReimbursementApproved"
+	EventReimbursementAccepted  = "ReimbursementAccepted"
+	EventReimbursementRejected  = "ReimbursementRejected"
+	EventRedeemRequested        = "RedeemRequested"
+	EventRedeemAccepted         = "RedeemAccepted"
+	EventRedeemRejected         = "RedeemRejected"
+
+	EventPaymentsMigrated       = "PaymentsMigrated"
+	EventMoneyMigrated          = "MoneyMigrated"
+	EventIndexesRebuilt         = "IndexesRebuilt"
+	EventIdentitiesMigrated     = "IdentitiesMigrated"
+	EventPrivateDetailsMigrated = "PrivateDetailsMigrated"
+
+	EventOrgConfigUpdated = "OrgConfigUpdated"
+)
+
+// EventHeader is common to every event payload.
+type EventHeader struct {
+	Version     int    `json:"version"`
+	Name        string `json:"name"`
+	Tx_ID       string `json:"tx_id"`
+	Timestamp   string `json:"timestamp"`
+	Actor       string `json:"actor"`
+	Actor_MSPID string `json:"actor_msp_id"`
+}
+
+// GrantEvent is emitted when a grant is created, changes status or is edited.
+// Previous_Status is empty for GrantInitiated; Awardee_ID is set by
+// AwardeeAdded and SubawardeeAdded, Amendment by the amendment events.
+type GrantEvent struct {
+	EventHeader
+	Grant_ID        string      `json:"grant_id"`
+	Previous_Status GrantStatus `json:"previous_status,omitempty"`
+	Status          GrantStatus `json:"status"`
+	Amount          Money       `json:"amount"`
+	Currency        string      `json:"currency"`
+	Awardee_ID      string      `json:"awardee_id,omitempty"`
+	Amendment       int         `json:"amendment,omitempty"`
+}
+
+// PaymentEvent is emitted when a reimbursement is requested or approved or a
+// payment changes status. Previous_Status is empty for ReimbursementRequested
+// and equals Status for ReimbursementApproved, which records an approval that
+// doesn't yet satisfy the approval policy; Funder_ID is the funder paying it.
+// Events are readable by every peer of the channel, so a rejection carries
+// only its code; the reason is read from the payment.
+type PaymentEvent struct {
+	EventHeader
+	Grant_ID        string        `json:"grant_id"`
+	Payment_ID      string        `json:"payment_id"`
+	Awardee_ID      string        `json:"awardee_id"`
+	Funder_ID       string        `json:"funder_id"`
+	Previous_Status PaymentStatus `json:"previous_status,omitempty"`
+	Status          PaymentStatus `json:"status"`
+	Total           Money         `json:"total"`
+	Currency        string        `json:"currency"`
+	Rejection_Code  RejectionCode `json:"rejection_code,omitempty"`
+}
+
+// MigrationEvent is emitted by the data migrations with the number of grants rewritten.
+type MigrationEvent struct {
+	EventHeader
+	Migrated int `json:"migrated"`
+}
+
+// ConfigEvent is emitted by SetOrgConfig with the configuration it stored.
+type ConfigEvent struct {
+	EventHeader
+	Config OrgConfig `json:"config"`
+}
+
+// eventHeader fills in the header for an event raised by caller in this transaction.
+func eventHeader(ctx contractapi.TransactionContextInterface, caller *Caller, name string) (EventHeader, error) {
+	now, err := txTime(ctx)
+	if err != nil {
+		return EventHeader{}, err
+	}
+
+	header := EventHeader{
+		Version:     EventVersion,
+		Name:        name,
+		Tx_ID:       ctx.GetStub().GetTxID(),
+		Timestamp:   now,
+		Actor:       caller.ID,
+		Actor_MSPID: caller.MSPID,
+	}
+	return header, nil
+}
+
+// emitGrantEvent sets a GrantEvent for the grant as the transaction's event.
+func emitGrantEvent(ctx contractapi.TransactionContextInterface, caller *Caller, name string, grant *Grant, previous GrantStatus) error {
+	header, err := eventHeader(ctx, caller, name)
+	if err != nil {
+		return err
+	}
+
+	event := GrantEvent{
+		EventHeader:     header,
+		Grant_ID:        grant.ID,
+		Previous_Status: previous,
+		Status:          grant.Status,
+		Amount:          grant.Amount,
+		Currency:        grantCurrency(grant),
+	}
+	return setEvent(ctx, name, event)
+}
+
+// emitAwardeeEvent sets a GrantEvent naming the awardee added to the grant.
+func emitAwardeeEvent(ctx contractapi.TransactionContextInterface, caller *Caller, name string, grant *Grant, awardeeID string) error {
+	header, err := eventHeader(ctx, caller, name)
+	if err != nil {
+		return err
+	}
+
+	event := GrantEvent{
+		EventHeader:     header,
+		Grant_ID:        grant.ID,
+		Previous_Status: grant.Status,
+		Status:          grant.Status,
+		Amount:          grant.Amount,
+		Currency:        grantCurrency(grant),
+		Awardee_ID:      awardeeID,
+	}
+	return setEvent(ctx, name, event)
+}
+
+// emitAmendmentEvent sets a GrantEvent naming the amendment requested or decided.
+func emitAmendmentEvent(ctx contractapi.TransactionContextInterface, caller *Caller, name string, grant *Grant, amendment *Amendment) error {
+	header, err := eventHeader(ctx, caller, name)
+	if err != nil {
+		return err
+	}
+
+	event := GrantEvent{
+		EventHeader:     header,
+		Grant_ID:        grant.ID,
+		Previous_Status: grant.Status,
+		Status:          grant.Status,
+		Amount:          grant.Amount,
+		Currency:        grantCurrency(grant),
+		Amendment:       amendment.Number,
+	}
+	return setEvent(ctx, name, event)
+}
+
+// emitPaymentEvent sets a PaymentEvent for the payment as the transaction's event.
+func emitPaymentEvent(ctx contractapi.TransactionContextInterface, caller *Caller, name string, grant *Grant, payment *Payment, previous PaymentStatus) error {
+	header, err := eventHeader(ctx, caller, name)
+	if err != nil {
+		return err
+	}
+
+	event := PaymentEvent{
+		EventHeader:     header,
+		Grant_ID:        grant.ID,
+		Payment_ID:      payment.ID,
+		Awardee_ID:      payment.Awardee_ID,
+		Funder_ID:       paymentFunderID(grant, payment),
+		Previous_Status: previous,
+		Status:          payment.Status,
+		Total:           payment.Total,
+		Currency:        grantCurrency(grant),
+	}
+	if payment.Rejection != nil {
+		event.Rejection_Code = payment.Rejection.Code
+	}
+	return setEvent(ctx, name, event)
+}
+
+// emitMigrationEvent sets a MigrationEvent as the transaction's event.
+func emitMigrationEvent(ctx contractapi.TransactionContextInterface, caller *Caller, name string, migrated int) error {
+	header, err := eventHeader(ctx, caller, name)
+	if err != nil {
+		return err
+	}
+
+	event := MigrationEvent{
+		EventHeader: header,
+		Migrated:    migrated,
+	}
+	return setEvent(ctx, name, event)
+}
+
+// emitConfigEvent sets a ConfigEvent as the transaction's event.
+func emitConfigEvent(ctx contractapi.TransactionContextInterface, caller *Caller, config *OrgConfig) error {
+	header, err := eventHeader(ctx, caller, EventOrgConfigUpdated)
+	if err != nil {
+		return err
+	}
+
+	event := ConfigEvent{
+		EventHeader: header,
+		Config:      *config,
+	}
+	return setEvent(ctx, EventOrgConfigUpdated, event)
+}
+
+// setEvent marshals the payload and sets it as the transaction's event. Fabric
+// keeps only the last event set in a transaction, so each transaction sets one.
+func setEvent(ctx contractapi.TransactionContextInterface, name string, payload interface{}) error {
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return errInternal("failed to marshal %s event: %v", name, err)
+	}
+
+	err = ctx.GetStub().SetEvent(name, payloadJSON)
+	if err != nil {
+		return errInternal("failed to set %s event: %v", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package chaincode
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+func TestRejectionEventCarriesOnlyTheCode(t *testing.T) {
+	l := newLedger(t)
+	l.activeGrant("G1")
+	_, err := l.reimburse(awardee, "G1", "P1", "AwardeeMSP/bob", item("Personnel", "100"))
+	l.must(err)
+	l.must(l.run(grantor, nil, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.RejectReimbursement(ctx, "G1", "P1", string(RejectionMissingDocumentation), "no receipts for the March invoice")
+		return err
+	}))
+
+	chaincodeEvent := l.stub.LastEvent()
+	if bytes.Contains(chaincodeEvent.Payload, []byte("March invoice")) {
+		t.Fatalf("event %s leaks the rejection reason", chaincodeEvent.Payload)
+	}
+	var event PaymentEvent
+	l.must(json.Unmarshal(chaincodeEvent.Payload, &event))
+	if event.Status != PaymentRejected || event.Rejection_Code != RejectionMissingDocumentation {
+		t.Fatalf("got event %+v", event)
+	}
+}
@@ -0,0 +1,157 @@
+package chaincode
+
+import (
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// A no-cost extension moves the end of a grant's period of performance
+// without changing its budget. The main awardee requests one with a new end
+// date and a justification before the grant ends; the grantor approves or
+// denies it. Once approved, its end date is the one the period of performance
+// checks use, and closeout starts from it.
+
+// RequestExtension asks for a no-cost extension of a grant. The extension_request
+// transient input holds the grant ID, the new end date and the justification.
+// It returns the number of the amendment recording the request - Main Awardee
+func (s *SmartContract) RequestExtension(ctx contractapi.TransactionContextInterface) (int, error) {
+	caller, err := authorize(ctx, "RequestExtension")
+	if err != nil {
+		return 0, err
+	}
+
+	transientMap, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return 0, errInternal("error getting transient: %v", err)
+	}
+	var input extensionTransientInput
+	err = readTransientInput(transientMap, "extension_request", &input)
+	if err != nil {
+		return 0, err
+	}
+
+	grant, err := readGrant(ctx, input.Grant_ID)
+	if err != nil {
+		return 0, err
+	}
+	if !checkAwardee(grant.Awardee, caller.ID) {
+		return 0, awardeeNotAssigned(caller.ID, grant)
+	}
+	err = checkGrantStatus(grant, GrantActive)
+	if err != nil {
+		return 0, err
+	}
+	if pending := pendingAmendment(grant, AmendmentExtension); pending != nil {
+		return 0, errDuplicate("Grant %s already has extension %d awaiting a decision", grant.ID, pending.Number).
+			with("grant_id", grant.ID).
+			with("amendment", pending.Number)
+	}
+
+	now, err := txTimestamp(ctx)
+	if err != nil {
+		return 0, err
+	}
+	period, err := periodOf(grant)
+	if err != nil {
+		return 0, err
+	}
+	if !period.end.IsZero() && !now.Before(period.end) {
+		return 0, outOfPeriod(grant, "Grant %s ended on %s, extensions must be requested before it ends", grant.ID, grantEndDate(grant))
+	}
+	newEnd, err := periodEnd(input.End_Date)
+	if err != nil {
+		return 0, errValidation("invalid end_date: %v", err).with("end_date", input.End_Date)
+	}
+	if !newEnd.After(period.end) {
+		return 0, errValidation("the new end date %s must be after the current end date %s", input.End_Date, grantEndDate(grant)).
+			with("grant_id", grant.ID).
+			with("end_date", grantEndDate(grant))
+	}
+
+	amendment := addAmendment(grant, Amendment{
+		Type:              AmendmentExtension,
+		Status:            AmendmentRequested,
+		Reason:            input.Justification,
+		Requested_By:      caller.ID,
+		Requested_At:      now.Format(time.RFC3339),
+		Previous_End_Date: grantEndDate(grant),
+		End_Date:          input.End_Date,
+	})
+
+	err = putGrant(ctx, grant)
+	if err != nil {
+		return 0, err
+	}
+
+	err = emitAmendmentEvent(ctx, caller, EventExtensionRequested, grant, amendment)
+	if err != nil {
+		return 0, err
+	}
+	return amendment.Number, nil
+}
+
+// ApproveExtension approves the requested extension with the amendment number,
+// moving the end of the grant's period of performance to its end date - Grantor
+func (s *SmartContract) ApproveExtension(ctx contractapi.TransactionContextInterface, grant_id string, number int, notes string) (bool, error) {
+	return decideExtension(ctx, "ApproveExtension", grant_id, number, AmendmentApproved, notes)
+}
+
+// DenyExtension denies the requested extension with the amendment number; the
+// grant keeps its end date - Grantor
+func (s *SmartContract) DenyExtension(ctx contractapi.TransactionContextInterface, grant_id string, number int, reason string) (bool, error) {
+	return decideExtension(ctx, "DenyExtension", grant_id, number, AmendmentDenied, reason)
+}
+
+func decideExtension(ctx contractapi.TransactionContextInterface, function string, grantID string, number int, status AmendmentStatus, notes string) (bool, error) {
+	caller, err := authorize(ctx, function)
+	if err != nil {
+		return false, err
+	}
+
+	grant, err := readGrant(ctx, grantID)
+	if err != nil {
+		return false, err
+	}
+	err = checkGrantOwner(caller, grant, "decide extensions of", RoleProgramOfficer)
+	if err != nil {
+		return false, err
+	}
+	err = checkGrantStatus(grant, GrantActive, GrantSuspended)
+	if err != nil {
+		return false, err
+	}
+
+	amendment, err := findAmendment(grant, number)
+	if err != nil {
+		return false, err
+	}
+	err = checkAmendmentRequested(grant, amendment, AmendmentExtension)
+	if err != nil {
+		return false, err
+	}
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return false, err
+	}
+	decideAmendment(amendment, status, caller, now, notes)
+	if status == AmendmentApproved {
+		amendment.Effective_Date = now
+	}
+
+	err = putGrant(ctx, grant)
+	if err != nil {
+		return false, err
+	}
+
+	name := EventExtensionApproved
+	if status == AmendmentDenied {
+		name = EventExtensionDenied
+	}
+	err = emitAmendmentEvent(ctx, caller, name, grant, amendment)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
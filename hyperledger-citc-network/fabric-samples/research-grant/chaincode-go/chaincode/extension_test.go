@@ -0,0 +1,76 @@
+package chaincode
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+func (l *ledger) requestExtension(grantID string, endDate string) (int, error) {
+	l.t.Helper()
+	input := map[string]interface{}{"grant_id": grantID, "end_date": endDate, "justification": "field season was delayed"}
+	var number int
+	err := l.run(awardee, map[string]interface{}{"extension_request": input}, func(ctx contractapi.TransactionContextInterface) error {
+		var err error
+		number, err = l.contract.RequestExtension(ctx)
+		return err
+	})
+	return number, err
+}
+
+func (l *ledger) decideExtension(grantID string, number int, approve bool) error {
+	l.t.Helper()
+	return l.run(grantor, nil, func(ctx contractapi.TransactionContextInterface) error {
+		var err error
+		if approve {
+			_, err = l.contract.ApproveExtension(ctx, grantID, number, "approved")
+		} else {
+			_, err = l.contract.DenyExtension(ctx, grantID, number, "denied")
+		}
+		return err
+	})
+}
+
+func TestApprovedExtensionMovesTheEndDate(t *testing.T) {
+	l := newLedger(t)
+	l.activeGrant("G1")
+
+	_, err := l.requestExtension("G1", "2022-12-31")
+	wantCode(t, err, CodeValidation)
+
+	number, err := l.requestExtension("G1", "2023-06-30")
+	l.must(err)
+	_, err = l.requestExtension("G1", "2023-09-30")
+	wantCode(t, err, CodeDuplicate)
+
+	l.at("2023-03-01T12:00:00Z")
+	wantCode(t, l.reimburseIncurred("G1", "P1", "2023-02-28"), CodeOutOfPeriod)
+
+	l.must(l.decideExtension("G1", number, true))
+	l.must(l.reimburseIncurred("G1", "P1", "2023-02-28"))
+	wantCode(t, l.decideExtension("G1", number, false), CodeInvalidState)
+
+	grant, err := l.read(grantor, "G1")
+	l.must(err)
+	amendment := grant.Amendments[number-1]
+	// The awarded end date is kept; the extension moves the effective one
+	if amendment.Status != AmendmentApproved || amendment.Previous_End_Date != "2022-12-31" || grant.End_Date != "2022-12-31" || grantEndDate(grant) != "2023-06-30" {
+		t.Fatalf("got amendment %+v and end date %s", amendment, grant.End_Date)
+	}
+}
+
+func TestDeniedExtensionKeepsTheEndDate(t *testing.T) {
+	l := newLedger(t)
+	l.activeGrant("G1")
+
+	number, err := l.requestExtension("G1", "2023-06-30")
+	l.must(err)
+	l.must(l.decideExtension("G1", number, false))
+
+	l.at("2023-03-01T12:00:00Z")
+	wantCode(t, l.reimburseIncurred("G1", "P1", "2023-02-28"), CodeOutOfPeriod)
+
+	// Extensions must be requested before the grant ends
+	_, err = l.requestExtension("G1", "2023-06-30")
+	wantCode(t, err, CodeOutOfPeriod)
+}
@@ -0,0 +1,353 @@
+package chaincode
+
+import (
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Funder is an agency co-funding a grant. It commits a share of the grant
+// Amount, split over the grant's Benefit lines, and the reimbursements it
+// pays out of that share are approved and redeemed by its owner Funder_ID.
+// Paid_Amount and Cashed_Out are derived from the payment records on read.
+//
+// Grants funded by their grantor alone carry no funders; grantFunders stands
+// the grantor in as the only funder of the whole budget.
+type Funder struct {
+	MSP         string    `json:"msp"`
+	Funder_ID   string    `json:"funder_id"`
+	Amount      Money     `json:"amount"`
+	Benefit     []Benefit `json:"benefit"`
+	Paid_Amount Money     `json:"paid_amount,omitempty" metadata:"paid_amount,optional"`
+	Cashed_Out  Money     `json:"cashed_out,omitempty" metadata:"cashed_out,optional"`
+}
+
+// checkFunders validates the funders of a grant whose budget checkBudget has
+// already validated, rewriting their amounts in canonical form and their IDs
+// qualified with their MSP. Every funder must belong to a grantor org, its
+// benefit shares must add up to its amount, and the shares of each benefit
+// line must add up to the line amount.
+func checkFunders(grant *Grant, config *OrgConfig) error {
+	if len(grant.Funder) == 0 {
+		grant.Funder = nil
+		return nil
+	}
+
+	currency := grant.Currency
+	lines := map[string]int64{}
+	for _, benefit := range grant.Benefit {
+		units, err := benefit.Amount.minor(currency)
+		if err != nil {
+			return invalidAmount(err, "invalid amount for %s benefit", benefit.Benefit)
+		}
+		lines[benefit.Benefit] += units
+	}
+
+	seen := map[string]bool{}
+	committed := map[string]int64{}
+	for i := range grant.Funder {
+		funder := &grant.Funder[i]
+		if len(funder.Funder_ID) == 0 {
+			return errValidation("funder_id field must be a non-empty string")
+		}
+		funderID, err := qualifyUserID(funder.Funder_ID, funder.MSP)
+		if err != nil {
+			return err
+		}
+		funder.Funder_ID = funderID
+		if seen[funder.Funder_ID] {
+			return errDuplicate("Funder %s is listed more than once", funder.Funder_ID).with("funder_id", funder.Funder_ID)
+		}
+		seen[funder.Funder_ID] = true
+		if config.orgOf(funder.MSP) != orgGrantor {
+			return errValidation("Funder %s belongs to org %v, which is not a grantor org", funder.Funder_ID, funder.MSP).with("funder_id", funder.Funder_ID).with("grantor_msps", config.grantorMSPs())
+		}
+		if len(funder.Benefit) == 0 {
+			return errValidation("Funder %s must commit a share of at least one benefit", funder.Funder_ID).with("funder_id", funder.Funder_ID)
+		}
+
+		amount, err := funder.Amount.minor(currency)
+		if err != nil {
+			return invalidAmount(err, "invalid amount for funder %s", funder.Funder_ID)
+		}
+		var total int64
+		for j, benefit := range funder.Benefit {
+			if _, ok := lines[benefit.Benefit]; !ok {
+				return errValidation("Funder %s commits a share of %s benefit, which the Grant %s doesn't have", funder.Funder_ID, benefit.Benefit, grant.ID).with("funder_id", funder.Funder_ID).with("benefit", benefit.Benefit)
+			}
+			units, err := benefit.Amount.minor(currency)
+			if err != nil {
+				return invalidAmount(err, "invalid amount for %s benefit of funder %s", benefit.Benefit, funder.Funder_ID)
+			}
+			funder.Benefit[j].Amount = moneyFromMinor(units, currency)
+			committed[benefit.Benefit] += units
+			total += units
+		}
+		if total != amount {
+			return errValidation("Benefit shares of funder %s add up to %s, not its amount %s", funder.Funder_ID, moneyFromMinor(total, currency), moneyFromMinor(amount, currency)).
+				with("funder_id", funder.Funder_ID).
+				with("total", moneyFromMinor(total, currency)).
+				with("amount", moneyFromMinor(amount, currency))
+		}
+		funder.Amount = moneyFromMinor(amount, currency)
+		funder.Paid_Amount = ""
+		funder.Cashed_Out = ""
+	}
+
+	for _, benefit := range grant.Benefit {
+		if committed[benefit.Benefit] != lines[benefit.Benefit] {
+			return errValidation("Funder shares of %s benefit add up to %s, not the benefit amount %s", benefit.Benefit, moneyFromMinor(committed[benefit.Benefit], currency), moneyFromMinor(lines[benefit.Benefit], currency)).
+				with("benefit", benefit.Benefit).
+				with("total", moneyFromMinor(committed[benefit.Benefit], currency)).
+				with("amount", moneyFromMinor(lines[benefit.Benefit], currency))
+		}
+	}
+	return nil
+}
+
+// grantFunders returns the funders of the grant. A grant without funders is
+// funded by its grantor alone, whose MSP is looked up among the grantor orgs.
+func grantFunders(grant *Grant, config *OrgConfig) []Funder {
+	if len(grant.Funder) != 0 {
+		return grant.Funder
+	}
+
+	return []Funder{{
+		MSP:         grantorMSPOf(grant, config),
+		Funder_ID:   grant.Grantor_ID,
+		Amount:      grant.Amount,
+		Benefit:     grant.Benefit,
+		Paid_Amount: grant.Paid_Amount,
+		Cashed_Out:  grant.Cashed_Out,
+	}}
+}
+
+// grantorMSPOf returns the MSP ID of the grant's grantor org, looked up
+// among the grantor orgs by the org name the grant records.
+func grantorMSPOf(grant *Grant, config *OrgConfig) string {
+	for _, grantorMSP := range config.grantorMSPs() {
+		if orgName(grantorMSP) == grant.Grantor {
+			return grantorMSP
+		}
+	}
+	return config.Grantor_MSP
+}
+
+func findFunder(funders []Funder, funderID string) *Funder {
+	for i := range funders {
+		if funders[i].Funder_ID == funderID {
+			return &funders[i]
+		}
+	}
+	return nil
+}
+
+// fundsGrant reports whether the org with the given MSP ID funds the grant,
+// as its grantor or as one of its funders.
+func fundsGrant(grant *Grant, mspID string) bool {
+	if orgName(mspID) == grant.Grantor {
+		return true
+	}
+	for _, funder := range grant.Funder {
+		if funder.MSP == mspID {
+			return true
+		}
+	}
+	return false
+}
+
+// paymentFunderID returns the funder paying the payment. Payments recorded
+// before grants had funders are paid by the grantor.
+func paymentFunderID(grant *Grant, payment *Payment) string {
+	if payment.Funder_ID == "" {
+		return grant.Grantor_ID
+	}
+	return payment.Funder_ID
+}
+
+// paymentFunder returns the funder paying the payment.
+func paymentFunder(ctx contractapi.TransactionContextInterface, grant *Grant, payment *Payment) (*Funder, error) {
+	config, err := getOrgConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	funderID := paymentFunderID(grant, payment)
+	funder := findFunder(grantFunders(grant, config), funderID)
+	if funder == nil {
+		return nil, errNotFound("Funder %s of the Payment %s doesn't fund the Grant %s", funderID, payment.ID, grant.ID).with("grant_id", grant.ID).with("funder_id", funderID)
+	}
+	return funder, nil
+}
+
+// checkPaymentFunder lets the owner of the funder share paying the payment act
+// on it, and members of the funder's org holding one of roles. Admins always
+// can. Either way the caller must be of the funder's org.
+func checkPaymentFunder(caller *Caller, funder *Funder, payment *Payment, action string, roles ...Role) error {
+	if caller.Org == orgGrantor && caller.MSPID == funder.MSP {
+		if caller.ID == funder.Funder_ID || hasExplicitRole(caller, roles) {
+			return nil
+		}
+	}
+	return errForbidden("User %s is not allowed to %s for the Payment %s funded by %s", caller.ID, action, payment.ID, funder.Funder_ID).
+		with("payment_id", payment.ID).
+		with("funder_id", funder.Funder_ID)
+}
+
+// checkFundersKept rejects replacing the funders of a grant if a funder paying
+// one of its payments would be dropped.
+func checkFundersKept(ctx contractapi.TransactionContextInterface, grant *Grant, funders []Funder) error {
+	payments, err := getGrantPayments(ctx, grant.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, payment := range payments {
+		funderID := paymentFunderID(grant, &payment)
+		if len(funders) == 0 && funderID == grant.Grantor_ID {
+			continue
+		}
+		if findFunder(funders, funderID) == nil {
+			return errInvalidState("Funder %s pays the Payment %s and can't be removed from the Grant %s", funderID, payment.ID, grant.ID).
+				with("funder_id", funderID).
+				with("payment_id", payment.ID)
+		}
+	}
+	return nil
+}
+
+// benefitRequest is the amount requested for one benefit line.
+type benefitRequest struct {
+	benefit string
+	amount  int64
+}
+
+// chooseFunder picks the funder that pays a reimbursement of the requested
+// benefit amounts, given the grant's existing payments. A named funder must
+// have enough of its share of every line left; otherwise the first funder in
+// grant order that has is chosen. Requests no single funder can cover have
+// to be split.
+func chooseFunder(grant *Grant, funders []Funder, payments []Payment, funderID string, requested []benefitRequest) (*Funder, error) {
+	if funderID != "" {
+		funder := findFunder(funders, funderID)
+		if funder == nil {
+			return nil, errNotFound("Funder %s doesn't fund the Grant %s", funderID, grant.ID).with("grant_id", grant.ID).with("funder_id", funderID)
+		}
+		err := checkFunderShare(grant, funder, payments, requested)
+		if err != nil {
+			return nil, err
+		}
+		return funder, nil
+	}
+
+	for i := range funders {
+		err := checkFunderShare(grant, &funders[i], payments, requested)
+		if err == nil {
+			return &funders[i], nil
+		}
+		if len(funders) == 1 {
+			return nil, err
+		}
+	}
+	return nil, errBudgetExceeded("No single funder of the Grant %s has enough of its share left for the requested items; split the request or name a funder_id", grant.ID).with("grant_id", grant.ID)
+}
+
+// checkFunderShare checks that what the funder has committed to pay on each
+// requested line, plus the request, stays within its share of the line.
+func checkFunderShare(grant *Grant, funder *Funder, payments []Payment, requested []benefitRequest) error {
+	currency := grantCurrency(grant)
+
+	shares := map[string]int64{}
+	for _, benefit := range funder.Benefit {
+		units, err := benefit.Amount.minor(currency)
+		if err != nil {
+			return storedAmountInvalid(err, "Funder %s has an invalid amount for %s benefit", funder.Funder_ID, benefit.Benefit)
+		}
+		shares[benefit.Benefit] += units
+	}
+
+	spent := map[string]int64{}
+	for _, payment := range payments {
+		if !payment.Status.committed() || paymentFunderID(grant, &payment) != funder.Funder_ID {
+			continue
+		}
+		for _, item := range payment.Item {
+			units, err := item.Amount.minor(currency)
+			if err != nil {
+				return storedAmountInvalid(err, "Payment %s has an invalid amount", payment.ID)
+			}
+			spent[item.Benefit] += units
+		}
+	}
+
+	for _, request := range requested {
+		spent[request.benefit] += request.amount
+		if spent[request.benefit] > shares[request.benefit] {
+			remaining := shares[request.benefit] - spent[request.benefit] + request.amount
+			if remaining < 0 {
+				remaining = 0
+			}
+			return errBudgetExceeded("Requested value of %s exceeds the share of funder %s for %s benefit. Remaining share is %s", moneyFromMinor(request.amount, currency), funder.Funder_ID, request.benefit, moneyFromMinor(remaining, currency)).
+				with("funder_id", funder.Funder_ID).
+				with("benefit", request.benefit).
+				with("requested", moneyFromMinor(request.amount, currency)).
+				with("remaining", moneyFromMinor(remaining, currency))
+		}
+	}
+	return nil
+}
+
+// setFunderTotals derives the Paid_Amount and Cashed_Out of each funder of a
+// co-funded grant from its payments.
+func setFunderTotals(grant *Grant, payments []Payment) error {
+	if len(grant.Funder) == 0 {
+		return nil
+	}
+
+	currency := grantCurrency(grant)
+	paid := map[string]int64{}
+	cashedOut := map[string]int64{}
+	for _, payment := range payments {
+		total, err := payment.Total.minor(currency)
+		if err != nil {
+			return storedAmountInvalid(err, "Payment %s has an invalid amount", payment.ID)
+		}
+		funderID := paymentFunderID(grant, &payment)
+		switch payment.Status {
+		case PaymentAccepted, PaymentPendingRedeem:
+			paid[funderID] += total
+		case PaymentRedeemed:
+			cashedOut[funderID] += total
+		}
+	}
+
+	funders := make([]Funder, len(grant.Funder))
+	for i, funder := range grant.Funder {
+		funder.Paid_Amount = moneyFromMinor(paid[funder.Funder_ID], currency)
+		funder.Cashed_Out = moneyFromMinor(cashedOut[funder.Funder_ID], currency)
+		funders[i] = funder
+	}
+	grant.Funder = funders
+	return nil
+}
+
+// GetFunders returns the funders of the grant with the amount each has paid
+// and cashed out. A grant funded by its grantor alone has one funder.
+func (s *SmartContract) GetFunders(ctx contractapi.TransactionContextInterface, grant_id string) ([]Funder, error) {
+	caller, err := authorize(ctx, "GetFunders")
+	if err != nil {
+		return nil, err
+	}
+
+	grant, _, err := readGrantAs(ctx, caller, grant_id)
+	if err != nil {
+		return nil, err
+	}
+	err = setPaymentTotals(ctx, grant)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := getOrgConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return grantFunders(grant, config), nil
+}
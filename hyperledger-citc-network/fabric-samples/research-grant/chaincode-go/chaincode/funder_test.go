@@ -0,0 +1,182 @@
+package chaincode
+
+import (
+	"testing"
+
+	"chaincode-go/chaincodetest"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+var agency = chaincodetest.NewIdentity("AgencyMSP", "erin").WithAttribute(roleAttribute, string(RoleAdmin))
+
+// withAgency configures AgencyMSP as a further grantor org funding grants.
+func (l *ledger) withAgency() {
+	l.t.Helper()
+	config := `{"grantor_msp":"GrantorMSP","funder_msps":["AgencyMSP"],"awardee_msp":"AwardeeMSP","subawardee_msp":"SubawardeeMSP","auditor_msp":"AuditorMSP"}`
+	l.must(l.run(grantor, nil, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.SetOrgConfig(ctx, config)
+		return err
+	}))
+}
+
+// coFundedGrant returns a grant input funded 600 by alice and 400 by erin of AgencyMSP.
+func coFundedGrant(id string) map[string]interface{} {
+	grant := newGrant(id)
+	grant["funder"] = []map[string]interface{}{
+		{"msp": GrantorMSP, "funder_id": "alice", "amount": "600", "benefit": []map[string]interface{}{item("Personnel", "600")}},
+		{"msp": "AgencyMSP", "funder_id": "erin", "amount": "400", "benefit": []map[string]interface{}{item("Equipment", "400")}},
+	}
+	return grant
+}
+
+func (l *ledger) accept(identity *chaincodetest.Identity, grantID string, paymentID string) error {
+	l.t.Helper()
+	return l.run(identity, nil, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.AcceptReimbursement(ctx, grantID, paymentID)
+		return err
+	})
+}
+
+// activeCoFundedGrant creates the co-funded grant id, assigned to bob and accepted.
+func (l *ledger) activeCoFundedGrant(id string) {
+	l.t.Helper()
+	l.withAgency()
+	l.must(l.initiate(coFundedGrant(id)))
+	l.must(l.assign(id, testAwardee("bob", "Main", AwardeeMSP)))
+	l.must(l.run(awardee, nil, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.AcceptGrant(ctx, id)
+		return err
+	}))
+}
+
+func TestFunderShares(t *testing.T) {
+	tests := []struct {
+		name    string
+		funders []map[string]interface{}
+	}{
+		{
+			name: "shares don't cover a line",
+			funders: []map[string]interface{}{
+				{"msp": GrantorMSP, "funder_id": "alice", "amount": "600", "benefit": []map[string]interface{}{item("Personnel", "600")}},
+				{"msp": "AgencyMSP", "funder_id": "erin", "amount": "300", "benefit": []map[string]interface{}{item("Equipment", "300")}},
+			},
+		},
+		{
+			name: "shares don't add up to the funder amount",
+			funders: []map[string]interface{}{
+				{"msp": GrantorMSP, "funder_id": "alice", "amount": "700", "benefit": []map[string]interface{}{item("Personnel", "600")}},
+				{"msp": "AgencyMSP", "funder_id": "erin", "amount": "400", "benefit": []map[string]interface{}{item("Equipment", "400")}},
+			},
+		},
+		{
+			name: "funder outside the grantor orgs",
+			funders: []map[string]interface{}{
+				{"msp": AwardeeMSP, "funder_id": "bob", "amount": "1000", "benefit": []map[string]interface{}{item("Personnel", "600"), item("Equipment", "400")}},
+			},
+		},
+		{
+			name: "share of a line the grant doesn't have",
+			funders: []map[string]interface{}{
+				{"msp": GrantorMSP, "funder_id": "alice", "amount": "1000", "benefit": []map[string]interface{}{item("Personnel", "600"), item("Travel", "400")}},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			l := newLedger(t)
+			l.withAgency()
+			grant := newGrant("G1")
+			grant["funder"] = test.funders
+			wantCode(t, l.initiate(grant), CodeValidation)
+		})
+	}
+}
+
+func TestPaymentsAreApprovedByTheirFunder(t *testing.T) {
+	l := newLedger(t)
+	l.activeCoFundedGrant("G1")
+	_, err := l.reimburse(awardee, "G1", "P1", "AwardeeMSP/bob", item("Equipment", "100"))
+	l.must(err)
+	_, err = l.reimburse(awardee, "G1", "P2", "AwardeeMSP/bob", item("Personnel", "100"))
+	l.must(err)
+
+	// erin of the grantor org is not the funder erin, nor is the grantor
+	for _, identity := range []*chaincodetest.Identity{chaincodetest.NewIdentity(GrantorMSP, "erin").WithAttribute(roleAttribute, string(RoleFinance)), grantor} {
+		wantCode(t, l.accept(identity, "G1", "P1"), CodeForbidden)
+	}
+	l.must(l.accept(agency, "G1", "P1"))
+	wantCode(t, l.accept(agency, "G1", "P2"), CodeForbidden)
+	l.must(l.accept(grantor, "G1", "P2"))
+
+	var funders []Funder
+	l.must(l.run(agency, nil, func(ctx contractapi.TransactionContextInterface) error {
+		var err error
+		funders, err = l.contract.GetFunders(ctx, "G1")
+		return err
+	}))
+	if funders[0].Paid_Amount != "100.00" || funders[1].Paid_Amount != "100.00" {
+		t.Fatalf("got funders %+v", funders)
+	}
+}
+
+func TestReimbursementFunderChoice(t *testing.T) {
+	l := newLedger(t)
+	l.activeCoFundedGrant("G1")
+
+	reimburse := func(id string, funderID string, items ...map[string]interface{}) error {
+		input := map[string]interface{}{"ID": id, "grant_id": "G1", "awardee_id": "AwardeeMSP/bob", "funder_id": funderID, "item": items}
+		return l.run(awardee, map[string]interface{}{"request_reimbursement": input}, func(ctx contractapi.TransactionContextInterface) error {
+			_, err := l.contract.RequestReimbursement(ctx)
+			return err
+		})
+	}
+	wantCode(t, reimburse("P1", "", item("Personnel", "10"), item("Equipment", "10")), CodeBudgetExceeded)
+	wantCode(t, reimburse("P1", "AgencyMSP/erin", item("Personnel", "10")), CodeBudgetExceeded)
+	l.must(reimburse("P1", "", item("Equipment", "10")))
+
+	payments, err := l.queryPayments(grantor, `{}`)
+	l.must(err)
+	if payments[0].Payment[0].Funder_ID != "AgencyMSP/erin" {
+		t.Fatalf("payment was attributed to %q, want AgencyMSP/erin", payments[0].Payment[0].Funder_ID)
+	}
+}
+
+func TestOnlyTheGrantorsOrgOwnsTheGrant(t *testing.T) {
+	l := newLedger(t)
+	l.withAgency()
+	l.must(l.initiate(coFundedGrant("G1")))
+	l.must(l.assign("G1", testAwardee("bob", "Main", AwardeeMSP)))
+
+	// alice of a funding agency is not the grantor alice
+	for _, identity := range []*chaincodetest.Identity{chaincodetest.NewIdentity("AgencyMSP", "alice").WithAttribute(roleAttribute, string(RoleProgramOfficer)), agency} {
+		err := l.run(identity, nil, func(ctx contractapi.TransactionContextInterface) error {
+			_, err := l.contract.RevokeGrant(ctx, "G1")
+			return err
+		})
+		wantCode(t, err, CodeForbidden)
+	}
+
+	l.must(l.run(grantor, nil, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.RevokeGrant(ctx, "G1")
+		return err
+	}))
+}
+
+// TestOwnerChecksNeedTheOrg covers users who keep their ID while their org
+// stops being a grantor org, e.g. after SetOrgConfig.
+func TestOwnerChecksNeedTheOrg(t *testing.T) {
+	grant := &Grant{ID: "G1", Grantor: "Grantor", Grantor_ID: "GrantorMSP/alice"}
+	funder := &Funder{MSP: "AgencyMSP", Funder_ID: "AgencyMSP/erin"}
+	payment := &Payment{ID: "P1"}
+
+	owner := &Caller{ID: "GrantorMSP/alice", MSPID: GrantorMSP, Org: orgGrantor}
+	noError(t, checkGrantOwner(owner, grant, "revoke"))
+	owner.Org = orgAwardee
+	wantCode(t, checkGrantOwner(owner, grant, "revoke"), CodeForbidden)
+
+	funderCaller := &Caller{ID: "AgencyMSP/erin", MSPID: "AgencyMSP", Org: orgGrantor}
+	noError(t, checkPaymentFunder(funderCaller, funder, payment, "accept"))
+	funderCaller.Org = orgAuditor
+	wantCode(t, checkPaymentFunder(funderCaller, funder, payment, "accept"), CodeForbidden)
+}
@@ -0,0 +1,322 @@
+package chaincode
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// FieldChange is one top-level field that differs from the previous version.
+// Old and New hold the field's JSON; Old is empty when the field was added and
+// New when it was removed.
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old,omitempty" metadata:"old,optional"`
+	New   string `json:"new,omitempty" metadata:"new,optional"`
+}
+
+// GrantVersion is one committed write of a grant.
+type GrantVersion struct {
+	Tx_ID     string        `json:"tx_id"`
+	Timestamp string        `json:"timestamp"`
+	Is_Delete bool          `json:"is_delete"`
+	Grant     *Grant        `json:"grant,omitempty" metadata:"grant,optional"`
+	Changes   []FieldChange `json:"changes"`
+}
+
+// PaymentStatusChange is one status a payment went through.
+type PaymentStatusChange struct {
+	Tx_ID       string        `json:"tx_id"`
+	Timestamp   string        `json:"timestamp"`
+	Is_Delete   bool          `json:"is_delete"`
+	Status      PaymentStatus `json:"status"`
+	Status_Date string        `json:"status_date"`
+	Updated_By  string        `json:"updated_by"`
+	Rejection   *Rejection    `json:"rejection,omitempty" metadata:"rejection,optional"`
+}
+
+// keyVersion is one entry of a key's history.
+type keyVersion struct {
+	txID      string
+	timestamp time.Time
+	isDelete  bool
+	value     []byte
+}
+
+// keyHistory returns every committed write of key, oldest first.
+func keyHistory(ctx contractapi.TransactionContextInterface, key string) ([]keyVersion, error) {
+	resultsIterator, err := ctx.GetStub().GetHistoryForKey(key)
+	if err != nil {
+		return nil, errInternal("failed to read history: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	var versions []keyVersion
+	for resultsIterator.HasNext() {
+		modification, err := resultsIterator.Next()
+		if err != nil {
+			return nil, errInternal("failed to read history: %v", err)
+		}
+
+		version := keyVersion{
+			txID:     modification.TxId,
+			isDelete: modification.IsDelete,
+			value:    modification.Value,
+		}
+		if modification.Timestamp != nil {
+			version.timestamp = time.Unix(modification.Timestamp.Seconds, int64(modification.Timestamp.Nanos)).UTC()
+		}
+		versions = append(versions, version)
+	}
+
+	// Fabric 2.x returns the newest write first. Timestamps are set by the
+	// client and don't follow commit order, so the order is only reversed.
+	for i, j := 0, len(versions)-1; i < j; i, j = i+1, j-1 {
+		versions[i], versions[j] = versions[j], versions[i]
+	}
+	return versions, nil
+}
+
+// diffFields compares the top-level fields of two JSON objects. previous may be nil.
+func diffFields(previous []byte, current []byte) ([]FieldChange, error) {
+	oldFields := map[string]json.RawMessage{}
+	if previous != nil {
+		if err := json.Unmarshal(previous, &oldFields); err != nil {
+			return nil, err
+		}
+	}
+	newFields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(current, &newFields); err != nil {
+		return nil, err
+	}
+
+	changes := []FieldChange{}
+	for field, value := range newFields {
+		oldValue, ok := oldFields[field]
+		if ok && compactJSON(oldValue) == compactJSON(value) {
+			continue
+		}
+		change := FieldChange{Field: field, New: compactJSON(value)}
+		if ok {
+			change.Old = compactJSON(oldValue)
+		}
+		changes = append(changes, change)
+	}
+	for field, value := range oldFields {
+		if _, ok := newFields[field]; !ok {
+			changes = append(changes, FieldChange{Field: field, Old: compactJSON(value)})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Field < changes[j].Field
+	})
+	return changes, nil
+}
+
+func compactJSON(value json.RawMessage) string {
+	var buffer bytes.Buffer
+	if err := json.Compact(&buffer, value); err != nil {
+		return string(value)
+	}
+	return buffer.String()
+}
+
+// grantHistoryAccess returns the caller's access to a grant judged by its
+// latest stored version, so the history of a deleted grant stays readable to
+// the same callers.
+func grantHistoryAccess(caller *Caller, grantID string, versions []keyVersion) (grantAccess, error) {
+	for i := len(versions) - 1; i >= 0; i-- {
+		if versions[i].isDelete {
+			continue
+		}
+		var grant Grant
+		err := json.Unmarshal(versions[i].value, &grant)
+		if err != nil {
+			return accessNone, errInternal("failed to decode version %s of the Grant %s: %v", versions[i].txID, grantID, err)
+		}
+		access := grantAccessOf(caller, &grant)
+		if access == accessNone {
+			return accessNone, readForbidden(caller, grantID)
+		}
+		return access, nil
+	}
+	return accessNone, errNotFound("Grant %s has no history", grantID).with("grant_id", grantID)
+}
+
+// GetGrantHistory returns every version of the grant, oldest first, with the
+// fields each version changed. Summary readers get redacted versions and
+// don't see changes to redacted fields.
+func (s *SmartContract) GetGrantHistory(ctx contractapi.TransactionContextInterface, grant_id string) ([]GrantVersion, error) {
+	caller, err := authorize(ctx, "GetGrantHistory")
+	if err != nil {
+		return nil, err
+	}
+
+	grantKey, err := ctx.GetStub().CreateCompositeKey("grant", []string{grant_id})
+	if err != nil {
+		return nil, errInternal("failed to create grant key: %v", err)
+	}
+
+	versions, err := keyHistory(ctx, grantKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(versions) == 0 {
+		return nil, errNotFound("Grant %s has no history", grant_id).with("grant_id", grant_id)
+	}
+	access, err := grantHistoryAccess(caller, grant_id, versions)
+	if err != nil {
+		return nil, err
+	}
+
+	history := []GrantVersion{}
+	var previous []byte
+	for _, version := range versions {
+		grantVersion := GrantVersion{
+			Tx_ID:     version.txID,
+			Timestamp: version.timestamp.Format(time.RFC3339),
+			Is_Delete: version.isDelete,
+			Changes:   []FieldChange{},
+		}
+
+		if !version.isDelete {
+			var grant Grant
+			err = json.Unmarshal(version.value, &grant)
+			if err != nil {
+				return nil, errInternal("failed to decode version %s of the Grant %s: %v", version.txID, grant_id, err)
+			}
+			value := version.value
+			if access != accessFull {
+				redactGrant(&grant)
+				value, err = json.Marshal(&grant)
+				if err != nil {
+					return nil, errInternal("failed to marshal grant into JSON: %v", err)
+				}
+			}
+			grantVersion.Grant = &grant
+
+			grantVersion.Changes, err = diffFields(previous, value)
+			if err != nil {
+				return nil, errInternal("failed to decode version %s of the Grant %s: %v", version.txID, grant_id, err)
+			}
+			previous = value
+		} else {
+			previous = nil
+		}
+
+		history = append(history, grantVersion)
+	}
+
+	return history, nil
+}
+
+// GetPaymentHistory returns the statuses a payment went through, oldest first.
+// Payments recorded before they had their own records are followed through
+// the versions of the grant that embedded them.
+func (s *SmartContract) GetPaymentHistory(ctx contractapi.TransactionContextInterface, grant_id string, payment_id string) ([]PaymentStatusChange, error) {
+	caller, err := authorize(ctx, "GetPaymentHistory")
+	if err != nil {
+		return nil, err
+	}
+
+	grantKey, err := ctx.GetStub().CreateCompositeKey("grant", []string{grant_id})
+	if err != nil {
+		return nil, errInternal("failed to create grant key: %v", err)
+	}
+	grantVersions, err := keyHistory(ctx, grantKey)
+	if err != nil {
+		return nil, err
+	}
+	access, err := grantHistoryAccess(caller, grant_id, grantVersions)
+	if err != nil {
+		return nil, err
+	}
+
+	history := []PaymentStatusChange{}
+	appendChange := func(version keyVersion, payment *Payment) error {
+		change := PaymentStatusChange{
+			Tx_ID:     version.txID,
+			Timestamp: version.timestamp.Format(time.RFC3339),
+			Is_Delete: payment == nil,
+		}
+		if payment != nil {
+			err := normalizePayment(payment)
+			if err != nil {
+				return err
+			}
+			if access != accessFull {
+				redactPayment(payment)
+			}
+			change.Status = payment.Status
+			change.Status_Date = payment.Status_Date
+			change.Updated_By = payment.Updated_By
+			change.Rejection = payment.Rejection
+		}
+
+		// Only keep versions where the payment's status moved
+		if len(history) > 0 {
+			last := history[len(history)-1]
+			if last.Is_Delete == change.Is_Delete && last.Status == change.Status {
+				return nil
+			}
+		}
+		history = append(history, change)
+		return nil
+	}
+
+	for _, version := range grantVersions {
+		if version.isDelete {
+			continue
+		}
+		var grant Grant
+		err = json.Unmarshal(version.value, &grant)
+		if err != nil {
+			return nil, errInternal("failed to decode version %s of the Grant %s: %v", version.txID, grant_id, err)
+		}
+		for i := range grant.Payment {
+			if grant.Payment[i].ID == payment_id {
+				err = appendChange(version, &grant.Payment[i])
+				if err != nil {
+					return nil, err
+				}
+				break
+			}
+		}
+	}
+
+	paymentKey, err := ctx.GetStub().CreateCompositeKey(paymentObjectType, []string{grant_id, payment_id})
+	if err != nil {
+		return nil, errInternal("failed to create payment key: %v", err)
+	}
+	paymentVersions, err := keyHistory(ctx, paymentKey)
+	if err != nil {
+		return nil, err
+	}
+	for _, version := range paymentVersions {
+		if version.isDelete {
+			err = appendChange(version, nil)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		var payment Payment
+		err = json.Unmarshal(version.value, &payment)
+		if err != nil {
+			return nil, errInternal("failed to decode version %s of the Payment %s: %v", version.txID, payment_id, err)
+		}
+		err = appendChange(version, &payment)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(history) == 0 {
+		return nil, paymentNotFound(grant_id, payment_id)
+	}
+	return history, nil
+}
@@ -0,0 +1,56 @@
+package chaincode
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+func TestGrantHistoryIsInCommitOrder(t *testing.T) {
+	l := newLedger(t)
+	l.must(l.initiate(newGrant("G1")))
+	// The client of the next transaction has a clock running an hour behind
+	l.stub.Clock = l.stub.Clock.Add(-time.Hour)
+	l.must(l.assign("G1", testAwardee("bob", "Main", AwardeeMSP)))
+
+	var versions []GrantVersion
+	l.must(l.run(grantor, nil, func(ctx contractapi.TransactionContextInterface) error {
+		var err error
+		versions, err = l.contract.GetGrantHistory(ctx, "G1")
+		return err
+	}))
+	if len(versions) != 2 {
+		t.Fatalf("got %d versions, want 2", len(versions))
+	}
+	if versions[0].Grant.Status != GrantDraft || versions[1].Grant.Status != GrantAssigned {
+		t.Fatalf("got statuses %s, %s", versions[0].Grant.Status, versions[1].Grant.Status)
+	}
+	var statusChanged bool
+	for _, change := range versions[1].Changes {
+		if change.Field == "status" {
+			statusChanged = change.Old == `"Draft"` && change.New == `"Assigned"`
+		}
+	}
+	if !statusChanged {
+		t.Errorf("got changes %+v, want status from Draft to Assigned", versions[1].Changes)
+	}
+}
+
+func TestPaymentHistory(t *testing.T) {
+	l := newLedger(t)
+	l.activeGrant("G1")
+	_, err := l.reimburse(awardee, "G1", "P1", "AwardeeMSP/bob", item("Personnel", "100"))
+	l.must(err)
+	l.must(l.accept(grantor, "G1", "P1"))
+
+	var changes []PaymentStatusChange
+	l.must(l.run(awardee, nil, func(ctx contractapi.TransactionContextInterface) error {
+		var err error
+		changes, err = l.contract.GetPaymentHistory(ctx, "G1", "P1")
+		return err
+	}))
+	if len(changes) != 2 || changes[0].Status != PaymentRequested || changes[1].Status != PaymentAccepted {
+		t.Fatalf("got history %+v", changes)
+	}
+}
@@ -0,0 +1,337 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Reasons a client identity can fail to resolve. They are wrapped in an
+// IdentityError inside an UNAUTHENTICATED ContractError, so callers can match
+// them with errors.Is.
+var (
+	ErrMissingMSPID       = errors.New("client identity has no MSP ID")
+	ErrMissingCertificate = errors.New("client identity has no X.509 certificate")
+	ErrMissingCommonName  = errors.New("client certificate has no common name")
+	ErrInvalidAttributes  = errors.New("client certificate attributes are invalid")
+)
+
+// IdentityError reports why the identity that submitted a transaction could not be resolved.
+type IdentityError struct {
+	Reason error
+	Err    error
+}
+
+func (e *IdentityError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("failed to resolve the client identity: %v", e.Reason)
+	}
+	return fmt.Sprintf("failed to resolve the client identity: %v: %v", e.Reason, e.Err)
+}
+
+// Unwrap returns the reason, so errors.Is(err, ErrMissingCertificate) and friends work.
+func (e *IdentityError) Unwrap() error {
+	return e.Reason
+}
+
+// unauthenticated reports an IdentityError as an UNAUTHENTICATED ContractError
+// that still unwraps to it.
+func unauthenticated(err *IdentityError) *ContractError {
+	return newContractError(CodeUnauthenticated, "%v", err).wrapping(err)
+}
+
+// Caller describes the identity that submitted the transaction, read from its X.509 certificate.
+type Caller struct {
+	// ID is the identifier the contract records for the caller (Grantor_ID,
+	// Awardee ID, ...): the MSP ID and the certificate's common name as
+	// MSPID/CN, so users with the same common name in different orgs differ.
+	ID                  string
+	MSPID               string
+	CommonName          string
+	OrganizationalUnits []string
+	EnrollmentID        string
+	Issuer              string
+	// Role is the grant.role attribute of the certificate, empty if it has none.
+	Role Role
+	// Org is the part the caller's org plays; authorize fills it in.
+	Org orgKind
+}
+
+// enrollmentIDAttribute is the attribute Fabric CA embeds in every certificate it enrolls.
+const enrollmentIDAttribute = "hf.EnrollmentID"
+
+// getCaller resolves the identity that submitted the current transaction.
+func getCaller(ctx contractapi.TransactionContextInterface) (*Caller, error) {
+	clientIdentity := ctx.GetClientIdentity()
+
+	mspID, err := clientIdentity.GetMSPID()
+	if err != nil {
+		return nil, unauthenticated(&IdentityError{Reason: ErrMissingMSPID, Err: err})
+	}
+	if mspID == "" {
+		return nil, unauthenticated(&IdentityError{Reason: ErrMissingMSPID})
+	}
+
+	cert, err := clientIdentity.GetX509Certificate()
+	if err != nil {
+		return nil, unauthenticated(&IdentityError{Reason: ErrMissingCertificate, Err: err})
+	}
+	if cert == nil {
+		return nil, unauthenticated(&IdentityError{Reason: ErrMissingCertificate})
+	}
+
+	commonName := cert.Subject.CommonName
+	if commonName == "" {
+		return nil, unauthenticated(&IdentityError{Reason: ErrMissingCommonName})
+	}
+
+	enrollmentID, found, err := clientIdentity.GetAttributeValue(enrollmentIDAttribute)
+	if err != nil {
+		return nil, unauthenticated(&IdentityError{Reason: ErrInvalidAttributes, Err: err})
+	}
+	if !found || enrollmentID == "" {
+		// Certificates issued outside Fabric CA (e.g. cryptogen) carry no attributes
+		enrollmentID = commonName
+	}
+
+	// Attributes that can't be read must not pass for a missing role
+	role, found, err := clientIdentity.GetAttributeValue(roleAttribute)
+	if err != nil {
+		return nil, unauthenticated(&IdentityError{Reason: ErrInvalidAttributes, Err: err})
+	}
+	if !found {
+		role = ""
+	}
+
+	caller := Caller{
+		ID:                  userID(mspID, commonName),
+		MSPID:               mspID,
+		CommonName:          commonName,
+		OrganizationalUnits: cert.Subject.OrganizationalUnit,
+		EnrollmentID:        enrollmentID,
+		Issuer:              cert.Issuer.String(),
+		Role:                Role(role),
+	}
+	return &caller, nil
+}
+
+// userID returns the ID the contract records for the user with the common name in the org.
+func userID(mspID string, commonName string) string {
+	return mspID + "/" + commonName
+}
+
+// userMSP returns the MSP ID a recorded user ID is qualified with, or "" for
+// a bare common name.
+func userMSP(id string) string {
+	i := strings.Index(id, "/")
+	if i < 0 {
+		return ""
+	}
+	return id[:i]
+}
+
+// qualifyUserID returns the recorded ID of a user of the org mspID given by a
+// client. A bare common name is qualified with mspID; an ID already qualified
+// must belong to mspID.
+func qualifyUserID(id string, mspID string) (string, error) {
+	if !strings.Contains(id, "/") {
+		return userID(mspID, id), nil
+	}
+	if !strings.HasPrefix(id, mspID+"/") {
+		return "", errValidation("User %s doesn't belong to org %v", id, mspID).with("user_id", id).with("msp", mspID)
+	}
+	return id, nil
+}
+
+// grantAwardeeID returns the recorded ID of the grant's awardee or subawardee
+// a client names with id. Clients may give the bare common name, which is
+// qualified with the org of the grant's awardee of that name. An ID that names
+// no awardee of the grant is returned as given, for the caller to report.
+func grantAwardeeID(grant *Grant, id string) (string, error) {
+	if userMSP(id) != "" {
+		return id, nil
+	}
+
+	var matched string
+	for _, awardee := range grant.Awardee {
+		if awardee.ID == id {
+			// Stored before MigrateIdentities
+			return id, nil
+		}
+		mspID := userMSP(awardee.ID)
+		if mspID == "" || awardee.ID != userID(mspID, id) || awardee.ID == matched {
+			continue
+		}
+		if matched != "" {
+			return "", errValidation("Awardee %s of the Grant %s is ambiguous, name it as %s or %s", id, grant.ID, matched, awardee.ID).
+				with("grant_id", grant.ID).
+				with("awardee_id", id)
+		}
+		matched = awardee.ID
+	}
+	if matched == "" {
+		return id, nil
+	}
+	return matched, nil
+}
+
+// qualifyAwardee qualifies the ID of an awardee given by a client with the
+// MSP ID of the org the awardee belongs to.
+func qualifyAwardee(awardee *Awardee, config *OrgConfig) error {
+	id, err := qualifyUserID(awardee.ID, awardeeMSP(awardee, config))
+	if err != nil {
+		return err
+	}
+	awardee.ID = id
+	return nil
+}
+
+// MigrateIdentities qualifies the user IDs stored before they carried the MSP
+// ID of the user's org: the grantor, awardees, funders and approvers of each
+// grant, the awardees and funders its payments name, and the keys of its
+// awardees' private details. Grantors and awardees belong to the orgs the
+// grant records for them. It has to be endorsed by a peer holding the
+// awardee collections, and fails if that peer lacks any details it moves. It
+// returns the number of grants rewritten - Grantor
+func (s *SmartContract) MigrateIdentities(ctx contractapi.TransactionContextInterface) (int, error) {
+	caller, err := authorize(ctx, "MigrateIdentities")
+	if err != nil {
+		return 0, err
+	}
+	config, err := getOrgConfig(ctx)
+	if err != nil {
+		return 0, err
+	}
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey("grant", []string{})
+	if err != nil {
+		return 0, errInternal("failed to query the world state: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	var migrated int
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return 0, errInternal("failed to read from world state: %v", err)
+		}
+
+		var grant Grant
+		err = json.Unmarshal(queryResponse.Value, &grant)
+		if err != nil {
+			return 0, errInternal("failed to unmarshal JSON: %v", err)
+		}
+		changed, err := migrateGrantIdentities(ctx, &grant, config)
+		if err != nil {
+			return 0, err
+		}
+		if changed {
+			migrated++
+		}
+	}
+
+	err = emitMigrationEvent(ctx, caller, EventIdentitiesMigrated, migrated)
+	if err != nil {
+		return 0, err
+	}
+
+	return migrated, nil
+}
+
+// migrateGrantIdentities qualifies the user IDs of one grant and its payments
+// and reports whether any changed.
+func migrateGrantIdentities(ctx contractapi.TransactionContextInterface, grant *Grant, config *OrgConfig) (bool, error) {
+	var changed bool
+	qualify := func(id *string, mspID string) {
+		if *id != "" && !strings.Contains(*id, "/") {
+			*id = userID(mspID, *id)
+			changed = true
+		}
+	}
+	grantorMSP := grantorMSPOf(grant, config)
+
+	// The MSP of a legacy ID named by a payment is found by the old ID
+	awardeeMSPs := map[string]string{}
+	for _, awardee := range grant.Awardee {
+		awardeeMSPs[awardee.ID] = awardeeMSP(&awardee, config)
+	}
+	funderMSPs := map[string]string{grant.Grantor_ID: grantorMSP}
+	for _, funder := range grant.Funder {
+		funderMSPs[funder.Funder_ID] = funder.MSP
+	}
+	qualifyPayment := func(payment *Payment) {
+		mspID, ok := awardeeMSPs[payment.Awardee_ID]
+		if !ok {
+			mspID = config.Awardee_MSP
+		}
+		qualify(&payment.Awardee_ID, mspID)
+		mspID, ok = funderMSPs[payment.Funder_ID]
+		if !ok {
+			mspID = grantorMSP
+		}
+		qualify(&payment.Funder_ID, mspID)
+		for i := range payment.Approvals {
+			qualify(&payment.Approvals[i].By, payment.Approvals[i].MSP)
+		}
+	}
+
+	err := deleteGrantIndexes(ctx, grant)
+	if err != nil {
+		return false, err
+	}
+
+	qualify(&grant.Grantor_ID, grantorMSP)
+	for i := range grant.Awardee {
+		awardee := &grant.Awardee[i]
+		oldID := awardee.ID
+		qualify(&awardee.ID, awardeeMSP(awardee, config))
+		if awardee.ID != oldID && awardee.Private_Hash != "" {
+			err = moveAwardeePrivateDetails(ctx, grant.ID, oldID, awardee)
+			if err != nil {
+				return false, err
+			}
+		}
+	}
+	for i := range grant.Funder {
+		qualify(&grant.Funder[i].Funder_ID, grant.Funder[i].MSP)
+	}
+	if grant.Approval_Policy != nil {
+		for i := range grant.Approval_Policy.Approvers {
+			qualify(&grant.Approval_Policy.Approvers[i], grantorMSP)
+		}
+	}
+	for i := range grant.Payment {
+		qualifyPayment(&grant.Payment[i])
+	}
+
+	payments, err := getGrantPayments(ctx, grant.ID)
+	if err != nil {
+		return false, err
+	}
+	for i := range payments {
+		before := changed
+		changed = false
+		qualifyPayment(&payments[i])
+		if changed {
+			err = putPayment(ctx, &payments[i])
+			if err != nil {
+				return false, err
+			}
+		}
+		changed = changed || before
+	}
+
+	if changed {
+		err = putGrant(ctx, grant)
+		if err != nil {
+			return false, err
+		}
+	}
+	err = putGrantIndexes(ctx, grant)
+	if err != nil {
+		return false, err
+	}
+	return changed, nil
+}
@@ -0,0 +1,243 @@
+package chaincode
+
+import (
+	"testing"
+
+	"chaincode-go/chaincodetest"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+func TestCallerIDIsQualifiedWithMSP(t *testing.T) {
+	l := newLedger(t)
+	var caller *Caller
+	l.must(l.run(grantor, nil, func(ctx contractapi.TransactionContextInterface) error {
+		var err error
+		caller, err = getCaller(ctx)
+		return err
+	}))
+	if caller.ID != "GrantorMSP/alice" || caller.CommonName != "alice" || caller.MSPID != GrantorMSP {
+		t.Fatalf("got caller %+v", caller)
+	}
+}
+
+func TestStoredIDsAreQualified(t *testing.T) {
+	l := newLedger(t)
+	l.activeGrant("G1")
+	l.addSubawardee("G1")
+
+	grant, err := l.read(grantor, "G1")
+	l.must(err)
+	if grant.Grantor_ID != "GrantorMSP/alice" {
+		t.Errorf("got grantor_id %s", grant.Grantor_ID)
+	}
+	want := []string{"AwardeeMSP/bob", "SubawardeeMSP/carol"}
+	for i, awardee := range grant.Awardee {
+		if awardee.ID != want[i] {
+			t.Errorf("got awardee[%d].id %s, want %s", i, awardee.ID, want[i])
+		}
+	}
+}
+
+func TestQualifyUserID(t *testing.T) {
+	tests := []struct {
+		id   string
+		want string
+		code ErrorCode
+	}{
+		{id: "bob", want: "AwardeeMSP/bob"},
+		{id: "AwardeeMSP/bob", want: "AwardeeMSP/bob"},
+		{id: "GrantorMSP/bob", code: CodeValidation},
+	}
+	for _, test := range tests {
+		got, err := qualifyUserID(test.id, AwardeeMSP)
+		if test.code != "" {
+			wantCode(t, err, test.code)
+			continue
+		}
+		if err != nil || got != test.want {
+			t.Errorf("qualifyUserID(%q) = %q, %v, want %q", test.id, got, err, test.want)
+		}
+	}
+}
+
+func TestSameCommonNameInAnotherOrgIsAnotherUser(t *testing.T) {
+	l := newLedger(t)
+	l.activeGrant("G1")
+
+	// alice of the awardee org isn't the grantor alice
+	impostor := chaincodetest.NewIdentity(AwardeeMSP, "alice")
+	err := l.run(impostor, nil, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.ReadGrant(ctx, "G1")
+		return err
+	})
+	wantCode(t, err, CodeForbidden)
+
+	// bob of the subawardee org isn't the awardee bob
+	err = l.run(chaincodetest.NewIdentity(SubawardeeMSP, "bob"), nil, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.MyWallet(ctx, "G1")
+		return err
+	})
+	wantCode(t, err, CodeForbidden)
+}
+
+func TestMigrateIdentities(t *testing.T) {
+	l := newLedger(t)
+	l.activeGrant("G1")
+	_, err := l.reimburse(awardee, "G1", "P1", "AwardeeMSP/bob", item("Personnel", "100"))
+	l.must(err)
+
+	// Store the grant and payment with the bare IDs of older versions
+	l.must(l.run(grantor, nil, func(ctx contractapi.TransactionContextInterface) error {
+		grant, err := readGrant(ctx, "G1")
+		if err != nil {
+			return err
+		}
+		err = deleteGrantIndexes(ctx, grant)
+		if err != nil {
+			return err
+		}
+		oldKey, _ := ctx.GetStub().CreateCompositeKey(awardeePrivateObjectType, []string{"G1", grant.Awardee[0].ID})
+		details, _ := ctx.GetStub().GetPrivateData(AwardeeCollection, oldKey)
+		grant.Grantor_ID = "alice"
+		grant.Awardee[0].ID = "bob"
+		newKey, _ := ctx.GetStub().CreateCompositeKey(awardeePrivateObjectType, []string{"G1", "bob"})
+		ctx.GetStub().PutPrivateData(AwardeeCollection, newKey, details)
+		ctx.GetStub().DelPrivateData(AwardeeCollection, oldKey)
+		err = putGrant(ctx, grant)
+		if err != nil {
+			return err
+		}
+		err = putGrantIndexes(ctx, grant)
+		if err != nil {
+			return err
+		}
+		payment, err := readPayment(ctx, "G1", "P1")
+		if err != nil {
+			return err
+		}
+		payment.Awardee_ID = "bob"
+		return putPayment(ctx, payment)
+	}))
+
+	var migrated int
+	l.must(l.run(grantor, nil, func(ctx contractapi.TransactionContextInterface) error {
+		var err error
+		migrated, err = l.contract.MigrateIdentities(ctx)
+		return err
+	}))
+	if migrated != 1 {
+		t.Fatalf("migrated %d grants, want 1", migrated)
+	}
+
+	grant, err := l.read(grantor, "G1")
+	l.must(err)
+	if grant.Grantor_ID != "GrantorMSP/alice" || grant.Awardee[0].ID != "AwardeeMSP/bob" {
+		t.Fatalf("got grantor %s and awardee %s", grant.Grantor_ID, grant.Awardee[0].ID)
+	}
+	l.must(l.run(awardee, nil, func(ctx contractapi.TransactionContextInterface) error {
+		payments, err := l.contract.GetPayments(ctx, "G1")
+		if err != nil {
+			return err
+		}
+		if payments[0].Awardee_ID != "AwardeeMSP/bob" {
+			t.Errorf("got payment awardee %s", payments[0].Awardee_ID)
+		}
+		details, err := l.contract.ReadAwardeePrivateDetails(ctx, "G1", "AwardeeMSP/bob")
+		if err != nil {
+			return err
+		}
+		if details.Awardee_ID != "AwardeeMSP/bob" || details.Account_Number != "ACC-bob" {
+			t.Errorf("got private details %+v", details)
+		}
+		return nil
+	}))
+}
+
+func TestBareAwardeeIDs(t *testing.T) {
+	l := newLedger(t)
+	l.activeGrant("G1")
+	l.addSubawardee("G1")
+
+	// The API names awardees by their common name alone
+	_, err := l.reimburse(awardee, "G1", "P1", "bob", item("Personnel", "100"))
+	l.must(err)
+	_, err = l.reimburse(subawardee, "G1", "P2", "carol", item("Equipment", "25.50"))
+	l.must(err)
+	if got := l.payment("G1", "P1").Awardee_ID; got != "AwardeeMSP/bob" {
+		t.Errorf("got payment awardee %s", got)
+	}
+
+	l.must(l.run(grantor, nil, func(ctx contractapi.TransactionContextInterface) error {
+		wallet, err := l.contract.GetWallet(ctx, "G1", "bob", "Requested")
+		if err != nil {
+			return err
+		}
+		if wallet != "100.00" {
+			t.Errorf("got wallet %s", wallet)
+		}
+		payments, err := l.contract.GetPaymentByAwardee(ctx, "G1", "carol")
+		if err != nil {
+			return err
+		}
+		if len(payments) != 1 || payments[0].ID != "P2" {
+			t.Errorf("got payments %+v", payments)
+		}
+		return nil
+	}))
+	details, err := l.readPrivate(awardee, "G1", "bob")
+	l.must(err)
+	if details.Awardee_ID != "AwardeeMSP/bob" {
+		t.Errorf("got private details %+v", details)
+	}
+}
+
+func TestAmbiguousBareAwardeeID(t *testing.T) {
+	l := newLedger(t)
+	l.activeGrant("G1")
+	// A subawardee with the awardee's common name
+	input := map[string]interface{}{"grant_id": "G1", "awardee": testAwardee("bob", "Sub", SubawardeeMSP)}
+	l.must(l.run(awardee, map[string]interface{}{"add_subawardee": input, "salt": testSalt}, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.AddSubawardee(ctx)
+		return err
+	}))
+
+	_, err := l.reimburse(awardee, "G1", "P1", "bob", item("Personnel", "100"))
+	wantCode(t, err, CodeValidation)
+	_, err = l.reimburse(awardee, "G1", "P1", "AwardeeMSP/bob", item("Personnel", "100"))
+	noError(t, err)
+}
+
+func TestMigrateIdentitiesNeedsThePrivateDetails(t *testing.T) {
+	l := newLedger(t)
+	l.activeGrant("G1")
+
+	// The grant of an older version, on a peer that doesn't hold bob's details
+	l.must(l.run(grantor, nil, func(ctx contractapi.TransactionContextInterface) error {
+		grant, err := readGrant(ctx, "G1")
+		if err != nil {
+			return err
+		}
+		err = deleteGrantIndexes(ctx, grant)
+		if err != nil {
+			return err
+		}
+		err = deleteAwardeePrivateDetails(ctx, grant)
+		if err != nil {
+			return err
+		}
+		grant.Awardee[0].ID = "bob"
+		return putGrant(ctx, grant)
+	}))
+
+	err := l.run(grantor, nil, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.MigrateIdentities(ctx)
+		return err
+	})
+	wantCode(t, err, CodeNotFound)
+	grant, err := l.read(grantor, "G1")
+	l.must(err)
+	if grant.Awardee[0].ID != "bob" {
+		t.Fatalf("got awardee %s, want it left unmigrated", grant.Awardee[0].ID)
+	}
+}
@@ -0,0 +1,314 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Secondary index keys map a participant to the grants they take part in, so
+// the user-scoped queries read only the caller's grants instead of scanning
+// every grant. The keys carry no data; following the Fabric convention the
+// value is a single zero byte, since an empty value would delete the key.
+const (
+	grantorGrantIndex = "grantor~grant"
+	awardeeGrantIndex = "awardee~grant"
+)
+
+var indexValue = []byte{0x00}
+
+// putGrantIndexes writes a grantor~grant key for the grantor and each funder
+// of the grant and an awardee~grant key for each of its awardees and subawardees.
+func putGrantIndexes(ctx contractapi.TransactionContextInterface, grant *Grant) error {
+	return forEachGrantIndexKey(ctx, grant, func(key string) error {
+		err := ctx.GetStub().PutState(key, indexValue)
+		if err != nil {
+			return errInternal("failed to put index key of grant %s: %v", grant.ID, err)
+		}
+		return nil
+	})
+}
+
+// deleteGrantIndexes removes the index keys putGrantIndexes wrote for the grant.
+func deleteGrantIndexes(ctx contractapi.TransactionContextInterface, grant *Grant) error {
+	return forEachGrantIndexKey(ctx, grant, func(key string) error {
+		err := ctx.GetStub().DelState(key)
+		if err != nil {
+			return errInternal("failed to delete index key of grant %s: %v", grant.ID, err)
+		}
+		return nil
+	})
+}
+
+func forEachGrantIndexKey(ctx contractapi.TransactionContextInterface, grant *Grant, fn func(key string) error) error {
+	if grant.Grantor_ID != "" {
+		key, err := ctx.GetStub().CreateCompositeKey(grantorGrantIndex, []string{grant.Grantor_ID, grant.ID})
+		if err != nil {
+			return errInternal("failed to create index key: %v", err)
+		}
+		err = fn(key)
+		if err != nil {
+			return err
+		}
+	}
+	for _, funder := range grant.Funder {
+		if funder.Funder_ID == "" || funder.Funder_ID == grant.Grantor_ID {
+			continue
+		}
+		key, err := ctx.GetStub().CreateCompositeKey(grantorGrantIndex, []string{funder.Funder_ID, grant.ID})
+		if err != nil {
+			return errInternal("failed to create index key: %v", err)
+		}
+		err = fn(key)
+		if err != nil {
+			return err
+		}
+	}
+	for _, awardee := range grant.Awardee {
+		if awardee.ID == "" {
+			continue
+		}
+		key, err := ctx.GetStub().CreateCompositeKey(awardeeGrantIndex, []string{awardee.ID, grant.ID})
+		if err != nil {
+			return errInternal("failed to create index key: %v", err)
+		}
+		err = fn(key)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// userGrantIDs returns the IDs of the grants userId takes part in as grantor,
+// funder, awardee or subawardee, in grant ID order.
+func userGrantIDs(ctx contractapi.TransactionContextInterface, userId string) ([]string, error) {
+	seen := map[string]bool{}
+	ids := []string{}
+	for _, index := range []string{grantorGrantIndex, awardeeGrantIndex} {
+		resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(index, []string{userId})
+		if err != nil {
+			return nil, errInternal("failed to query the world state: %v", err)
+		}
+
+		for resultsIterator.HasNext() {
+			queryResponse, err := resultsIterator.Next()
+			if err != nil {
+				resultsIterator.Close()
+				return nil, errInternal("failed to read from world state: %v", err)
+			}
+			_, keys, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+			if err != nil {
+				resultsIterator.Close()
+				return nil, errInternal("failed to split index key: %v", err)
+			}
+			if len(keys) != 2 || seen[keys[1]] {
+				continue
+			}
+			seen[keys[1]] = true
+			ids = append(ids, keys[1])
+		}
+		resultsIterator.Close()
+	}
+
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// readUserGrant reads a grant found through the index, returning it with its
+// stored JSON, or nil if it is gone or the caller no longer takes part in it. The
+// participant check guards against stale keys; RebuildIndexes clears those.
+func readUserGrant(ctx contractapi.TransactionContextInterface, id string, caller *Caller) (*Grant, []byte, error) {
+	requestCompositeKey, _ := ctx.GetStub().CreateCompositeKey("grant", []string{id})
+	grantJSON, err := ctx.GetStub().GetState(requestCompositeKey)
+	if err != nil {
+		return nil, nil, errInternal("failed to read from world state: %v", err)
+	}
+	if grantJSON == nil {
+		return nil, nil, nil
+	}
+
+	var grant Grant
+	err = json.Unmarshal(grantJSON, &grant)
+	if err != nil {
+		return nil, nil, errInternal("failed to unmarshal JSON: %v", err)
+	}
+	if !isParticipant(&grant, caller) {
+		return nil, nil, nil
+	}
+	return &grant, grantJSON, nil
+}
+
+// getUserGrants returns the grants the caller takes part in that match accepts,
+// with their payment totals set.
+func getUserGrants(ctx contractapi.TransactionContextInterface, caller *Caller, match func(*Grant) bool) ([]Grant, error) {
+	ids, err := userGrantIDs(ctx, caller.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	grants := []Grant{}
+	for _, id := range ids {
+		grant, _, err := readUserGrant(ctx, id, caller)
+		if err != nil {
+			return nil, err
+		}
+		if grant == nil || !match(grant) {
+			continue
+		}
+		err = setPaymentTotals(ctx, grant)
+		if err != nil {
+			return nil, err
+		}
+		grants = append(grants, *grant)
+	}
+	return grants, nil
+}
+
+// queryUserGrantPage is queryGrantPage over the grants the caller takes part in.
+// The bookmark is the ID of the grant the next page starts at.
+func queryUserGrantPage(ctx contractapi.TransactionContextInterface, caller *Caller, pageSize int32, bookmark string, match func(*Grant, []byte) (bool, error)) (*GrantPage, error) {
+	err := checkPageSize(pageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	ids, err := userGrantIDs(ctx, caller.ID)
+	if err != nil {
+		return nil, err
+	}
+	start := sort.SearchStrings(ids, bookmark)
+	end := start + int(pageSize)
+	if end > len(ids) {
+		end = len(ids)
+	}
+
+	page := GrantPage{Records: []Grant{}, Fetched_Count: int32(end - start)}
+	if end < len(ids) {
+		page.Bookmark = ids[end]
+	}
+	for _, id := range ids[start:end] {
+		grant, value, err := readUserGrant(ctx, id, caller)
+		if err != nil {
+			return nil, err
+		}
+		if grant == nil {
+			continue
+		}
+		ok, err := match(grant, value)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		err = setPaymentTotals(ctx, grant)
+		if err != nil {
+			return nil, err
+		}
+		page.Records = append(page.Records, *grant)
+	}
+	return &page, nil
+}
+
+// RebuildIndexes rewrites the grantor~grant and awardee~grant index keys from
+// the stored grants, dropping stale keys, and returns the number of grants
+// indexed. Grants and payments stored without a doc_type get one, so QueryGrants
+// and the CouchDB indexes find them. Run it once after upgrading from a version
+// without indexes - Grantor
+func (s *SmartContract) RebuildIndexes(ctx contractapi.TransactionContextInterface) (int, error) {
+	caller, err := authorize(ctx, "RebuildIndexes")
+	if err != nil {
+		return 0, err
+	}
+	for _, index := range []string{grantorGrantIndex, awardeeGrantIndex} {
+		err = deleteIndex(ctx, index)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey("grant", []string{})
+	if err != nil {
+		return 0, errInternal("failed to query the world state: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	var indexed int
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return 0, errInternal("failed to read from world state: %v", err)
+		}
+
+		var grant Grant
+		err = json.Unmarshal(queryResponse.Value, &grant)
+		if err != nil {
+			return 0, errInternal("failed to unmarshal JSON: %v", err)
+		}
+		err = putGrantIndexes(ctx, &grant)
+		if err != nil {
+			return 0, err
+		}
+		err = stampDocType(ctx, &grant)
+		if err != nil {
+			return 0, err
+		}
+		indexed++
+	}
+
+	err = emitMigrationEvent(ctx, caller, EventIndexesRebuilt, indexed)
+	if err != nil {
+		return 0, err
+	}
+
+	return indexed, nil
+}
+
+// stampDocType rewrites the grant and its payments if they were stored before
+// they carried a doc_type.
+func stampDocType(ctx contractapi.TransactionContextInterface, grant *Grant) error {
+	if grant.Doc_Type == "" {
+		err := putGrant(ctx, grant)
+		if err != nil {
+			return err
+		}
+	}
+
+	payments, err := getGrantPayments(ctx, grant.ID)
+	if err != nil {
+		return err
+	}
+	for i := range payments {
+		if payments[i].Doc_Type != "" {
+			continue
+		}
+		err = putPayment(ctx, &payments[i])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func deleteIndex(ctx contractapi.TransactionContextInterface, index string) error {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(index, []string{})
+	if err != nil {
+		return errInternal("failed to query the world state: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return errInternal("failed to read from world state: %v", err)
+		}
+		err = ctx.GetStub().DelState(queryResponse.Key)
+		if err != nil {
+			return errInternal("failed to delete index key %s: %v", queryResponse.Key, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// indexed reports whether the index key of the user and grant is in committed state.
+func (l *ledger) indexed(index string, userId string, grantID string) bool {
+	l.t.Helper()
+	key, err := l.stub.CreateCompositeKey(index, []string{userId, grantID})
+	l.must(err)
+	return l.stub.State(key) != nil
+}
+
+func TestGrantIndexKeys(t *testing.T) {
+	l := newLedger(t)
+	l.must(l.initiate(newGrant("G1")))
+	if !l.indexed(grantorGrantIndex, "GrantorMSP/alice", "G1") {
+		t.Fatalf("InitiateGrant did not index the grantor")
+	}
+
+	l.must(l.assign("G1", testAwardee("bob", "Main", AwardeeMSP)))
+	input := map[string]interface{}{"grant_id": "G1", "awardee": testAwardee("erin", "Main", AwardeeMSP)}
+	l.must(l.run(grantor, map[string]interface{}{"add_awardee": input, "salt": testSalt}, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.AddAwardee(ctx)
+		return err
+	}))
+	if !l.indexed(awardeeGrantIndex, "AwardeeMSP/bob", "G1") || !l.indexed(awardeeGrantIndex, "AwardeeMSP/erin", "G1") {
+		t.Fatalf("AssignGrant and AddAwardee did not index the awardees")
+	}
+
+	l.must(l.run(awardee, nil, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.RejectGrant(ctx, "G1")
+		return err
+	}))
+	l.must(l.run(grantor, nil, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.DeleteGrant(ctx, "G1")
+		return err
+	}))
+	if l.indexed(grantorGrantIndex, "GrantorMSP/alice", "G1") || l.indexed(awardeeGrantIndex, "AwardeeMSP/bob", "G1") || l.indexed(awardeeGrantIndex, "AwardeeMSP/erin", "G1") {
+		t.Fatalf("DeleteGrant left index keys behind")
+	}
+}
+
+func TestRebuildIndexes(t *testing.T) {
+	l := newLedger(t)
+	l.activeGrant("G1")
+	l.addSubawardee("G1")
+	l.must(l.initiate(newGrant("G2")))
+
+	// Grants written before the indexes existed have no keys
+	l.must(l.stub.Run(nil, func() error {
+		ctx := l.stub
+		for _, key := range [][]string{{grantorGrantIndex, "GrantorMSP/alice", "G1"}, {grantorGrantIndex, "GrantorMSP/alice", "G2"}, {awardeeGrantIndex, "AwardeeMSP/bob", "G1"}, {awardeeGrantIndex, "SubawardeeMSP/carol", "G1"}} {
+			indexKey, err := ctx.CreateCompositeKey(key[0], key[1:])
+			if err != nil {
+				return err
+			}
+			err = ctx.DelState(indexKey)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+
+	allGrantsUser := func() int {
+		var grants []Grant
+		l.must(l.run(subawardee, nil, func(ctx contractapi.TransactionContextInterface) error {
+			var err error
+			grants, err = l.contract.GetAllGrantsUser(ctx)
+			return err
+		}))
+		return len(grants)
+	}
+	if n := allGrantsUser(); n != 0 {
+		t.Fatalf("got %d grants without index keys", n)
+	}
+
+	var indexed int
+	l.must(l.run(grantor, nil, func(ctx contractapi.TransactionContextInterface) error {
+		var err error
+		indexed, err = l.contract.RebuildIndexes(ctx)
+		return err
+	}))
+	if indexed != 2 {
+		t.Fatalf("indexed %d grants, want 2", indexed)
+	}
+	if n := allGrantsUser(); n != 1 {
+		t.Fatalf("got %d grants after RebuildIndexes, want 1", n)
+	}
+}
+
+func TestRebuildIndexesStampsDocType(t *testing.T) {
+	l := newLedger(t)
+	l.activeGrant("G1")
+	_, err := l.reimburse(awardee, "G1", "P1", "AwardeeMSP/bob", item("Personnel", "100"))
+	l.must(err)
+
+	grantKey, _ := l.stub.CreateCompositeKey("grant", []string{"G1"})
+	paymentKey, _ := l.stub.CreateCompositeKey(paymentObjectType, []string{"G1", "P1"})
+	docType := func(key string) string {
+		var doc struct {
+			Doc_Type string `json:"doc_type"`
+		}
+		l.must(json.Unmarshal(l.stub.State(key), &doc))
+		return doc.Doc_Type
+	}
+
+	// Store both as older versions did, without a doc_type
+	l.must(l.stub.Run(nil, func() error {
+		for _, key := range []string{grantKey, paymentKey} {
+			var doc map[string]interface{}
+			err := json.Unmarshal(l.stub.State(key), &doc)
+			if err != nil {
+				return err
+			}
+			delete(doc, "doc_type")
+			value, err := json.Marshal(doc)
+			if err != nil {
+				return err
+			}
+			err = l.stub.PutState(key, value)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+	if docType(grantKey) != "" || docType(paymentKey) != "" {
+		t.Fatalf("doc_type left on the stored grant or payment")
+	}
+
+	l.must(l.run(grantor, nil, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.RebuildIndexes(ctx)
+		return err
+	}))
+	if docType(grantKey) != "grant" || docType(paymentKey) != "payment" {
+		t.Fatalf("got doc_type %q and %q", docType(grantKey), docType(paymentKey))
+	}
+}
@@ -0,0 +1,200 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// GrantStatus is the lifecycle state of a grant.
+type GrantStatus string
+
+const (
+	GrantDraft     GrantStatus = "Draft"
+	GrantAssigned  GrantStatus = "Assigned"
+	GrantActive    GrantStatus = "Active"
+	GrantSuspended GrantStatus = "Suspended"
+	GrantClosed    GrantStatus = "Closed"
+	GrantRevoked   GrantStatus = "Revoked"
+	GrantRejected  GrantStatus = "Rejected"
+)
+
+// legacyGrantStatuses maps the free-text statuses older grants were stored with.
+var legacyGrantStatuses = map[string]GrantStatus{
+	"Not Assigned": GrantDraft,
+	"Pending":      GrantAssigned,
+	"Approved":     GrantActive,
+}
+
+// GrantTransition is a status a grant may move to and the function that moves it there.
+type GrantTransition struct {
+	Status   GrantStatus `json:"status"`
+	Function string      `json:"function"`
+}
+
+// grantTransitions is the grant lifecycle. Every status change goes through
+// transitionGrant, which only allows the moves listed here; statuses without an
+// entry are terminal.
+var grantTransitions = map[GrantStatus][]GrantTransition{
+	GrantDraft: {
+		{Status: GrantAssigned, Function: "AssignGrant"},
+	},
+	GrantAssigned: {
+		{Status: GrantActive, Function: "AcceptGrant"},
+		{Status: GrantRejected, Function: "RejectGrant"},
+		{Status: GrantRevoked, Function: "RevokeGrant"},
+	},
+	GrantActive: {
+		{Status: GrantSuspended, Function: "SuspendGrant"},
+		{Status: GrantClosed, Function: "CloseGrant"},
+		{Status: GrantRevoked, Function: "RevokeGrant"},
+	},
+	GrantSuspended: {
+		{Status: GrantActive, Function: "ReinstateGrant"},
+		{Status: GrantClosed, Function: "CloseGrant"},
+		{Status: GrantRevoked, Function: "RevokeGrant"},
+	},
+}
+
+// parseGrantStatus accepts both current and legacy status names.
+func parseGrantStatus(status string) (GrantStatus, error) {
+	if legacy, ok := legacyGrantStatuses[status]; ok {
+		return legacy, nil
+	}
+	switch GrantStatus(status) {
+	case GrantDraft, GrantAssigned, GrantActive, GrantSuspended, GrantClosed, GrantRevoked, GrantRejected:
+		return GrantStatus(status), nil
+	}
+	return "", errValidation("unknown grant status %q", status).with("status", status)
+}
+
+// UnmarshalJSON maps legacy status names so stored grants decode to the current lifecycle.
+func (gs *GrantStatus) UnmarshalJSON(data []byte) error {
+	var status string
+	if err := json.Unmarshal(data, &status); err != nil {
+		return err
+	}
+	if legacy, ok := legacyGrantStatuses[status]; ok {
+		*gs = legacy
+		return nil
+	}
+	*gs = GrantStatus(status)
+	return nil
+}
+
+// transitionGrant moves the grant to status if the lifecycle allows it.
+func transitionGrant(grant *Grant, status GrantStatus) error {
+	for _, transition := range grantTransitions[grant.Status] {
+		if transition.Status == status {
+			grant.Status = status
+			return nil
+		}
+	}
+
+	allowed := []GrantStatus{}
+	for _, transition := range grantTransitions[grant.Status] {
+		allowed = append(allowed, transition.Status)
+	}
+	return errInvalidState("Grant %s cannot move from %s to %s, allowed transitions: %s", grant.ID, grant.Status, status, describeTransitions(grant.Status)).
+		with("grant_id", grant.ID).
+		with("status", grant.Status).
+		with("allowed", allowed)
+}
+
+// checkGrantStatus fails unless the grant is in one of the given statuses.
+func checkGrantStatus(grant *Grant, statuses ...GrantStatus) error {
+	var names []string
+	for _, status := range statuses {
+		if grant.Status == status {
+			return nil
+		}
+		names = append(names, string(status))
+	}
+
+	return errInvalidState("Grant %s is in %s status, expected %s", grant.ID, grant.Status, strings.Join(names, " or ")).
+		with("grant_id", grant.ID).
+		with("status", grant.Status).
+		with("allowed", statuses)
+}
+
+func describeTransitions(status GrantStatus) string {
+	transitions := grantTransitions[status]
+	if len(transitions) == 0 {
+		return "none"
+	}
+
+	var names []string
+	for _, transition := range transitions {
+		names = append(names, fmt.Sprintf("%s (%s)", transition.Status, transition.Function))
+	}
+	return strings.Join(names, ", ")
+}
+
+// GetAllowedTransitions returns the statuses the grant can move to next and the functions that do it.
+func (s *SmartContract) GetAllowedTransitions(ctx contractapi.TransactionContextInterface, grantID string) ([]GrantTransition, error) {
+	caller, err := authorize(ctx, "GetAllowedTransitions")
+	if err != nil {
+		return nil, err
+	}
+
+	grant, _, err := readGrantAs(ctx, caller, grantID)
+	if err != nil {
+		return nil, err
+	}
+
+	transitions := []GrantTransition{}
+	transitions = append(transitions, grantTransitions[grant.Status]...)
+	return transitions, nil
+}
+
+// SuspendGrant puts an active grant on hold - Grantor
+func (s *SmartContract) SuspendGrant(ctx contractapi.TransactionContextInterface, id string) (bool, error) {
+	return s.moveGrant(ctx, "SuspendGrant", id, GrantSuspended, "suspend", EventGrantSuspended)
+}
+
+// ReinstateGrant makes a suspended grant active again - Grantor
+func (s *SmartContract) ReinstateGrant(ctx contractapi.TransactionContextInterface, id string) (bool, error) {
+	return s.moveGrant(ctx, "ReinstateGrant", id, GrantActive, "reinstate", EventGrantReinstated)
+}
+
+// CloseGrant closes out an active or suspended grant - Grantor
+func (s *SmartContract) CloseGrant(ctx contractapi.TransactionContextInterface, id string) (bool, error) {
+	return s.moveGrant(ctx, "CloseGrant", id, GrantClosed, "close", EventGrantClosed)
+}
+
+// moveGrant applies a lifecycle transition on behalf of the grant's grantor.
+func (s *SmartContract) moveGrant(ctx contractapi.TransactionContextInterface, function string, id string, status GrantStatus, action string, event string) (bool, error) {
+	caller, err := authorize(ctx, function)
+	if err != nil {
+		return false, err
+	}
+
+	grant, err := readGrant(ctx, id)
+	if err != nil {
+		return false, err
+	}
+
+	err = checkGrantOwner(caller, grant, action)
+	if err != nil {
+		return false, err
+	}
+
+	previous := grant.Status
+	err = transitionGrant(grant, status)
+	if err != nil {
+		return false, err
+	}
+
+	err = putGrant(ctx, grant)
+	if err != nil {
+		return false, err
+	}
+
+	err = emitGrantEvent(ctx, caller, event, grant, previous)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,88 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestTransitionGrant(t *testing.T) {
+	tests := []struct {
+		from    GrantStatus
+		to      GrantStatus
+		allowed bool
+	}{
+		{from: GrantDraft, to: GrantAssigned, allowed: true},
+		{from: GrantDraft, to: GrantActive},
+		{from: GrantDraft, to: GrantRevoked},
+		{from: GrantAssigned, to: GrantActive, allowed: true},
+		{from: GrantAssigned, to: GrantRejected, allowed: true},
+		{from: GrantAssigned, to: GrantRevoked, allowed: true},
+		{from: GrantAssigned, to: GrantSuspended},
+		{from: GrantActive, to: GrantSuspended, allowed: true},
+		{from: GrantActive, to: GrantClosed, allowed: true},
+		{from: GrantActive, to: GrantAssigned},
+		{from: GrantSuspended, to: GrantActive, allowed: true},
+		{from: GrantSuspended, to: GrantClosed, allowed: true},
+		{from: GrantClosed, to: GrantActive},
+		{from: GrantRevoked, to: GrantActive},
+		{from: GrantRejected, to: GrantAssigned},
+	}
+	for _, test := range tests {
+		grant := Grant{ID: "g1", Status: test.from}
+		err := transitionGrant(&grant, test.to)
+		if test.allowed {
+			if err != nil || grant.Status != test.to {
+				t.Errorf("%s to %s: got %s, %v", test.from, test.to, grant.Status, err)
+			}
+			continue
+		}
+		if err == nil || grant.Status != test.from {
+			t.Errorf("%s to %s was allowed", test.from, test.to)
+		}
+	}
+}
+
+func TestTransitionErrorNamesTheAllowedMoves(t *testing.T) {
+	grant := Grant{ID: "g1", Status: GrantAssigned}
+	err := transitionGrant(&grant, GrantClosed)
+	if err == nil || !strings.Contains(err.Error(), "Active (AcceptGrant), Rejected (RejectGrant), Revoked (RevokeGrant)") {
+		t.Fatalf("got %v", err)
+	}
+	contractErr := err.(*ContractError)
+	allowed, _ := contractErr.Details["allowed"].([]GrantStatus)
+	if contractErr.Code != CodeInvalidState || len(allowed) != 3 || allowed[0] != GrantActive {
+		t.Fatalf("got %s error with details %v", contractErr.Code, contractErr.Details)
+	}
+
+	grant.Status = GrantClosed
+	err = transitionGrant(&grant, GrantActive)
+	if err == nil || !strings.Contains(err.Error(), "allowed transitions: none") {
+		t.Fatalf("got %v", err)
+	}
+}
+
+func TestLegacyGrantStatuses(t *testing.T) {
+	tests := map[string]GrantStatus{
+		"Not Assigned": GrantDraft,
+		"Pending":      GrantAssigned,
+		"Approved":     GrantActive,
+		"Revoked":      GrantRevoked,
+	}
+	for stored, want := range tests {
+		var grant Grant
+		err := json.Unmarshal([]byte(`{"ID":"g1","status":"`+stored+`"}`), &grant)
+		if err != nil || grant.Status != want {
+			t.Errorf("stored %q decoded as %q, %v, want %q", stored, grant.Status, err, want)
+		}
+
+		status, err := parseGrantStatus(stored)
+		if err != nil || status != want {
+			t.Errorf("parseGrantStatus(%q) = %q, %v, want %q", stored, status, err, want)
+		}
+	}
+
+	if _, err := parseGrantStatus("Approved-ish"); err == nil {
+		t.Errorf("unknown status was parsed")
+	}
+}
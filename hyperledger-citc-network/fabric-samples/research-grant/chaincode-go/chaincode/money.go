@@ -0,0 +1,328 @@
+package chaincode
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Money is an exact decimal amount such as "1234.50" in the currency of the
+// grant it belongs to. It is held as a string so that JSON never routes it
+// through float64; all arithmetic is done on int64 minor units (cents for
+// USD) obtained with minor and turned back with moneyFromMinor.
+type Money string
+
+// defaultCurrency is assumed for grants created before amounts carried a currency.
+const defaultCurrency = "USD"
+
+// currencyExponents lists the ISO 4217 currencies the contract accepts with
+// the number of minor-unit digits each one has.
+var currencyExponents = map[string]int{
+	"AED": 2,
+	"AUD": 2,
+	"BHD": 3,
+	"CAD": 2,
+	"CHF": 2,
+	"CNY": 2,
+	"EUR": 2,
+	"GBP": 2,
+	"INR": 2,
+	"JPY": 0,
+	"KRW": 0,
+	"KWD": 3,
+	"OMR": 3,
+	"SAR": 2,
+	"USD": 2,
+}
+
+// currencyExponent returns the number of minor-unit digits of an ISO 4217 currency code.
+func currencyExponent(currency string) (int, error) {
+	exponent, ok := currencyExponents[currency]
+	if !ok {
+		return 0, fmt.Errorf("currency %q is not a supported ISO 4217 code", currency)
+	}
+	return exponent, nil
+}
+
+// grantCurrency returns the grant's currency, defaulting for legacy grants.
+func grantCurrency(grant *Grant) string {
+	if grant.Currency == "" {
+		return defaultCurrency
+	}
+	return grant.Currency
+}
+
+// decimalPattern is the form amounts are written in. big.Rat alone would also
+// take fractions like "1/3", exponents and hex.
+var decimalPattern = regexp.MustCompile(`^-?\d+(\.\d+)?$`)
+
+// parseDecimal returns the amount as a big.Rat, accepting plain decimals only.
+func parseDecimal(value string) (*big.Rat, error) {
+	if !decimalPattern.MatchString(value) {
+		return nil, fmt.Errorf("amount %q is not a decimal number", value)
+	}
+	rat, ok := new(big.Rat).SetString(value)
+	if !ok {
+		return nil, fmt.Errorf("amount %q is not a decimal number", value)
+	}
+	return rat, nil
+}
+
+// UnmarshalJSON accepts both decimal strings and bare JSON numbers written
+// without an exponent. The literal text of a number is kept, so 0.1 stays
+// exactly 0.1.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		*m = ""
+		return nil
+	}
+
+	var value string
+	if len(data) > 0 && data[0] == '"' {
+		if err := json.Unmarshal(data, &value); err != nil {
+			return err
+		}
+	} else {
+		value = string(data)
+	}
+
+	value = strings.TrimSpace(value)
+	if value != "" {
+		if _, err := parseDecimal(value); err != nil {
+			return err
+		}
+	}
+	*m = Money(value)
+	return nil
+}
+
+// minor converts the amount to minor units of currency. Amounts with more
+// decimals than the currency has are rejected rather than rounded.
+func (m Money) minor(currency string) (int64, error) {
+	scaled, err := m.scaled(currency)
+	if err != nil {
+		return 0, err
+	}
+	if !scaled.IsInt() {
+		return 0, fmt.Errorf("amount %s has more decimals than %s allows", m, currency)
+	}
+	return ratToInt64(m, scaled.Num())
+}
+
+// roundedMinor converts the amount to minor units of currency, rounding half
+// away from zero. It is only meant for migrating float-valued records.
+func (m Money) roundedMinor(currency string) (int64, error) {
+	scaled, err := m.scaled(currency)
+	if err != nil {
+		return 0, err
+	}
+
+	num := new(big.Int).Abs(scaled.Num())
+	quo, rem := new(big.Int).QuoRem(num, scaled.Denom(), new(big.Int))
+	if new(big.Int).Mul(rem, big.NewInt(2)).Cmp(scaled.Denom()) >= 0 {
+		quo.Add(quo, big.NewInt(1))
+	}
+	if scaled.Sign() < 0 {
+		quo.Neg(quo)
+	}
+	return ratToInt64(m, quo)
+}
+
+func (m Money) scaled(currency string) (*big.Rat, error) {
+	exponent, err := currencyExponent(currency)
+	if err != nil {
+		return nil, err
+	}
+	if m == "" {
+		return new(big.Rat), nil
+	}
+
+	value, err := parseDecimal(string(m))
+	if err != nil {
+		return nil, err
+	}
+	factor := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(exponent)), nil)
+	return value.Mul(value, new(big.Rat).SetInt(factor)), nil
+}
+
+func ratToInt64(m Money, value *big.Int) (int64, error) {
+	if !value.IsInt64() {
+		return 0, fmt.Errorf("amount %s is out of range", m)
+	}
+	return value.Int64(), nil
+}
+
+// moneyFromMinor formats minor units of currency as a canonical decimal, e.g. 123450 USD as "1234.50".
+func moneyFromMinor(units int64, currency string) Money {
+	exponent, ok := currencyExponents[currency]
+	if !ok {
+		exponent = currencyExponents[defaultCurrency]
+	}
+
+	digits := new(big.Int).Abs(big.NewInt(units)).String()
+	if exponent > 0 {
+		if len(digits) <= exponent {
+			digits = strings.Repeat("0", exponent-len(digits)+1) + digits
+		}
+		digits = digits[:len(digits)-exponent] + "." + digits[len(digits)-exponent:]
+	}
+	if units < 0 {
+		digits = "-" + digits
+	}
+	return Money(digits)
+}
+
+// percentOf returns percentage % of units, rounded down to a whole minor unit.
+// The percentage is taken to two decimals so the result stays exact.
+func percentOf(units int64, percentage float64) int64 {
+	basisPoints := big.NewInt(int64(percentage*100 + 0.5))
+	share := new(big.Int).Mul(big.NewInt(units), basisPoints)
+	return share.Quo(share, big.NewInt(10000)).Int64()
+}
+
+// checkBudget validates the grant's currency, rewrites its amounts in canonical
+// form and checks that the benefit lines add up to the grant amount exactly.
+func checkBudget(grant *Grant) error {
+	currency := grant.Currency
+	if _, err := currencyExponent(currency); err != nil {
+		return errValidation("%v", err).with("currency", currency)
+	}
+
+	amount, err := grant.Amount.minor(currency)
+	if err != nil {
+		return invalidAmount(err, "invalid grant amount")
+	}
+	grant.Amount = moneyFromMinor(amount, currency)
+
+	var benefitAmount int64
+	for i, benefit := range grant.Benefit {
+		units, err := benefit.Amount.minor(currency)
+		if err != nil {
+			return invalidAmount(err, "invalid amount for %s benefit", benefit.Benefit)
+		}
+		grant.Benefit[i].Amount = moneyFromMinor(units, currency)
+		benefitAmount += units
+	}
+
+	if benefitAmount != amount {
+		return errValidation("Total Benefit %s doesn't match with the Grant Amount %s", moneyFromMinor(benefitAmount, currency), grant.Amount).
+			with("total", moneyFromMinor(benefitAmount, currency)).
+			with("amount", grant.Amount)
+	}
+	return nil
+}
+
+// roundMoney rewrites m in canonical form, rounding away float artifacts, and
+// reports whether the stored value changed.
+func roundMoney(m *Money, currency string) (bool, error) {
+	units, err := m.roundedMinor(currency)
+	if err != nil {
+		return false, err
+	}
+	rounded := moneyFromMinor(units, currency)
+	if rounded == *m {
+		return false, nil
+	}
+	*m = rounded
+	return true, nil
+}
+
+// MigrateMoney converts grants and payments stored with float amounts to
+// canonical decimal strings in the grant currency, defaulting the currency to
+// USD. It returns the number of grants rewritten - Grantor
+func (s *SmartContract) MigrateMoney(ctx contractapi.TransactionContextInterface) (int, error) {
+	caller, err := authorize(ctx, "MigrateMoney")
+	if err != nil {
+		return 0, err
+	}
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey("grant", []string{})
+	if err != nil {
+		return 0, errInternal("failed to query the world state: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	var migrated int
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return 0, errInternal("failed to read from world state: %v", err)
+		}
+
+		var grant Grant
+		err = json.Unmarshal(queryResponse.Value, &grant)
+		if err != nil {
+			return 0, errInternal("failed to unmarshal JSON: %v", err)
+		}
+
+		changed := grant.Currency == ""
+		currency := grantCurrency(&grant)
+		grant.Currency = currency
+
+		amounts := []*Money{&grant.Amount, &grant.Cashed_Out, &grant.Paid_Amount}
+		for i := range grant.Benefit {
+			amounts = append(amounts, &grant.Benefit[i].Amount)
+		}
+		for i := range grant.Payment {
+			amounts = append(amounts, paymentAmounts(&grant.Payment[i])...)
+		}
+		for _, amount := range amounts {
+			rounded, err := roundMoney(amount, currency)
+			if err != nil {
+				return 0, errInternal("Grant %s: %v", grant.ID, err).with("grant_id", grant.ID)
+			}
+			changed = changed || rounded
+		}
+
+		payments, err := getGrantPayments(ctx, grant.ID)
+		if err != nil {
+			return 0, err
+		}
+		for i := range payments {
+			var paymentChanged bool
+			for _, amount := range paymentAmounts(&payments[i]) {
+				rounded, err := roundMoney(amount, currency)
+				if err != nil {
+					return 0, errInternal("Payment %s of the Grant %s: %v", payments[i].ID, grant.ID, err).with("grant_id", grant.ID).with("payment_id", payments[i].ID)
+				}
+				paymentChanged = paymentChanged || rounded
+			}
+			if paymentChanged {
+				err = putPayment(ctx, &payments[i])
+				if err != nil {
+					return 0, err
+				}
+				changed = true
+			}
+		}
+
+		if !changed {
+			continue
+		}
+		err = putGrant(ctx, &grant)
+		if err != nil {
+			return 0, err
+		}
+		migrated++
+	}
+
+	err = emitMigrationEvent(ctx, caller, EventMoneyMigrated, migrated)
+	if err != nil {
+		return 0, err
+	}
+
+	return migrated, nil
+}
+
+func paymentAmounts(payment *Payment) []*Money {
+	amounts := []*Money{&payment.Total}
+	for i := range payment.Item {
+		amounts = append(amounts, &payment.Item[i].Amount)
+	}
+	return amounts
+}
@@ -0,0 +1,138 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestMoneyUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Money
+		fails bool
+	}{
+		{input: `"1234.50"`, want: "1234.50"},
+		{input: `0.1`, want: "0.1"},
+		{input: `" 7 "`, want: "7"},
+		{input: `null`, want: ""},
+		{input: `"twelve"`, fails: true},
+		{input: `"1/3"`, fails: true},
+		{input: `"1e3"`, fails: true},
+		{input: `1e3`, fails: true},
+		{input: `"0x10"`, fails: true},
+		{input: `"+5"`, fails: true},
+		{input: `".5"`, fails: true},
+		{input: `"5."`, fails: true},
+	}
+	for _, test := range tests {
+		var m Money
+		err := json.Unmarshal([]byte(test.input), &m)
+		if test.fails {
+			if err == nil {
+				t.Errorf("%s: got %q, want an error", test.input, m)
+			}
+			continue
+		}
+		if err != nil || m != test.want {
+			t.Errorf("%s: got %q, %v, want %q", test.input, m, err, test.want)
+		}
+	}
+}
+
+func TestMoneyMinor(t *testing.T) {
+	tests := []struct {
+		amount   Money
+		currency string
+		want     int64
+		fails    bool
+	}{
+		{amount: "1234.5", currency: "USD", want: 123450},
+		{amount: "0.1", currency: "USD", want: 10},
+		{amount: "", currency: "USD", want: 0},
+		{amount: "1000", currency: "JPY", want: 1000},
+		{amount: "1.234", currency: "KWD", want: 1234},
+		{amount: "1.005", currency: "USD", fails: true},
+		{amount: "1.5", currency: "JPY", fails: true},
+		{amount: "1", currency: "XXX", fails: true},
+		{amount: "100000000000000000000", currency: "USD", fails: true},
+		{amount: "300/100", currency: "USD", fails: true},
+	}
+	for _, test := range tests {
+		got, err := test.amount.minor(test.currency)
+		if test.fails {
+			if err == nil {
+				t.Errorf("%s %s: got %d, want an error", test.amount, test.currency, got)
+			}
+			continue
+		}
+		if err != nil || got != test.want {
+			t.Errorf("%s %s: got %d, %v, want %d", test.amount, test.currency, got, err, test.want)
+		}
+	}
+}
+
+func TestMoneyFromMinor(t *testing.T) {
+	tests := []struct {
+		units    int64
+		currency string
+		want     Money
+	}{
+		{units: 123450, currency: "USD", want: "1234.50"},
+		{units: 5, currency: "USD", want: "0.05"},
+		{units: -5, currency: "USD", want: "-0.05"},
+		{units: 1000, currency: "JPY", want: "1000"},
+		{units: 1, currency: "BHD", want: "0.001"},
+	}
+	for _, test := range tests {
+		if got := moneyFromMinor(test.units, test.currency); got != test.want {
+			t.Errorf("moneyFromMinor(%d, %s) = %s, want %s", test.units, test.currency, got, test.want)
+		}
+	}
+}
+
+func TestRoundMoney(t *testing.T) {
+	m := Money("0.30000000000000004")
+	changed, err := roundMoney(&m, "USD")
+	if err != nil || !changed || m != "0.30" {
+		t.Fatalf("got %s, %v, %v, want 0.30 changed", m, changed, err)
+	}
+	changed, err = roundMoney(&m, "USD")
+	if err != nil || changed {
+		t.Fatalf("rounding 0.30 again reported a change")
+	}
+
+	m = Money("-2.675")
+	_, err = roundMoney(&m, "USD")
+	if err != nil || m != "-2.68" {
+		t.Fatalf("got %s, %v, want -2.68", m, err)
+	}
+}
+
+func TestPercentOf(t *testing.T) {
+	if got := percentOf(40000, 20); got != 8000 {
+		t.Errorf("20%% of 40000 = %d, want 8000", got)
+	}
+	if got := percentOf(999, 33.33); got != 332 {
+		t.Errorf("33.33%% of 999 = %d, want 332", got)
+	}
+}
+
+func TestCheckBudget(t *testing.T) {
+	grant := Grant{Amount: "0.3", Currency: "USD", Benefit: []Benefit{{Benefit: "Travel", Amount: "0.1"}, {Benefit: "Supplies", Amount: "0.2"}}}
+	if err := checkBudget(&grant); err != nil {
+		t.Fatalf("0.1 + 0.2 = 0.3 was rejected: %v", err)
+	}
+	if grant.Amount != "0.30" || grant.Benefit[0].Amount != "0.10" {
+		t.Fatalf("amounts were not made canonical: %s and %s", grant.Amount, grant.Benefit[0].Amount)
+	}
+
+	grant = Grant{Amount: "100", Currency: "USD", Benefit: []Benefit{{Benefit: "Travel", Amount: "99.99"}}}
+	if err := checkBudget(&grant); err == nil {
+		t.Fatalf("benefits of 99.99 matched an amount of 100")
+	}
+
+	grant = Grant{Amount: "100", Currency: "usd", Benefit: []Benefit{{Benefit: "Travel", Amount: "100"}}}
+	if err := checkBudget(&grant); err == nil {
+		t.Fatalf("lower-case currency code was accepted")
+	}
+}
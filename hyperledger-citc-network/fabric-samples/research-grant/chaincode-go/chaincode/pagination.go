@@ -0,0 +1,264 @@
+package chaincode
+
+import (
+	"encoding/json"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// maxPageSize caps the page size of the paginated queries, keeping responses
+// under the gRPC message limit and the peer query timeout.
+const maxPageSize = 100
+
+// GrantPage is one page of a paginated grant query. Records are in grant ID
+// order. Fetched_Count is the number of grants read for this page; filtered
+// queries can return fewer records than that. An empty Bookmark means there
+// are no more pages, otherwise pass it back to read the next one.
+type GrantPage struct {
+	Records       []Grant `json:"records"`
+	Fetched_Count int32   `json:"fetchedCount"`
+	Bookmark      string  `json:"bookmark"`
+}
+
+// PaymentPage is one page of a paginated payment query, in grant ID and
+// payment ID order. The fields mean the same as in GrantPage.
+type PaymentPage struct {
+	Records       []Payment `json:"records"`
+	Fetched_Count int32     `json:"fetchedCount"`
+	Bookmark      string    `json:"bookmark"`
+}
+
+func checkPageSize(pageSize int32) error {
+	if pageSize < 1 || pageSize > maxPageSize {
+		return errValidation("page size must be between 1 and %d, got %d", maxPageSize, pageSize).with("max_page_size", maxPageSize)
+	}
+	return nil
+}
+
+// queryGrantPage reads one page of grants and keeps the ones match accepts.
+// match also gets the grant as stored, for matching on the raw document.
+func queryGrantPage(ctx contractapi.TransactionContextInterface, pageSize int32, bookmark string, match func(*Grant, []byte) (bool, error)) (*GrantPage, error) {
+	err := checkPageSize(pageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	resultsIterator, metadata, err := ctx.GetStub().GetStateByPartialCompositeKeyWithPagination("grant", []string{}, pageSize, bookmark)
+	if err != nil {
+		return nil, errInternal("failed to query the world state: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	page := GrantPage{Records: []Grant{}}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, errInternal("failed to read from world state: %v", err)
+		}
+
+		var grant Grant
+		err = json.Unmarshal(queryResponse.Value, &grant)
+		if err != nil {
+			return nil, errInternal("failed to unmarshal JSON: %v", err)
+		}
+		ok, err := match(&grant, queryResponse.Value)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		err = setPaymentTotals(ctx, &grant)
+		if err != nil {
+			return nil, err
+		}
+		page.Records = append(page.Records, grant)
+	}
+
+	if metadata != nil {
+		page.Fetched_Count = metadata.FetchedRecordsCount
+		page.Bookmark = metadata.Bookmark
+	}
+	return &page, nil
+}
+
+// queryPaymentPage reads one page of payment records and keeps the ones match
+// accepts. Payments still embedded in unmigrated grants are not included.
+func queryPaymentPage(ctx contractapi.TransactionContextInterface, pageSize int32, bookmark string, match func(*Payment) (bool, error)) (*PaymentPage, error) {
+	err := checkPageSize(pageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	resultsIterator, metadata, err := ctx.GetStub().GetStateByPartialCompositeKeyWithPagination(paymentObjectType, []string{}, pageSize, bookmark)
+	if err != nil {
+		return nil, errInternal("failed to query the world state: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	page := PaymentPage{Records: []Payment{}}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, errInternal("failed to read from world state: %v", err)
+		}
+
+		var payment Payment
+		err = json.Unmarshal(queryResponse.Value, &payment)
+		if err != nil {
+			return nil, errInternal("failed to unmarshal JSON: %v", err)
+		}
+		err = normalizePayment(&payment)
+		if err != nil {
+			return nil, err
+		}
+
+		ok, err := match(&payment)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			page.Records = append(page.Records, payment)
+		}
+	}
+
+	if metadata != nil {
+		page.Fetched_Count = metadata.FetchedRecordsCount
+		page.Bookmark = metadata.Bookmark
+	}
+	return &page, nil
+}
+
+// isParticipant reports whether the caller is the grantor, a funder, an
+// awardee or a subawardee of the grant. Both the ID and the org must match:
+// the grantor's and funders' orgs are those the grant records, and an
+// awardee's is the org its ID is qualified with.
+func isParticipant(grant *Grant, caller *Caller) bool {
+	if caller.ID == grant.Grantor_ID && orgName(caller.MSPID) == grant.Grantor {
+		return true
+	}
+	if funder := findFunder(grant.Funder, caller.ID); funder != nil && funder.MSP == caller.MSPID {
+		return true
+	}
+	for _, awardee := range grant.Awardee {
+		if awardee.ID == caller.ID && userMSP(awardee.ID) == caller.MSPID {
+			return true
+		}
+	}
+	return false
+}
+
+// GetAllGrantsWithPagination returns one page of all grants the user may read.
+func (s *SmartContract) GetAllGrantsWithPagination(ctx contractapi.TransactionContextInterface, pageSize int32, bookmark string) (*GrantPage, error) {
+	caller, err := authorize(ctx, "GetAllGrantsWithPagination")
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := queryGrantPage(ctx, pageSize, bookmark, func(grant *Grant, value []byte) (bool, error) {
+		return grantAccessOf(caller, grant) != accessNone, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i := range page.Records {
+		viewGrant(&page.Records[i], grantAccessOf(caller, &page.Records[i]))
+	}
+	return page, nil
+}
+
+// GetAllGrantsUserWithPagination returns one page of the grants the user takes part in.
+func (s *SmartContract) GetAllGrantsUserWithPagination(ctx contractapi.TransactionContextInterface, pageSize int32, bookmark string) (*GrantPage, error) {
+	caller, err := authorize(ctx, "GetAllGrantsUserWithPagination")
+	if err != nil {
+		return nil, err
+	}
+
+	return queryUserGrantPage(ctx, caller, pageSize, bookmark, func(grant *Grant, value []byte) (bool, error) {
+		return true, nil
+	})
+}
+
+// GetAllApprovedGrantsWithPagination returns one page of the active grants the user takes part in.
+func (s *SmartContract) GetAllApprovedGrantsWithPagination(ctx contractapi.TransactionContextInterface, pageSize int32, bookmark string) (*GrantPage, error) {
+	caller, err := authorize(ctx, "GetAllApprovedGrantsWithPagination")
+	if err != nil {
+		return nil, err
+	}
+
+	return queryUserGrantPage(ctx, caller, pageSize, bookmark, func(grant *Grant, value []byte) (bool, error) {
+		return grant.Status == GrantActive, nil
+	})
+}
+
+// GetGrantsByStatusWithPagination returns one page of the grants the user takes part in with the given status.
+func (s *SmartContract) GetGrantsByStatusWithPagination(ctx contractapi.TransactionContextInterface, status string, pageSize int32, bookmark string) (*GrantPage, error) {
+	caller, err := authorize(ctx, "GetGrantsByStatusWithPagination")
+	if err != nil {
+		return nil, err
+	}
+
+	grantStatus, err := parseGrantStatus(status)
+	if err != nil {
+		return nil, err
+	}
+
+	return queryUserGrantPage(ctx, caller, pageSize, bookmark, func(grant *Grant, value []byte) (bool, error) {
+		return grant.Status == grantStatus, nil
+	})
+}
+
+// QueryPaymentsWithPagination returns one page of the payments matching
+// filterJSON, a JSON PaymentFilter, in the grants the user may read. Payments
+// of grants the user reads a summary of are redacted as in QueryPayments.
+func (s *SmartContract) QueryPaymentsWithPagination(ctx contractapi.TransactionContextInterface, filterJSON string, pageSize int32, bookmark string) (*PaymentPage, error) {
+	caller, err := authorize(ctx, "QueryPaymentsWithPagination")
+	if err != nil {
+		return nil, err
+	}
+
+	filter, err := parsePaymentFilter(filterJSON)
+	if err != nil {
+		return nil, err
+	}
+	matcher, err := newPaymentMatcher(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	accesses := map[string]grantAccess{}
+	page, err := queryPaymentPage(ctx, pageSize, bookmark, func(payment *Payment) (bool, error) {
+		if !matcher.matchGrant(payment.Grant_ID) || !matcher.match(payment) {
+			return false, nil
+		}
+		access, ok := accesses[payment.Grant_ID]
+		if !ok {
+			grant, err := readGrant(ctx, payment.Grant_ID)
+			if err != nil {
+				return false, err
+			}
+			access = grantAccessOf(caller, grant)
+			accesses[payment.Grant_ID] = access
+		}
+		return access != accessNone, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i := range page.Records {
+		if accesses[page.Records[i].Grant_ID] != accessFull {
+			redactPayment(&page.Records[i])
+		}
+	}
+	return page, nil
+}
+
+func hasPaymentStatus(payment *Payment, statuses []PaymentStatus) bool {
+	for _, status := range statuses {
+		if payment.Status == status {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,218 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"chaincode-go/chaincodetest"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// pageGrants reads every page of query and returns the grant IDs in order
+// with the number of pages read.
+func (l *ledger) pageGrants(query func(ctx contractapi.TransactionContextInterface, bookmark string) (*GrantPage, error)) ([]string, int) {
+	l.t.Helper()
+	var ids []string
+	var pages int
+	bookmark := ""
+	for {
+		var page *GrantPage
+		l.must(l.run(grantor, nil, func(ctx contractapi.TransactionContextInterface) error {
+			var err error
+			page, err = query(ctx, bookmark)
+			return err
+		}))
+		pages++
+		for _, grant := range page.Records {
+			ids = append(ids, grant.ID)
+		}
+		if page.Bookmark == "" {
+			return ids, pages
+		}
+		bookmark = page.Bookmark
+	}
+}
+
+func TestGrantPagination(t *testing.T) {
+	l := newLedger(t)
+	for i := 1; i <= 5; i++ {
+		l.must(l.initiate(newGrant(fmt.Sprintf("G%d", i))))
+	}
+	l.must(l.assign("G2", testAwardee("bob", "Main", AwardeeMSP)))
+	l.must(l.assign("G4", testAwardee("bob", "Main", AwardeeMSP)))
+
+	ids, pages := l.pageGrants(func(ctx contractapi.TransactionContextInterface, bookmark string) (*GrantPage, error) {
+		return l.contract.GetAllGrantsWithPagination(ctx, 2, bookmark)
+	})
+	if want := []string{"G1", "G2", "G3", "G4", "G5"}; !reflect.DeepEqual(ids, want) || pages != 3 {
+		t.Fatalf("got %v in %d pages, want %v in 3", ids, pages, want)
+	}
+
+	// Filters apply within a page, so a page can come back short
+	ids, _ = l.pageGrants(func(ctx contractapi.TransactionContextInterface, bookmark string) (*GrantPage, error) {
+		return l.contract.GetGrantsByStatusWithPagination(ctx, "Assigned", 3, bookmark)
+	})
+	if want := []string{"G2", "G4"}; !reflect.DeepEqual(ids, want) {
+		t.Fatalf("got %v, want %v", ids, want)
+	}
+}
+
+func TestPaymentPagination(t *testing.T) {
+	l := newLedger(t)
+	l.activeGrant("G1")
+	for i := 1; i <= 3; i++ {
+		_, err := l.reimburse(awardee, "G1", fmt.Sprintf("P%d", i), "AwardeeMSP/bob", item("Personnel", "10"))
+		l.must(err)
+	}
+	l.must(l.run(grantor, nil, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.AcceptReimbursement(ctx, "G1", "P2")
+		return err
+	}))
+
+	var page *PaymentPage
+	err := l.run(grantor, nil, func(ctx contractapi.TransactionContextInterface) error {
+		var err error
+		page, err = l.contract.QueryPaymentsWithPagination(ctx, `{"statuses":["Requested"]}`, 2, "")
+		return err
+	})
+	noError(t, err)
+	if len(page.Records) != 1 || page.Records[0].ID != "P1" || page.Fetched_Count != 2 || page.Bookmark == "" {
+		t.Fatalf("got page %+v", page)
+	}
+}
+
+func TestPageSize(t *testing.T) {
+	tests := []struct {
+		pageSize int32
+		wantErr  bool
+	}{
+		{0, true},
+		{1, false},
+		{maxPageSize, false},
+		{maxPageSize + 1, true},
+	}
+
+	l := newLedger(t)
+	for _, tt := range tests {
+		err := l.run(grantor, nil, func(ctx contractapi.TransactionContextInterface) error {
+			_, err := l.contract.GetAllGrantsWithPagination(ctx, tt.pageSize, "")
+			return err
+		})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("page size %d: got error %v, want error %v", tt.pageSize, err, tt.wantErr)
+		}
+	}
+}
+
+func TestQueryPaymentsWithPaginationFollowsGrantAccess(t *testing.T) {
+	l := newLedger(t)
+	l.activeGrant("G1")
+	l.addSubawardee("G1")
+	_, err := l.reimburse(awardee, "G1", "P1", "AwardeeMSP/bob", item("Personnel", "10"))
+	l.must(err)
+	_, err = l.reimburse(subawardee, "G1", "P2", "SubawardeeMSP/carol", item("Equipment", "10"))
+	l.must(err)
+
+	tests := []struct {
+		name     string
+		identity *chaincodetest.Identity
+		filter   string
+		want     []string
+		redacted bool
+	}{
+		{"grantor", grantor, `{}`, []string{"P1", "P2"}, false},
+		{"grantor by awardee", grantor, `{"awardee_id":"SubawardeeMSP/carol"}`, []string{"P2"}, false},
+		{"other grant", grantor, `{"grant_ids":["G2"]}`, nil, false},
+		{"auditor", auditor, `{"statuses":["Requested"]}`, []string{"P1", "P2"}, true},
+		{"other grantor", chaincodetest.NewIdentity(GrantorMSP, "eve"), `{}`, nil, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var page *PaymentPage
+			err := l.run(test.identity, nil, func(ctx contractapi.TransactionContextInterface) error {
+				var err error
+				page, err = l.contract.QueryPaymentsWithPagination(ctx, test.filter, 10, "")
+				return err
+			})
+			noError(t, err)
+			var got []string
+			for _, payment := range page.Records {
+				got = append(got, payment.ID)
+				if payment.Redacted != test.redacted {
+					t.Errorf("payment %s redacted %v, want %v", payment.ID, payment.Redacted, test.redacted)
+				}
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Fatalf("got payments %v, want %v", got, test.want)
+			}
+		})
+	}
+
+	err = l.run(grantor, nil, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.QueryPaymentsWithPagination(ctx, `{"status":["Requested"]}`, 10, "")
+		return err
+	})
+	wantCode(t, err, CodeValidation)
+}
+
+func TestMigratePaymentsResumesFromTheBookmark(t *testing.T) {
+	l := newLedger(t)
+	for i := 1; i <= 3; i++ {
+		l.must(l.initiate(newGrant(fmt.Sprintf("G%d", i))))
+	}
+
+	// Store each grant as older versions did, with its payment embedded
+	l.must(l.stub.Run(nil, func() error {
+		for i := 1; i <= 3; i++ {
+			key, _ := l.stub.CreateCompositeKey("grant", []string{fmt.Sprintf("G%d", i)})
+			var doc map[string]interface{}
+			err := json.Unmarshal(l.stub.State(key), &doc)
+			if err != nil {
+				return err
+			}
+			doc["payment"] = []map[string]interface{}{{"ID": "P1", "awardee_id": "AwardeeMSP/bob", "status": "Pending", "total": "10"}}
+			value, err := json.Marshal(doc)
+			if err != nil {
+				return err
+			}
+			err = l.stub.PutState(key, value)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+
+	migrate := func(bookmark string) *MigrationPage {
+		var page *MigrationPage
+		l.must(l.run(grantor, nil, func(ctx contractapi.TransactionContextInterface) error {
+			var err error
+			page, err = l.contract.MigratePayments(ctx, 2, bookmark)
+			return err
+		}))
+		return page
+	}
+	page := migrate("")
+	if page.Migrated != 2 || page.Fetched_Count != 2 || page.Bookmark == "" {
+		t.Fatalf("got first page %+v", page)
+	}
+	page = migrate(page.Bookmark)
+	if page.Migrated != 1 || page.Fetched_Count != 1 || page.Bookmark != "" {
+		t.Fatalf("got last page %+v", page)
+	}
+	for i := 1; i <= 3; i++ {
+		key, _ := l.stub.CreateCompositeKey(paymentObjectType, []string{fmt.Sprintf("G%d", i), "P1"})
+		if l.stub.State(key) == nil {
+			t.Fatalf("payment of G%d was not migrated", i)
+		}
+	}
+
+	// Running it again finds nothing left to migrate
+	page = migrate("")
+	if page.Migrated != 0 || page.Bookmark == "" {
+		t.Fatalf("got rerun %+v", page)
+	}
+}
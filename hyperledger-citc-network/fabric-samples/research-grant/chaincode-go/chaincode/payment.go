@@ -0,0 +1,271 @@
+package chaincode
+
+import (
+	"encoding/json"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Payments are stored as their own world-state records under the composite key
+// payment~grantID~paymentID, so reimbursement traffic on a grant no longer
+// rewrites (and conflicts on) the grant record itself.
+const paymentObjectType = "payment"
+
+// readPayment returns the payment paymentID of grant grantID.
+func readPayment(ctx contractapi.TransactionContextInterface, grantID string, paymentID string) (*Payment, error) {
+	paymentKey, err := ctx.GetStub().CreateCompositeKey(paymentObjectType, []string{grantID, paymentID})
+	if err != nil {
+		return nil, errInternal("failed to create payment key: %v", err)
+	}
+	paymentJSON, err := ctx.GetStub().GetState(paymentKey)
+	if err != nil {
+		return nil, errInternal("failed to read from world state: %v", err)
+	}
+	if paymentJSON == nil {
+		return nil, paymentNotFound(grantID, paymentID)
+	}
+
+	var payment Payment
+	err = json.Unmarshal(paymentJSON, &payment)
+	if err != nil {
+		return nil, errInternal("failed to unmarshal JSON: %v", err)
+	}
+	err = normalizePayment(&payment)
+	if err != nil {
+		return nil, err
+	}
+
+	return &payment, nil
+}
+
+// normalizePayment converts payment timestamps written in the legacy layout to
+// RFC 3339 and free-text rejection statuses to a Rejection record.
+func normalizePayment(payment *Payment) error {
+	payment.Date = normalizeTime(payment.Date)
+	payment.Status_Date = normalizeTime(payment.Status_Date)
+	return normalizePaymentStatus(payment)
+}
+
+// paymentExists reports whether grant grantID already has a payment with the given id.
+func paymentExists(ctx contractapi.TransactionContextInterface, grantID string, paymentID string) (bool, error) {
+	paymentKey, err := ctx.GetStub().CreateCompositeKey(paymentObjectType, []string{grantID, paymentID})
+	if err != nil {
+		return false, errInternal("failed to create payment key: %v", err)
+	}
+	paymentJSON, err := ctx.GetStub().GetState(paymentKey)
+	if err != nil {
+		return false, errInternal("failed to read from world state: %v", err)
+	}
+
+	return paymentJSON != nil, nil
+}
+
+// putPayment stamps updated_by with the submitter, and doc_type, and writes a
+// payment record under its grant.
+func putPayment(ctx contractapi.TransactionContextInterface, payment *Payment) error {
+	caller, err := getCaller(ctx)
+	if err != nil {
+		return err
+	}
+	payment.Doc_Type = paymentObjectType
+	payment.Updated_By = caller.ID
+
+	paymentKey, err := ctx.GetStub().CreateCompositeKey(paymentObjectType, []string{payment.Grant_ID, payment.ID})
+	if err != nil {
+		return errInternal("failed to create payment key: %v", err)
+	}
+
+	paymentJSON, err := json.Marshal(payment)
+	if err != nil {
+		return errInternal("failed to marshal payment into JSON: %v", err)
+	}
+
+	err = ctx.GetStub().PutState(paymentKey, paymentJSON)
+	if err != nil {
+		return errInternal("failed to put payment into ledger: %v", err)
+	}
+	return nil
+}
+
+// getGrantPayments returns every payment recorded against grant grantID, in key order.
+func getGrantPayments(ctx contractapi.TransactionContextInterface, grantID string) ([]Payment, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(paymentObjectType, []string{grantID})
+	if err != nil {
+		return nil, errInternal("failed to query the world state: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	payments := []Payment{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, errInternal("failed to read from world state: %v", err)
+		}
+
+		var payment Payment
+		err = json.Unmarshal(queryResponse.Value, &payment)
+		if err != nil {
+			return nil, errInternal("failed to unmarshal JSON: %v", err)
+		}
+		err = normalizePayment(&payment)
+		if err != nil {
+			return nil, err
+		}
+		payments = append(payments, payment)
+	}
+
+	return payments, nil
+}
+
+// deleteGrantPayments removes every payment record of grant grantID.
+func deleteGrantPayments(ctx contractapi.TransactionContextInterface, grantID string) error {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(paymentObjectType, []string{grantID})
+	if err != nil {
+		return errInternal("failed to query the world state: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return errInternal("failed to read from world state: %v", err)
+		}
+		err = ctx.GetStub().DelState(queryResponse.Key)
+		if err != nil {
+			return errInternal("failed to delete payment %s: %v", queryResponse.Key, err)
+		}
+	}
+	return nil
+}
+
+// checkPaymentsMigrated rejects payment operations on grants that still carry
+// the legacy embedded Payment slice; MigratePayments has to be run first.
+func checkPaymentsMigrated(grant *Grant) error {
+	if len(grant.Payment) != 0 {
+		return errMigrationRequired("Grant %s still has embedded payments, run MigratePayments first", grant.ID).with("grant_id", grant.ID).with("migration", "MigratePayments")
+	}
+	return nil
+}
+
+// setPaymentTotals derives Paid_Amount and Cashed_Out from the payment records,
+// since payment transitions no longer write the grant. Legacy grants keep
+// their stored totals until they are migrated.
+func setPaymentTotals(ctx contractapi.TransactionContextInterface, grant *Grant) error {
+	if len(grant.Payment) != 0 {
+		return nil
+	}
+
+	payments, err := getGrantPayments(ctx, grant.ID)
+	if err != nil {
+		return err
+	}
+
+	currency := grantCurrency(grant)
+	var paidAmount int64
+	var cashedOut int64
+	for _, payment := range payments {
+		total, err := payment.Total.minor(currency)
+		if err != nil {
+			return storedAmountInvalid(err, "Payment %s has an invalid amount", payment.ID)
+		}
+		switch payment.Status {
+		case PaymentAccepted, PaymentPendingRedeem:
+			paidAmount += total
+		case PaymentRedeemed:
+			cashedOut += total
+		}
+	}
+
+	grant.Paid_Amount = moneyFromMinor(paidAmount, currency)
+	grant.Cashed_Out = moneyFromMinor(cashedOut, currency)
+	return setFunderTotals(grant, payments)
+}
+
+// MigrationPage reports one run of a migration that works through the grants
+// a page at a time. Migrated is the number of grants it rewrote and
+// Fetched_Count the number it read. An empty Bookmark means every grant has
+// been seen, otherwise pass it back to migrate the next page.
+type MigrationPage struct {
+	Migrated      int    `json:"migrated"`
+	Fetched_Count int32  `json:"fetchedCount"`
+	Bookmark      string `json:"bookmark"`
+}
+
+// MigratePayments moves the payments embedded in up to pageSize grants, from
+// bookmark on, into their own payment records. Call it again with the returned
+// bookmark until it is empty. Fabric only pages range queries in read-only
+// transactions, so the bookmark is the key of the first grant of the next page
+// rather than a peer bookmark - Grantor
+func (s *SmartContract) MigratePayments(ctx contractapi.TransactionContextInterface, pageSize int32, bookmark string) (*MigrationPage, error) {
+	caller, err := authorize(ctx, "MigratePayments")
+	if err != nil {
+		return nil, err
+	}
+	err = checkPageSize(pageSize)
+	if err != nil {
+		return nil, err
+	}
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey("grant", []string{})
+	if err != nil {
+		return nil, errInternal("failed to query the world state: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	page := MigrationPage{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, errInternal("failed to read from world state: %v", err)
+		}
+		if queryResponse.Key < bookmark {
+			continue
+		}
+		if page.Fetched_Count == pageSize {
+			page.Bookmark = queryResponse.Key
+			break
+		}
+		page.Fetched_Count++
+
+		var grant Grant
+		err = json.Unmarshal(queryResponse.Value, &grant)
+		if err != nil {
+			return nil, errInternal("failed to unmarshal JSON: %v", err)
+		}
+		if len(grant.Payment) == 0 {
+			continue
+		}
+
+		for _, payment := range grant.Payment {
+			exists, err := paymentExists(ctx, grant.ID, payment.ID)
+			if err != nil {
+				return nil, err
+			}
+			if exists {
+				return nil, errDuplicate("Payment %s of the Grant %s is already stored as a record", payment.ID, grant.ID).with("grant_id", grant.ID).with("payment_id", payment.ID)
+			}
+			payment.Grant_ID = grant.ID
+			err = normalizePayment(&payment)
+			if err != nil {
+				return nil, err
+			}
+			err = putPayment(ctx, &payment)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		grant.Payment = nil
+		err = putGrant(ctx, &grant)
+		if err != nil {
+			return nil, err
+		}
+		page.Migrated++
+	}
+
+	err = emitMigrationEvent(ctx, caller, EventPaymentsMigrated, page.Migrated)
+	if err != nil {
+		return nil, err
+	}
+
+	return &page, nil
+}
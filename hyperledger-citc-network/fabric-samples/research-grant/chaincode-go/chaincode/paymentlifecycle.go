@@ -0,0 +1,187 @@
+package chaincode
+
+import (
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// PaymentStatus is the state of a reimbursement payment. The values match the
+// strings payments have always been stored with.
+type PaymentStatus string
+
+const (
+	PaymentRequested      PaymentStatus = "Requested"
+	PaymentAccepted       PaymentStatus = "Accepted"
+	PaymentRejected       PaymentStatus = "Rejected"
+	PaymentPendingRedeem  PaymentStatus = "Pending-redeem"
+	PaymentRedeemed       PaymentStatus = "Accept_redeem"
+	PaymentRedeemRejected PaymentStatus = "Redeem_rejected"
+)
+
+// paymentTransitions lists the statuses each payment status may move to;
+// statuses without an entry are terminal.
+var paymentTransitions = map[PaymentStatus][]PaymentStatus{
+	PaymentRequested:     {PaymentAccepted, PaymentRejected},
+	PaymentAccepted:      {PaymentPendingRedeem},
+	PaymentPendingRedeem: {PaymentRedeemed, PaymentRedeemRejected},
+}
+
+// committed reports whether a payment in this status still draws on the grant budget.
+func (ps PaymentStatus) committed() bool {
+	switch ps {
+	case PaymentRequested, PaymentAccepted, PaymentPendingRedeem, PaymentRedeemed:
+		return true
+	}
+	return false
+}
+
+// parsePaymentStatus validates a status name supplied by a client.
+func parsePaymentStatus(status string) (PaymentStatus, error) {
+	switch PaymentStatus(status) {
+	case PaymentRequested, PaymentAccepted, PaymentRejected, PaymentPendingRedeem, PaymentRedeemed, PaymentRedeemRejected:
+		return PaymentStatus(status), nil
+	}
+	return "", errValidation("unknown payment status %q", status).with("status", status)
+}
+
+// parsePaymentStatuses validates a list of status names used as a query filter.
+func parsePaymentStatuses(statuses []string) ([]PaymentStatus, error) {
+	var parsed []PaymentStatus
+	for _, status := range statuses {
+		paymentStatus, err := parsePaymentStatus(status)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, paymentStatus)
+	}
+	return parsed, nil
+}
+
+// RejectionCode classifies why a reimbursement or redemption was rejected.
+type RejectionCode string
+
+const (
+	RejectionIneligibleCost       RejectionCode = "INELIGIBLE_COST"
+	RejectionMissingDocumentation RejectionCode = "MISSING_DOCUMENTATION"
+	RejectionOverBudget           RejectionCode = "OVER_BUDGET"
+	RejectionDuplicate            RejectionCode = "DUPLICATE"
+	RejectionOutOfPeriod          RejectionCode = "OUT_OF_PERIOD"
+	RejectionOther                RejectionCode = "OTHER"
+	// RejectionLegacy marks rejections recorded as free text in Payment.Status.
+	RejectionLegacy RejectionCode = "LEGACY"
+)
+
+// parseRejectionCode validates a rejection code supplied by a client.
+func parseRejectionCode(code string) (RejectionCode, error) {
+	switch RejectionCode(code) {
+	case RejectionIneligibleCost, RejectionMissingDocumentation, RejectionOverBudget, RejectionDuplicate, RejectionOutOfPeriod, RejectionOther:
+		return RejectionCode(code), nil
+	}
+	return "", errValidation("unknown rejection code %q", code).with("code", code)
+}
+
+// Rejection records why, when and by whom a payment was rejected.
+type Rejection struct {
+	Reason string        `json:"reason"`
+	Code   RejectionCode `json:"code"`
+	By     string        `json:"by"`
+	At     string        `json:"at"`
+}
+
+// checkPaymentTransition fails unless the status table allows the payment to move to status.
+func checkPaymentTransition(payment *Payment, status PaymentStatus) error {
+	var names []string
+	allowed := []PaymentStatus{}
+	for _, next := range paymentTransitions[payment.Status] {
+		if next == status {
+			return nil
+		}
+		names = append(names, string(next))
+		allowed = append(allowed, next)
+	}
+	if len(names) == 0 {
+		names = append(names, "none")
+	}
+	return errInvalidState("Payment %s cannot move from %s to %s, allowed transitions: %s", payment.ID, payment.Status, status, strings.Join(names, ", ")).
+		with("payment_id", payment.ID).
+		with("status", payment.Status).
+		with("allowed", allowed)
+}
+
+// transitionPayment moves the payment to status if its status table allows it
+// and records when it happened.
+func transitionPayment(ctx contractapi.TransactionContextInterface, payment *Payment, status PaymentStatus) error {
+	err := checkPaymentTransition(payment, status)
+	if err != nil {
+		return err
+	}
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	payment.Status = status
+	payment.Status_Date = now
+	return nil
+}
+
+// rejectPayment moves the payment to a rejected status and records the rejection.
+func rejectPayment(ctx contractapi.TransactionContextInterface, payment *Payment, status PaymentStatus, code string, reason string, by string) error {
+	rejectionCode, err := parseRejectionCode(code)
+	if err != nil {
+		return err
+	}
+
+	err = transitionPayment(ctx, payment, status)
+	if err != nil {
+		return err
+	}
+
+	payment.Rejection = &Rejection{
+		Reason: reason,
+		Code:   rejectionCode,
+		By:     by,
+		At:     payment.Status_Date,
+	}
+	return nil
+}
+
+// normalizePaymentStatus turns the free-text statuses older rejections wrote
+// into Payment.Status into a rejected status with a legacy rejection record.
+// Older versions wrote the caller's message for both kinds of rejection;
+// messages naming a rejected redeem, e.g. "Redeem rejected" or
+// "reject_redeem", become Redeem_rejected and the rest Rejected. An empty
+// status, or one that isn't a status on a record written since rejections
+// had their own record, is an error.
+func normalizePaymentStatus(payment *Payment) error {
+	status := string(payment.Status)
+	if status == "" {
+		return errInternal("Payment %s has no status", payment.ID).with("payment_id", payment.ID)
+	}
+	if _, err := parsePaymentStatus(status); err == nil {
+		return nil
+	}
+	if payment.Rejection != nil || len(payment.Approvals) != 0 {
+		return errInternal("Payment %s has the unknown status %q", payment.ID, status).with("payment_id", payment.ID).with("status", status)
+	}
+
+	payment.Rejection = &Rejection{
+		Reason: status,
+		Code:   RejectionLegacy,
+		At:     payment.Status_Date,
+	}
+	payment.Status = PaymentRejected
+	if isLegacyRedeemRejection(status) {
+		payment.Status = PaymentRedeemRejected
+	}
+	return nil
+}
+
+// isLegacyRedeemRejection reports whether a free-text status written by an
+// older version rejected a redeem rather than a reimbursement.
+func isLegacyRedeemRejection(status string) bool {
+	status = strings.ToLower(status)
+	return strings.Contains(status, "redeem") && strings.Contains(status, "reject")
+}
@@ -0,0 +1,83 @@
+package chaincode
+
+import "testing"
+
+func TestCommittedPaymentStatuses(t *testing.T) {
+	tests := map[PaymentStatus]bool{
+		PaymentRequested:      true,
+		PaymentAccepted:       true,
+		PaymentPendingRedeem:  true,
+		PaymentRedeemed:       true,
+		PaymentRejected:       false,
+		PaymentRedeemRejected: false,
+	}
+	for status, want := range tests {
+		if got := status.committed(); got != want {
+			t.Errorf("%s committed = %v, want %v", status, got, want)
+		}
+	}
+}
+
+func TestParsePaymentStatuses(t *testing.T) {
+	statuses, err := parsePaymentStatuses([]string{"Requested", "Accept_redeem"})
+	if err != nil || len(statuses) != 2 || statuses[1] != PaymentRedeemed {
+		t.Fatalf("got %v, %v", statuses, err)
+	}
+	if _, err := parsePaymentStatuses([]string{"Requested", "requested"}); err == nil {
+		t.Fatalf("a status in the wrong case was accepted")
+	}
+}
+
+func TestParseRejectionCode(t *testing.T) {
+	if code, err := parseRejectionCode("OVER_BUDGET"); err != nil || code != RejectionOverBudget {
+		t.Fatalf("got %s, %v", code, err)
+	}
+	for _, code := range []string{"", "LEGACY", "over_budget"} {
+		if _, err := parseRejectionCode(code); err == nil {
+			t.Errorf("rejection code %q was accepted", code)
+		}
+	}
+}
+
+func TestNormalizePaymentStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		payment Payment
+		want    PaymentStatus
+		reason  string
+		code    ErrorCode
+	}{
+		{name: "valid status", payment: Payment{Status: PaymentAccepted}, want: PaymentAccepted},
+		{name: "legacy rejection", payment: Payment{Status: "receipts missing"}, want: PaymentRejected, reason: "receipts missing"},
+		{name: "legacy redeem rejection", payment: Payment{Status: "Redeem rejected: no invoice"}, want: PaymentRedeemRejected, reason: "Redeem rejected: no invoice"},
+		{name: "legacy redeem rejection code", payment: Payment{Status: "reject_redeem"}, want: PaymentRedeemRejected, reason: "reject_redeem"},
+		{name: "empty status", payment: Payment{}, code: CodeInternal},
+		{name: "unknown status with a rejection record", payment: Payment{Status: "Cancelled", Rejection: &Rejection{Code: RejectionOther}}, code: CodeInternal},
+		{name: "unknown status with approvals", payment: Payment{Status: "Approved", Approvals: []Approval{{By: "GrantorMSP/alice"}}}, code: CodeInternal},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			payment := test.payment
+			payment.ID = "p1"
+			payment.Status_Date = "2022-03-01T10:00:00Z"
+			err := normalizePaymentStatus(&payment)
+			if test.code != "" {
+				wantCode(t, err, test.code)
+				return
+			}
+			noError(t, err)
+			if payment.Status != test.want {
+				t.Fatalf("got status %s, want %s", payment.Status, test.want)
+			}
+			if test.reason == "" {
+				if payment.Rejection != nil {
+					t.Fatalf("a valid status got a rejection: %+v", payment.Rejection)
+				}
+				return
+			}
+			if payment.Rejection == nil || payment.Rejection.Code != RejectionLegacy || payment.Rejection.Reason != test.reason || payment.Rejection.At != "2022-03-01T10:00:00Z" {
+				t.Fatalf("got rejection %+v", payment.Rejection)
+			}
+		})
+	}
+}
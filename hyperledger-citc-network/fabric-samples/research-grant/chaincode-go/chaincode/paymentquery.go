@@ -0,0 +1,174 @@
+package chaincode
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// PaymentFilter selects the payments QueryPayments returns. Every field is
+// optional and an empty field doesn't filter; the fields that are set must all
+// match. From and To bound the payment date as RFC 3339 timestamps, From
+// inclusive and To exclusive.
+type PaymentFilter struct {
+	Statuses   []string `json:"statuses"`
+	Awardee_ID string   `json:"awardee_id"`
+	From       string   `json:"from"`
+	To         string   `json:"to"`
+	Grant_IDs  []string `json:"grant_ids"`
+}
+
+// parsePaymentFilter decodes a PaymentFilter, rejecting unknown fields so a
+// misspelt filter fails instead of matching everything.
+func parsePaymentFilter(filterJSON string) (PaymentFilter, error) {
+	var filter PaymentFilter
+	decoder := json.NewDecoder(bytes.NewReader([]byte(filterJSON)))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&filter)
+	if err != nil {
+		return PaymentFilter{}, errValidation("filter must be a JSON PaymentFilter object: %v", err)
+	}
+	return filter, nil
+}
+
+// paymentMatcher is a parsed PaymentFilter.
+type paymentMatcher struct {
+	statuses  []PaymentStatus
+	awardeeID string
+	from      time.Time
+	to        time.Time
+	grantIDs  map[string]bool
+}
+
+func newPaymentMatcher(filter PaymentFilter) (*paymentMatcher, error) {
+	statuses, err := parsePaymentStatuses(filter.Statuses)
+	if err != nil {
+		return nil, err
+	}
+
+	matcher := paymentMatcher{
+		statuses:  statuses,
+		awardeeID: filter.Awardee_ID,
+	}
+	if filter.From != "" {
+		matcher.from, err = time.Parse(time.RFC3339, filter.From)
+		if err != nil {
+			return nil, errValidation("from must be an RFC 3339 timestamp: %v", err)
+		}
+	}
+	if filter.To != "" {
+		matcher.to, err = time.Parse(time.RFC3339, filter.To)
+		if err != nil {
+			return nil, errValidation("to must be an RFC 3339 timestamp: %v", err)
+		}
+	}
+	if len(filter.Grant_IDs) != 0 {
+		matcher.grantIDs = map[string]bool{}
+		for _, id := range filter.Grant_IDs {
+			matcher.grantIDs[id] = true
+		}
+	}
+	return &matcher, nil
+}
+
+func (m *paymentMatcher) matchGrant(grantID string) bool {
+	return m.grantIDs == nil || m.grantIDs[grantID]
+}
+
+func (m *paymentMatcher) match(payment *Payment) bool {
+	if len(m.statuses) != 0 && !hasPaymentStatus(payment, m.statuses) {
+		return false
+	}
+	if m.awardeeID != "" && payment.Awardee_ID != m.awardeeID {
+		return false
+	}
+	if m.from.IsZero() && m.to.IsZero() {
+		return true
+	}
+
+	// Dates that don't parse can't be placed in the range
+	date, err := time.Parse(time.RFC3339, payment.Date)
+	if err != nil {
+		return false
+	}
+	if !m.from.IsZero() && date.Before(m.from) {
+		return false
+	}
+	if !m.to.IsZero() && !date.Before(m.to) {
+		return false
+	}
+	return true
+}
+
+// QueryPayments returns the payments matching filterJSON, a JSON
+// PaymentFilter, in the grants the user may read, grouped by grant in grant ID
+// order. Payments of grants the user reads a summary of are redacted as in
+// GetPayments. Grants without matching payments are left out, so no match is
+// an empty list.
+func (s *SmartContract) QueryPayments(ctx contractapi.TransactionContextInterface, filterJSON string) ([]GrantPayments, error) {
+	caller, err := authorize(ctx, "QueryPayments")
+	if err != nil {
+		return nil, err
+	}
+
+	filter, err := parsePaymentFilter(filterJSON)
+	if err != nil {
+		return nil, err
+	}
+	matcher, err := newPaymentMatcher(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey("grant", []string{})
+	if err != nil {
+		return nil, errInternal("failed to query the world state: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	responses := []GrantPayments{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, errInternal("failed to read from world state: %v", err)
+		}
+
+		var grant Grant
+		err = json.Unmarshal(queryResponse.Value, &grant)
+		if err != nil {
+			return nil, errInternal("failed to unmarshal JSON: %v", err)
+		}
+		if !matcher.matchGrant(grant.ID) {
+			continue
+		}
+		access := grantAccessOf(caller, &grant)
+		if access == accessNone {
+			continue
+		}
+		if filter.Awardee_ID != "" && !checkAwardee(grant.Awardee, filter.Awardee_ID) && !checkSubAwardee(grant.Awardee, filter.Awardee_ID) {
+			continue
+		}
+
+		grantPayments, err := getGrantPayments(ctx, grant.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		payments := []Payment{}
+		for _, payment := range grantPayments {
+			if matcher.match(&payment) {
+				payments = append(payments, payment)
+			}
+		}
+		if len(payments) != 0 {
+			responses = append(responses, GrantPayments{
+				Grant_ID: grant.ID,
+				Payment:  viewPayments(payments, access),
+			})
+		}
+	}
+
+	return responses, nil
+}
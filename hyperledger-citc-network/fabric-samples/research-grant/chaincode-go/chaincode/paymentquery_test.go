@@ -0,0 +1,125 @@
+package chaincode
+
+import (
+	"reflect"
+	"testing"
+
+	"chaincode-go/chaincodetest"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+func (l *ledger) queryPayments(identity *chaincodetest.Identity, filter string) ([]GrantPayments, error) {
+	l.t.Helper()
+	var payments []GrantPayments
+	err := l.run(identity, nil, func(ctx contractapi.TransactionContextInterface) error {
+		var err error
+		payments, err = l.contract.QueryPayments(ctx, filter)
+		return err
+	})
+	return payments, err
+}
+
+// paymentIDs flattens a QueryPayments result into grant/payment IDs.
+func paymentIDs(grantPayments []GrantPayments) []string {
+	var ids []string
+	for _, grant := range grantPayments {
+		for _, payment := range grant.Payment {
+			ids = append(ids, grant.Grant_ID+"/"+payment.ID)
+		}
+	}
+	return ids
+}
+
+func TestQueryPayments(t *testing.T) {
+	l := newLedger(t)
+	l.activeGrant("G1")
+	l.addSubawardee("G1")
+	l.activeGrant("G2")
+	l.must(l.initiate(newGrant("G3")))
+	_, err := l.reimburse(awardee, "G1", "P1", "AwardeeMSP/bob", item("Personnel", "100"))
+	l.must(err)
+	_, err = l.reimburse(subawardee, "G1", "P2", "SubawardeeMSP/carol", item("Equipment", "10"))
+	l.must(err)
+	_, err = l.reimburse(awardee, "G2", "P1", "AwardeeMSP/bob", item("Personnel", "100"))
+	l.must(err)
+	l.must(l.run(grantor, nil, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.AcceptReimbursement(ctx, "G2", "P1")
+		return err
+	}))
+
+	// The fake clock moves on with every transaction, so P2 of G1 was
+	// requested after P1 and before P1 of G2
+	all, err := l.queryPayments(grantor, `{"grant_ids":["G1"]}`)
+	l.must(err)
+	second := all[0].Payment[1].Date
+
+	tests := []struct {
+		name     string
+		identity *chaincodetest.Identity
+		filter   string
+		want     []string
+	}{
+		{"everything", grantor, `{}`, []string{"G1/P1", "G1/P2", "G2/P1"}},
+		{"by status", grantor, `{"statuses":["Requested"]}`, []string{"G1/P1", "G1/P2"}},
+		{"by several statuses", grantor, `{"statuses":["Accepted","Rejected"]}`, []string{"G2/P1"}},
+		{"by awardee", grantor, `{"awardee_id":"SubawardeeMSP/carol"}`, []string{"G1/P2"}},
+		{"by grant", grantor, `{"grant_ids":["G2","G3"]}`, []string{"G2/P1"}},
+		{"from the second payment", grantor, `{"from":"` + second + `"}`, []string{"G1/P2", "G2/P1"}},
+		{"before the second payment", grantor, `{"to":"` + second + `"}`, []string{"G1/P1"}},
+		{"no match", grantor, `{"statuses":["Rejected"]}`, nil},
+		{"subawardee", subawardee, `{}`, []string{"G1/P1", "G1/P2"}},
+		{"other grantor", chaincodetest.NewIdentity(GrantorMSP, "eve"), `{}`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payments, err := l.queryPayments(tt.identity, tt.filter)
+			noError(t, err)
+			if payments == nil {
+				t.Fatalf("got a nil result, want a list")
+			}
+			if got := paymentIDs(payments); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryPaymentsRejectsBadFilters(t *testing.T) {
+	l := newLedger(t)
+	for _, filter := range []string{`{"status":["Requested"]}`, `{"statuses":["requested"]}`, `{"from":"2022-01-01"}`, `[]`} {
+		_, err := l.queryPayments(grantor, filter)
+		if err == nil {
+			t.Errorf("filter %s was accepted", filter)
+		}
+	}
+}
+
+func TestQueryPaymentsFollowsGrantAccess(t *testing.T) {
+	l := newLedger(t)
+	l.activeGrant("G1")
+	_, err := l.reimburse(awardee, "G1", "P1", "AwardeeMSP/bob", item("Personnel", "100"))
+	l.must(err)
+	l.must(l.run(grantor, nil, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.RejectReimbursement(ctx, "G1", "P1", string(RejectionMissingDocumentation), "no receipts for the March invoice")
+		return err
+	}))
+
+	for _, identity := range []*chaincodetest.Identity{grantor, awardee} {
+		payments, err := l.queryPayments(identity, `{"statuses":["Rejected"]}`)
+		noError(t, err)
+		if len(payments) != 1 || payments[0].Payment[0].Redacted || payments[0].Payment[0].Rejection.Reason == "" {
+			t.Fatalf("%s got %+v, want the full payment", identity.MSPID, payments)
+		}
+	}
+
+	payments, err := l.queryPayments(auditor, `{"statuses":["Rejected"]}`)
+	noError(t, err)
+	if len(payments) != 1 || len(payments[0].Payment) != 1 {
+		t.Fatalf("auditor got %+v, want the payment", payments)
+	}
+	payment := payments[0].Payment[0]
+	if !payment.Redacted || payment.Rejection.Reason != "" || payment.Rejection.Code != RejectionMissingDocumentation {
+		t.Fatalf("auditor got %+v, want the payment redacted", payment)
+	}
+}
@@ -0,0 +1,157 @@
+package chaincode
+
+import (
+	"time"
+)
+
+// A grant's period of performance runs from Start_Date to End_Date, or to the
+// end date of its last approved extension; dates given as YYYY-MM-DD cover
+// the whole day. Reimbursements and progress reports
+// are only accepted within it. For Closeout_Days after End_Date the grant is
+// in closeout: costs incurred before End_Date can still be reimbursed and
+// final progress reported, but nothing incurred after End_Date.
+//
+// Grants created before dates were enforced may lack either date; the
+// missing bound is then not checked.
+
+// grantPeriod is the parsed period of performance of a grant. Unset bounds
+// are zero; end and closeout are exclusive.
+type grantPeriod struct {
+	start    time.Time
+	end      time.Time
+	closeout time.Time
+}
+
+// periodEnd parses an end date as an exclusive bound: a calendar date ends
+// at the start of the next day.
+func periodEnd(value string) (time.Time, error) {
+	if t, err := time.Parse(dateLayout, value); err == nil {
+		return t.AddDate(0, 0, 1), nil
+	}
+	return parseDate(value)
+}
+
+// grantEndDate returns the date the grant's period of performance ends: the
+// end date of its last approved extension, or else its End_Date.
+func grantEndDate(grant *Grant) string {
+	for i := len(grant.Amendments) - 1; i >= 0; i-- {
+		amendment := &grant.Amendments[i]
+		if amendment.Type == AmendmentExtension && amendment.Status == AmendmentApproved {
+			return amendment.End_Date
+		}
+	}
+	return grant.End_Date
+}
+
+// periodOf parses the period of performance of the grant.
+func periodOf(grant *Grant) (*grantPeriod, error) {
+	var period grantPeriod
+	if grant.Start_Date != "" {
+		start, err := parseDate(grant.Start_Date)
+		if err != nil {
+			return nil, errInternal("Grant %s has an invalid start date: %v", grant.ID, err).with("grant_id", grant.ID)
+		}
+		period.start = start
+	}
+	if endDate := grantEndDate(grant); endDate != "" {
+		end, err := periodEnd(endDate)
+		if err != nil {
+			return nil, errInternal("Grant %s has an invalid end date: %v", grant.ID, err).with("grant_id", grant.ID)
+		}
+		period.end = end
+		period.closeout = end.AddDate(0, 0, grant.Closeout_Days)
+	}
+	return &period, nil
+}
+
+// outOfPeriod is the error for activity outside the grant's period of performance.
+func outOfPeriod(grant *Grant, format string, args ...interface{}) *ContractError {
+	err := newContractError(CodeOutOfPeriod, format, args...).
+		with("grant_id", grant.ID).
+		with("start_date", grant.Start_Date).
+		with("end_date", grantEndDate(grant))
+	if grant.Closeout_Days != 0 {
+		err.with("closeout_days", grant.Closeout_Days)
+	}
+	return err
+}
+
+// checkProgressPeriod fails unless progress can be reported at now: from the
+// start of the grant until its closeout ends.
+func checkProgressPeriod(grant *Grant, now time.Time) error {
+	period, err := periodOf(grant)
+	if err != nil {
+		return err
+	}
+	if !period.start.IsZero() && now.Before(period.start) {
+		return outOfPeriod(grant, "Grant %s starts on %s, progress can't be reported before", grant.ID, grant.Start_Date)
+	}
+	if !period.closeout.IsZero() && !now.Before(period.closeout) {
+		return outOfPeriod(grant, "Grant %s ended on %s and its closeout is over", grant.ID, grantEndDate(grant))
+	}
+	return nil
+}
+
+// checkReimbursementPeriod fails unless costs incurred at incurred can be
+// reimbursed at now. Costs must fall within the period of performance, and
+// be requested before the closeout ends.
+func checkReimbursementPeriod(grant *Grant, now time.Time, incurred time.Time) error {
+	period, err := periodOf(grant)
+	if err != nil {
+		return err
+	}
+	if incurred.After(now) {
+		return errValidation("costs can't be incurred after the request, got %s", incurred.Format(time.RFC3339)).with("date", incurred.Format(time.RFC3339))
+	}
+	if !period.start.IsZero() && incurred.Before(period.start) {
+		return outOfPeriod(grant, "Grant %s starts on %s, costs incurred before can't be reimbursed", grant.ID, grant.Start_Date).
+			with("date", incurred.Format(time.RFC3339))
+	}
+	if period.end.IsZero() {
+		return nil
+	}
+	if !now.Before(period.closeout) {
+		return outOfPeriod(grant, "Grant %s ended on %s and its closeout is over", grant.ID, grantEndDate(grant))
+	}
+	if !incurred.Before(period.end) {
+		return outOfPeriod(grant, "Grant %s ended on %s, only costs incurred before can be reimbursed", grant.ID, grantEndDate(grant)).
+			with("date", incurred.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// updatePeriod takes over the period of performance given in an UpdateGrant
+// input. It can only change while the grant is a Draft.
+func updatePeriod(grant *Grant, input *Grant) error {
+	start, end, closeoutDays := grant.Start_Date, grant.End_Date, grant.Closeout_Days
+	if input.Start_Date != "" {
+		start = input.Start_Date
+	}
+	if input.End_Date != "" {
+		end = input.End_Date
+	}
+	if input.Closeout_Days != 0 {
+		closeoutDays = input.Closeout_Days
+	}
+	if start == grant.Start_Date && end == grant.End_Date && closeoutDays == grant.Closeout_Days {
+		return nil
+	}
+
+	if grant.Status != GrantDraft {
+		return errInvalidState("the period of performance of Grant %s can only change while it is a Draft", grant.ID).
+			with("grant_id", grant.ID).
+			with("status", grant.Status).
+			with("allowed", []GrantStatus{GrantDraft})
+	}
+	v := validator{input: "update_grant"}
+	v.dateOrder("start_date", start, "end_date", end)
+	err := v.err()
+	if err != nil {
+		return err
+	}
+
+	grant.Start_Date = start
+	grant.End_Date = end
+	grant.Closeout_Days = closeoutDays
+	return nil
+}
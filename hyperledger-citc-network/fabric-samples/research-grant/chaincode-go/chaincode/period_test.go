@@ -0,0 +1,139 @@
+package chaincode
+
+import (
+	"testing"
+	"time"
+
+	"chaincode-go/chaincodetest"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// at sets the ledger clock so the next transaction runs at the time.
+func (l *ledger) at(value string) {
+	l.t.Helper()
+	t, err := time.Parse(time.RFC3339, value)
+	l.must(err)
+	l.stub.Clock = t.Add(-time.Second)
+}
+
+// reimburseIncurred has bob request a Personnel reimbursement for costs
+// incurred on date.
+func (l *ledger) reimburseIncurred(grantID string, paymentID string, date string) error {
+	l.t.Helper()
+	input := map[string]interface{}{
+		"ID":         paymentID,
+		"grant_id":   grantID,
+		"awardee_id": "AwardeeMSP/bob",
+		"date":       date,
+		"item":       []map[string]interface{}{item("Personnel", "10")},
+	}
+	return l.run(awardee, map[string]interface{}{"request_reimbursement": input}, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.RequestReimbursement(ctx)
+		return err
+	})
+}
+
+func (l *ledger) addProgress(identity *chaincodetest.Identity, grantID string) error {
+	l.t.Helper()
+	input := map[string]interface{}{"grant_id": grantID, "progress": map[string]interface{}{"notes": "first samples analysed", "percentage": "10%"}}
+	return l.run(identity, map[string]interface{}{"add_progress": input}, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.AddProgress(ctx)
+		return err
+	})
+}
+
+// acceptGrant has bob accept the grant assigned to him.
+func (l *ledger) acceptGrant(id string) {
+	l.t.Helper()
+	l.must(l.run(awardee, nil, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.AcceptGrant(ctx, id)
+		return err
+	}))
+}
+
+func TestReimbursementPeriod(t *testing.T) {
+	tests := []struct {
+		name     string
+		now      string
+		incurred string
+		code     ErrorCode
+	}{
+		{name: "within the period", now: "2022-06-01T12:00:00Z", incurred: "2022-05-31"},
+		{name: "on the last day", now: "2022-12-31T23:00:00Z", incurred: "2022-12-31"},
+		{name: "before the start", now: "2022-06-01T12:00:00Z", incurred: "2021-12-31", code: CodeOutOfPeriod},
+		{name: "after the request", now: "2022-06-01T12:00:00Z", incurred: "2022-06-02", code: CodeValidation},
+		{name: "in closeout for earlier costs", now: "2023-01-15T12:00:00Z", incurred: "2022-12-20"},
+		{name: "in closeout for later costs", now: "2023-01-15T12:00:00Z", incurred: "2023-01-02", code: CodeOutOfPeriod},
+		{name: "after closeout", now: "2023-01-31T00:00:00Z", incurred: "2022-12-20", code: CodeOutOfPeriod},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			l := newLedger(t)
+			grant := newGrant("G1")
+			grant["closeout_days"] = 30
+			l.must(l.initiate(grant))
+			l.must(l.assign("G1", testAwardee("bob", "Main", AwardeeMSP)))
+			l.acceptGrant("G1")
+
+			l.at(test.now)
+			err := l.reimburseIncurred("G1", "P1", test.incurred)
+			if test.code == "" {
+				noError(t, err)
+				return
+			}
+			wantCode(t, err, test.code)
+		})
+	}
+}
+
+func TestUndatedCostsAreIncurredOnTheRequest(t *testing.T) {
+	l := newLedger(t)
+	l.activeGrant("G1")
+	l.at("2022-04-01T09:30:00Z")
+	_, err := l.reimburse(awardee, "G1", "P1", "AwardeeMSP/bob", item("Personnel", "10"))
+	noError(t, err)
+
+	if incurred := l.payment("G1", "P1").Incurred_Date; incurred != "2022-04-01T09:30:00Z" {
+		t.Fatalf("got incurred date %s", incurred)
+	}
+}
+
+func TestProgressPeriod(t *testing.T) {
+	l := newLedger(t)
+	grant := newGrant("G1")
+	grant["start_date"] = "2022-03-01"
+	l.must(l.initiate(grant))
+	l.must(l.assign("G1", testAwardee("bob", "Main", AwardeeMSP)))
+	l.acceptGrant("G1")
+
+	wantCode(t, l.addProgress(awardee, "G1"), CodeOutOfPeriod)
+	l.at("2022-03-01T00:00:00Z")
+	l.must(l.addProgress(awardee, "G1"))
+	l.at("2023-01-01T00:00:00Z")
+	wantCode(t, l.addProgress(awardee, "G1"), CodeOutOfPeriod)
+}
+
+func TestPeriodChangesOnlyWhileDraft(t *testing.T) {
+	update := func(l *ledger) error {
+		input := newGrant("G1")
+		input["end_date"] = "2023-06-30"
+		return l.run(grantor, map[string]interface{}{"update_grant": input}, func(ctx contractapi.TransactionContextInterface) error {
+			_, err := l.contract.UpdateGrant(ctx)
+			return err
+		})
+	}
+
+	l := newLedger(t)
+	l.must(l.initiate(newGrant("G1")))
+	l.must(update(l))
+	grant, err := l.read(grantor, "G1")
+	noError(t, err)
+	if grant.End_Date != "2023-06-30" {
+		t.Fatalf("got end date %s", grant.End_Date)
+	}
+
+	l = newLedger(t)
+	l.activeGrant("G1")
+	wantCode(t, update(l), CodeInvalidState)
+}
@@ -0,0 +1,333 @@
+package chaincode
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Awardee bank account and contact details are kept in a private data
+// collection shared by the grantor org and the awardee's org. The public
+// grant only carries a salted hash of them (Awardee.Private_Hash). The
+// collections are defined in collections_config.json, whose policies name
+// the default MSP IDs rather than the OrgConfig in effect.
+const (
+	AwardeeCollection    = "grantorAwardeeCollection"
+	SubawardeeCollection = "grantorSubawardeeCollection"
+)
+
+const awardeePrivateObjectType = "awardee"
+
+// minSaltLength is the shortest salt accepted in the "salt" transient field.
+const minSaltLength = 16
+
+// AwardeePrivateDetails is the private record of an awardee on a grant.
+type AwardeePrivateDetails struct {
+	Grant_ID       string `json:"grant_id"`
+	Awardee_ID     string `json:"awardee_id"`
+	Account_Number string `json:"account_number"`
+	Contact        string `json:"contact"`
+	Salt           string `json:"salt"`
+}
+
+// awardeeCollection returns the collection holding the awardee's private details.
+func awardeeCollection(awardee *Awardee) string {
+	if awardee.Awardee_Type == "Sub" {
+		return SubawardeeCollection
+	}
+	return AwardeeCollection
+}
+
+// collectionMembers returns the orgs that hold a copy of the collection. It
+// reads them from the OrgConfig, which has to agree with the collection
+// policies in collections_config.json.
+func collectionMembers(config *OrgConfig, collection string) []string {
+	switch collection {
+	case AwardeeCollection:
+		return []string{config.Grantor_MSP, config.Awardee_MSP}
+	case SubawardeeCollection:
+		return []string{config.Grantor_MSP, config.Subawardee_MSP}
+	}
+	return nil
+}
+
+// transientSalt reads the salt the client generated for hashing private details.
+// It has to come from the client: every endorser must compute the same hash,
+// so the chaincode cannot draw one itself.
+func transientSalt(transientMap map[string][]byte) ([]byte, error) {
+	salt, ok := transientMap["salt"]
+	if !ok {
+		return nil, errValidation("salt not found in the transient map input").with("input", "salt")
+	}
+	if len(salt) < minSaltLength {
+		return nil, errValidation("salt must be at least %d bytes long", minSaltLength).with("input", "salt").with("min_length", minSaltLength)
+	}
+	return salt, nil
+}
+
+// hashPrivateDetails returns the hex SHA-256 of the salt, account number and contact.
+func hashPrivateDetails(details *AwardeePrivateDetails) (string, error) {
+	salt, err := hex.DecodeString(details.Salt)
+	if err != nil {
+		return "", errInternal("invalid salt: %v", err)
+	}
+
+	hash := sha256.New()
+	hash.Write(salt)
+	hash.Write([]byte{0})
+	hash.Write([]byte(details.Account_Number))
+	hash.Write([]byte{0})
+	hash.Write([]byte(details.Contact))
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// putAwardeePrivateDetails moves the awardee's account number and contact into
+// the awardee's collection and leaves only their salted hash on the awardee.
+func putAwardeePrivateDetails(ctx contractapi.TransactionContextInterface, grantID string, awardee *Awardee, salt []byte) error {
+	details := AwardeePrivateDetails{
+		Grant_ID:       grantID,
+		Awardee_ID:     awardee.ID,
+		Account_Number: awardee.Account_Number,
+		Contact:        awardee.Contact,
+		Salt:           hex.EncodeToString(salt),
+	}
+
+	hash, err := hashPrivateDetails(&details)
+	if err != nil {
+		return err
+	}
+
+	detailsJSON, err := json.Marshal(details)
+	if err != nil {
+		return errInternal("failed to marshal private details into JSON: %v", err)
+	}
+
+	detailsKey, err := ctx.GetStub().CreateCompositeKey(awardeePrivateObjectType, []string{grantID, awardee.ID})
+	if err != nil {
+		return errInternal("failed to create private details key: %v", err)
+	}
+	err = ctx.GetStub().PutPrivateData(awardeeCollection(awardee), detailsKey, detailsJSON)
+	if err != nil {
+		return errInternal("failed to put private details of awardee %s: %v", awardee.ID, err)
+	}
+
+	awardee.Account_Number = ""
+	awardee.Contact = ""
+	awardee.Private_Hash = hash
+	return nil
+}
+
+// moveAwardeePrivateDetails moves the private details of an awardee recorded
+// under oldID to the awardee's current ID. The awardee has a Private_Hash, so
+// the details exist; if this peer doesn't hold them the move fails rather than
+// leave them under a key nothing reads, and the client has to send the
+// transaction to a peer of the collection.
+func moveAwardeePrivateDetails(ctx contractapi.TransactionContextInterface, grantID string, oldID string, awardee *Awardee) error {
+	collection := awardeeCollection(awardee)
+	oldKey, err := ctx.GetStub().CreateCompositeKey(awardeePrivateObjectType, []string{grantID, oldID})
+	if err != nil {
+		return errInternal("failed to create private details key: %v", err)
+	}
+	detailsJSON, err := ctx.GetStub().GetPrivateData(collection, oldKey)
+	if err != nil {
+		return errInternal("failed to read private details: %v", err)
+	}
+	if detailsJSON == nil {
+		return errNotFound("private details of awardee %s are not available on this peer", oldID).with("grant_id", grantID).with("awardee_id", oldID).with("collection", collection)
+	}
+
+	var details AwardeePrivateDetails
+	err = json.Unmarshal(detailsJSON, &details)
+	if err != nil {
+		return errInternal("failed to unmarshal JSON: %v", err)
+	}
+	details.Awardee_ID = awardee.ID
+	detailsJSON, err = json.Marshal(details)
+	if err != nil {
+		return errInternal("failed to marshal private details into JSON: %v", err)
+	}
+
+	detailsKey, err := ctx.GetStub().CreateCompositeKey(awardeePrivateObjectType, []string{grantID, awardee.ID})
+	if err != nil {
+		return errInternal("failed to create private details key: %v", err)
+	}
+	err = ctx.GetStub().PutPrivateData(collection, detailsKey, detailsJSON)
+	if err != nil {
+		return errInternal("failed to put private details of awardee %s: %v", awardee.ID, err)
+	}
+	err = ctx.GetStub().DelPrivateData(collection, oldKey)
+	if err != nil {
+		return errInternal("failed to delete private details of awardee %s: %v", oldID, err)
+	}
+	return nil
+}
+
+// MigratePrivateDetails moves the account numbers and contacts that grants
+// stored before the private data collections off the public awardee records
+// and into the awardees' collections, hashed with the salt in the transient
+// map. It has to be endorsed by a peer holding the awardee collections. It
+// returns the number of grants rewritten - Grantor
+func (s *SmartContract) MigratePrivateDetails(ctx contractapi.TransactionContextInterface) (int, error) {
+	caller, err := authorize(ctx, "MigratePrivateDetails")
+	if err != nil {
+		return 0, err
+	}
+	transientMap, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return 0, errInternal("error getting transient: %v", err)
+	}
+	salt, err := transientSalt(transientMap)
+	if err != nil {
+		return 0, err
+	}
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey("grant", []string{})
+	if err != nil {
+		return 0, errInternal("failed to query the world state: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	var migrated int
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return 0, errInternal("failed to read from world state: %v", err)
+		}
+
+		var grant Grant
+		err = json.Unmarshal(queryResponse.Value, &grant)
+		if err != nil {
+			return 0, errInternal("failed to unmarshal JSON: %v", err)
+		}
+
+		var changed bool
+		for i := range grant.Awardee {
+			awardee := &grant.Awardee[i]
+			if awardee.Private_Hash != "" || (awardee.Account_Number == "" && awardee.Contact == "") {
+				continue
+			}
+			err = putAwardeePrivateDetails(ctx, grant.ID, awardee, salt)
+			if err != nil {
+				return 0, err
+			}
+			changed = true
+		}
+		if !changed {
+			continue
+		}
+		err = putGrant(ctx, &grant)
+		if err != nil {
+			return 0, err
+		}
+		migrated++
+	}
+
+	err = emitMigrationEvent(ctx, caller, EventPrivateDetailsMigrated, migrated)
+	if err != nil {
+		return 0, err
+	}
+
+	return migrated, nil
+}
+
+// deleteAwardeePrivateDetails removes the private details of every awardee on the grant.
+func deleteAwardeePrivateDetails(ctx contractapi.TransactionContextInterface, grant *Grant) error {
+	for i := range grant.Awardee {
+		if grant.Awardee[i].Private_Hash == "" {
+			continue
+		}
+		detailsKey, err := ctx.GetStub().CreateCompositeKey(awardeePrivateObjectType, []string{grant.ID, grant.Awardee[i].ID})
+		if err != nil {
+			return errInternal("failed to create private details key: %v", err)
+		}
+		err = ctx.GetStub().DelPrivateData(awardeeCollection(&grant.Awardee[i]), detailsKey)
+		if err != nil {
+			return errInternal("failed to delete private details of awardee %s: %v", grant.Awardee[i].ID, err)
+		}
+	}
+	return nil
+}
+
+// ReadAwardeePrivateDetails returns the account number and contact of an awardee.
+// Only the grant's grantor and the awardee themselves, each from their own
+// org, may read them, and only from an org that is a member of the awardee's
+// collection.
+func (s *SmartContract) ReadAwardeePrivateDetails(ctx contractapi.TransactionContextInterface, grant_id string, awardee_id string) (*AwardeePrivateDetails, error) {
+	caller, err := authorize(ctx, "ReadAwardeePrivateDetails")
+	if err != nil {
+		return nil, err
+	}
+
+	grant, err := readGrant(ctx, grant_id)
+	if err != nil {
+		return nil, err
+	}
+
+	awardee_id, err = grantAwardeeID(grant, awardee_id)
+	if err != nil {
+		return nil, err
+	}
+	var awardee *Awardee
+	for i := range grant.Awardee {
+		if grant.Awardee[i].ID == awardee_id {
+			awardee = &grant.Awardee[i]
+			break
+		}
+	}
+	if awardee == nil {
+		return nil, awardeeNotAssigned(awardee_id, grant)
+	}
+
+	config, err := getOrgConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	collection := awardeeCollection(awardee)
+	var member bool
+	for _, mspID := range collectionMembers(config, collection) {
+		if caller.MSPID == mspID {
+			member = true
+		}
+	}
+	if !member {
+		return nil, errForbidden("User from org %v is not a member of the collection %s", caller.MSPID, collection).with("collection", collection)
+	}
+	isGrantor := caller.MSPID == grantorMSPOf(grant, config) && caller.ID == grant.Grantor_ID
+	isAwardee := caller.MSPID == awardeeMSP(awardee, config) && caller.ID == awardee.ID
+	if !isGrantor && !isAwardee {
+		return nil, errForbidden("User %s is not allowed to read the private details of awardee %s", caller.ID, awardee.ID).with("awardee_id", awardee.ID)
+	}
+
+	if awardee.Private_Hash == "" {
+		return nil, errNotFound("Awardee %s in the Grant %s has no private details", awardee.ID, grant.ID).with("grant_id", grant.ID).with("awardee_id", awardee.ID)
+	}
+
+	detailsKey, err := ctx.GetStub().CreateCompositeKey(awardeePrivateObjectType, []string{grant.ID, awardee.ID})
+	if err != nil {
+		return nil, errInternal("failed to create private details key: %v", err)
+	}
+	detailsJSON, err := ctx.GetStub().GetPrivateData(collection, detailsKey)
+	if err != nil {
+		return nil, errInternal("failed to read private details: %v", err)
+	}
+	if detailsJSON == nil {
+		return nil, errNotFound("private details of awardee %s are not available on this peer", awardee.ID).with("grant_id", grant.ID).with("awardee_id", awardee.ID)
+	}
+
+	var details AwardeePrivateDetails
+	err = json.Unmarshal(detailsJSON, &details)
+	if err != nil {
+		return nil, errInternal("failed to unmarshal JSON: %v", err)
+	}
+
+	hash, err := hashPrivateDetails(&details)
+	if err != nil {
+		return nil, err
+	}
+	if hash != awardee.Private_Hash {
+		return nil, errInternal("private details of awardee %s do not match the hash on the Grant %s", awardee.ID, grant.ID).with("grant_id", grant.ID).with("awardee_id", awardee.ID)
+	}
+
+	return &details, nil
+}
@@ -0,0 +1,113 @@
+package chaincode
+
+import (
+	"testing"
+
+	"chaincode-go/chaincodetest"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+func (l *ledger) readPrivate(identity *chaincodetest.Identity, grantID string, awardeeID string) (*AwardeePrivateDetails, error) {
+	l.t.Helper()
+	var details *AwardeePrivateDetails
+	err := l.run(identity, nil, func(ctx contractapi.TransactionContextInterface) error {
+		var err error
+		details, err = l.contract.ReadAwardeePrivateDetails(ctx, grantID, awardeeID)
+		return err
+	})
+	return details, err
+}
+
+func TestPrivateDetailsStayOffTheGrant(t *testing.T) {
+	l := newLedger(t)
+	l.activeGrant("G1")
+
+	grant, err := l.read(grantor, "G1")
+	l.must(err)
+	if grant.Awardee[0].Account_Number != "" || grant.Awardee[0].Contact != "" || grant.Awardee[0].Private_Hash == "" {
+		t.Fatalf("got awardee %+v on the grant", grant.Awardee[0])
+	}
+}
+
+func TestReadAwardeePrivateDetails(t *testing.T) {
+	l := newLedger(t)
+	l.activeGrant("G1")
+	// A second grant whose awardee has the grantor's common name
+	l.must(l.initiate(newGrant("G2")))
+	l.must(l.assign("G2", testAwardee("alice", "Main", AwardeeMSP)))
+
+	tests := []struct {
+		name     string
+		identity *chaincodetest.Identity
+		code     ErrorCode
+	}{
+		{name: "grantor", identity: grantor},
+		{name: "awardee", identity: awardee},
+		{name: "awardee with the grantor's common name", identity: chaincodetest.NewIdentity(AwardeeMSP, "alice"), code: CodeForbidden},
+		{name: "another grantor user", identity: chaincodetest.NewIdentity(GrantorMSP, "bob"), code: CodeForbidden},
+		{name: "subawardee org", identity: subawardee, code: CodeForbidden},
+		{name: "auditor", identity: auditor, code: CodeForbidden},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			details, err := l.readPrivate(test.identity, "G1", "AwardeeMSP/bob")
+			if test.code != "" {
+				wantCode(t, err, test.code)
+				return
+			}
+			noError(t, err)
+			if details.Account_Number != "ACC-bob" || details.Contact != "bob@example.org" {
+				t.Fatalf("got details %+v", details)
+			}
+		})
+	}
+}
+
+func TestMigratePrivateDetails(t *testing.T) {
+	l := newLedger(t)
+	l.activeGrant("G1")
+
+	// Store bob's details on the public grant, as older versions did
+	l.must(l.run(grantor, nil, func(ctx contractapi.TransactionContextInterface) error {
+		grant, err := readGrant(ctx, "G1")
+		if err != nil {
+			return err
+		}
+		err = deleteAwardeePrivateDetails(ctx, grant)
+		if err != nil {
+			return err
+		}
+		grant.Awardee[0].Account_Number = "ACC-old"
+		grant.Awardee[0].Contact = "bob@old.example.org"
+		grant.Awardee[0].Private_Hash = ""
+		return putGrant(ctx, grant)
+	}))
+
+	err := l.run(grantor, nil, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.MigratePrivateDetails(ctx)
+		return err
+	})
+	wantCode(t, err, CodeValidation)
+
+	var migrated int
+	l.must(l.run(grantor, map[string]interface{}{"salt": testSalt}, func(ctx contractapi.TransactionContextInterface) error {
+		var err error
+		migrated, err = l.contract.MigratePrivateDetails(ctx)
+		return err
+	}))
+	if migrated != 1 {
+		t.Fatalf("migrated %d grants, want 1", migrated)
+	}
+
+	grant, err := l.read(grantor, "G1")
+	l.must(err)
+	if grant.Awardee[0].Account_Number != "" || grant.Awardee[0].Contact != "" || grant.Awardee[0].Private_Hash == "" {
+		t.Fatalf("got awardee %+v on the grant", grant.Awardee[0])
+	}
+	details, err := l.readPrivate(awardee, "G1", "AwardeeMSP/bob")
+	l.must(err)
+	if details.Account_Number != "ACC-old" || details.Contact != "bob@old.example.org" {
+		t.Fatalf("got details %+v", details)
+	}
+}
@@ -0,0 +1,322 @@
+package chaincode
+
+import (
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// A rebudget moves money between the benefit lines of a grant, e.g. from
+// Equipment to Personnel, leaving its amount unchanged. The main awardee
+// requests one as a list of transfers with a justification. Small rebudgets
+// are approved at once: as long as the transfers auto-approved so far,
+// including this one, move no more than Rebudget_Threshold percent of the
+// grant amount in total. Anything beyond needs the grantor's approval. Every
+// request is kept in the grant's amendments, and each approved one makes a
+// new version of the budget.
+//
+// Funder shares are split per benefit line, so the lines of a co-funded grant
+// only change by budget amendment.
+
+// Transfer moves Amount from the From benefit line to the To line.
+type Transfer struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Amount Money  `json:"amount"`
+}
+
+// rebudgetOf returns the budget of the grant after the transfers, and the
+// total amount they move. The budget is checked like any new budget.
+func rebudgetOf(ctx contractapi.TransactionContextInterface, grant *Grant, transfers []Transfer) (*Budget, int64, error) {
+	currency := grantCurrency(grant)
+	lines := append([]Benefit{}, grant.Benefit...)
+	amounts := map[string]int64{}
+	index := map[string]int{}
+	for i, line := range lines {
+		units, err := line.Amount.minor(currency)
+		if err != nil {
+			return nil, 0, storedAmountInvalid(err, "Grant %s has an invalid amount for %s benefit", grant.ID, line.Benefit)
+		}
+		amounts[line.Benefit] = units
+		index[line.Benefit] = i
+	}
+
+	var moved int64
+	for _, transfer := range transfers {
+		for _, line := range []string{transfer.From, transfer.To} {
+			if _, ok := index[line]; !ok {
+				return nil, 0, errValidation("Grant %s has no %s benefit", grant.ID, line).
+					with("grant_id", grant.ID).
+					with("benefit", line)
+			}
+		}
+		units, err := transfer.Amount.minor(currency)
+		if err != nil {
+			return nil, 0, invalidAmount(err, "invalid amount for the transfer from %s to %s", transfer.From, transfer.To)
+		}
+		if units > amounts[transfer.From] {
+			return nil, 0, errBudgetExceeded("Transfer of %s exceeds the %s left in %s benefit", moneyFromMinor(units, currency), moneyFromMinor(amounts[transfer.From], currency), transfer.From).
+				with("grant_id", grant.ID).
+				with("benefit", transfer.From).
+				with("requested", moneyFromMinor(units, currency)).
+				with("remaining", moneyFromMinor(amounts[transfer.From], currency))
+		}
+		amounts[transfer.From] -= units
+		amounts[transfer.To] += units
+		moved += units
+	}
+	for line, i := range index {
+		lines[i].Amount = moneyFromMinor(amounts[line], currency)
+	}
+
+	budget, err := checkNewBudget(ctx, grant, &Grant{ID: grant.ID, Amount: grant.Amount, Benefit: lines})
+	if err != nil {
+		return nil, 0, err
+	}
+	budget.Version = grant.Budget_Version + 1
+	return budget, moved, nil
+}
+
+// autoApproved reports whether a rebudget moving moved can be approved at
+// once: whether the rebudgets auto-approved so far and it together move no
+// more than the grant's Rebudget_Threshold percent of its amount.
+func autoApproved(grant *Grant, moved int64) (bool, error) {
+	currency := grantCurrency(grant)
+	amount, err := grant.Amount.minor(currency)
+	if err != nil {
+		return false, storedAmountInvalid(err, "Grant %s has an invalid amount", grant.ID)
+	}
+
+	total := moved
+	for _, amendment := range grant.Amendments {
+		if amendment.Type != AmendmentRebudget || !amendment.Auto_Approved {
+			continue
+		}
+		units, err := amendment.Moved.minor(currency)
+		if err != nil {
+			return false, storedAmountInvalid(err, "Amendment %d of the Grant %s has an invalid amount", amendment.Number, grant.ID)
+		}
+		total += units
+	}
+	return total <= percentOf(amount, grant.Rebudget_Threshold), nil
+}
+
+// RebudgetRequest requests transfers between the benefit lines of a grant.
+// The rebudget_request transient input holds the grant ID, the transfers and
+// the justification. Transfers within the grant's rebudget threshold take
+// effect at once, larger ones once the grantor approves them. It returns the
+// number of the amendment recording the request - Main Awardee
+func (s *SmartContract) RebudgetRequest(ctx contractapi.TransactionContextInterface) (int, error) {
+	caller, err := authorize(ctx, "RebudgetRequest")
+	if err != nil {
+		return 0, err
+	}
+
+	transientMap, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return 0, errInternal("error getting transient: %v", err)
+	}
+	var input rebudgetTransientInput
+	err = readTransientInput(transientMap, "rebudget_request", &input)
+	if err != nil {
+		return 0, err
+	}
+
+	grant, err := readGrant(ctx, input.Grant_ID)
+	if err != nil {
+		return 0, err
+	}
+	if !checkAwardee(grant.Awardee, caller.ID) {
+		return 0, awardeeNotAssigned(caller.ID, grant)
+	}
+	err = checkGrantStatus(grant, GrantActive)
+	if err != nil {
+		return 0, err
+	}
+	if len(grant.Funder) != 0 {
+		return 0, errInvalidState("Grant %s is co-funded, its benefit lines can only change by amendment", grant.ID).
+			with("grant_id", grant.ID).
+			with("function", "ProposeAmendment")
+	}
+	if pending := pendingBudgetChange(grant); pending != nil {
+		return 0, budgetChangePending(grant, pending)
+	}
+
+	budget, moved, err := rebudgetOf(ctx, grant, input.Transfer)
+	if err != nil {
+		return 0, err
+	}
+	auto, err := autoApproved(grant, moved)
+	if err != nil {
+		return 0, err
+	}
+
+	now, err := txTimestamp(ctx)
+	if err != nil {
+		return 0, err
+	}
+	at := now.Format(time.RFC3339)
+	amendment := addAmendment(grant, Amendment{
+		Type:            AmendmentRebudget,
+		Status:          AmendmentRequested,
+		Reason:          input.Justification,
+		Requested_By:    caller.ID,
+		Requested_At:    at,
+		Transfer:        input.Transfer,
+		Moved:           moneyFromMinor(moved, grantCurrency(grant)),
+		Previous_Budget: budgetOf(grant),
+		Budget:          budget,
+	})
+
+	name := EventRebudgetRequested
+	if auto {
+		name = EventRebudgetApproved
+		amendment.Status = AmendmentApproved
+		amendment.Auto_Approved = true
+		amendment.Decided_At = at
+		amendment.Effective_Date = at
+		err = putGrantBudget(ctx, grant, budget)
+	} else {
+		err = putGrant(ctx, grant)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	err = emitAmendmentEvent(ctx, caller, name, grant, amendment)
+	if err != nil {
+		return 0, err
+	}
+	return amendment.Number, nil
+}
+
+// ApproveRebudget approves the requested rebudget with the amendment number,
+// applying its transfers to the current budget - Grantor
+func (s *SmartContract) ApproveRebudget(ctx contractapi.TransactionContextInterface, grant_id string, number int, notes string) (bool, error) {
+	caller, grant, amendment, err := readRequestedRebudget(ctx, "ApproveRebudget", grant_id, number)
+	if err != nil {
+		return false, err
+	}
+
+	// The transfers are checked again, payments made since may have used the lines
+	budget, _, err := rebudgetOf(ctx, grant, amendment.Transfer)
+	if err != nil {
+		return false, err
+	}
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return false, err
+	}
+	amendment.Previous_Budget = budgetOf(grant)
+	amendment.Budget = budget
+	decideAmendment(amendment, AmendmentApproved, caller, now, notes)
+	amendment.Effective_Date = now
+
+	err = putGrantBudget(ctx, grant, budget)
+	if err != nil {
+		return false, err
+	}
+
+	err = emitAmendmentEvent(ctx, caller, EventRebudgetApproved, grant, amendment)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// DenyRebudget denies the requested rebudget with the amendment number; the
+// grant keeps its budget - Grantor
+func (s *SmartContract) DenyRebudget(ctx contractapi.TransactionContextInterface, grant_id string, number int, reason string) (bool, error) {
+	caller, grant, amendment, err := readRequestedRebudget(ctx, "DenyRebudget", grant_id, number)
+	if err != nil {
+		return false, err
+	}
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return false, err
+	}
+	decideAmendment(amendment, AmendmentDenied, caller, now, reason)
+
+	err = putGrant(ctx, grant)
+	if err != nil {
+		return false, err
+	}
+
+	err = emitAmendmentEvent(ctx, caller, EventRebudgetDenied, grant, amendment)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// readRequestedRebudget authorizes the grantor of the grant to decide the
+// rebudget with the number and returns it.
+func readRequestedRebudget(ctx contractapi.TransactionContextInterface, function string, grantID string, number int) (*Caller, *Grant, *Amendment, error) {
+	caller, err := authorize(ctx, function)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	grant, err := readGrant(ctx, grantID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	err = checkGrantOwner(caller, grant, "decide rebudgets of", RoleProgramOfficer)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	err = checkGrantStatus(grant, GrantActive, GrantSuspended)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	amendment, err := findAmendment(grant, number)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	err = checkAmendmentRequested(grant, amendment, AmendmentRebudget)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return caller, grant, amendment, nil
+}
+
+// SetRebudgetThreshold sets the percentage of the grant amount that rebudgets
+// may move in total without the grantor's approval. 0 has every rebudget
+// approved by the grantor - Grantor
+func (s *SmartContract) SetRebudgetThreshold(ctx contractapi.TransactionContextInterface, grant_id string, threshold float64) (bool, error) {
+	caller, err := authorize(ctx, "SetRebudgetThreshold")
+	if err != nil {
+		return false, err
+	}
+
+	grant, err := readGrant(ctx, grant_id)
+	if err != nil {
+		return false, err
+	}
+	err = checkGrantOwner(caller, grant, "set the rebudget threshold of")
+	if err != nil {
+		return false, err
+	}
+	err = checkGrantStatus(grant, GrantDraft, GrantAssigned, GrantActive, GrantSuspended)
+	if err != nil {
+		return false, err
+	}
+	if threshold < 0 || threshold > 100 {
+		return false, errValidation("rebudget threshold must be between 0 and 100, got %v", threshold).with("threshold", threshold)
+	}
+	grant.Rebudget_Threshold = threshold
+
+	err = putGrant(ctx, grant)
+	if err != nil {
+		return false, err
+	}
+
+	err = emitGrantEvent(ctx, caller, EventRebudgetThresholdSet, grant, grant.Status)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
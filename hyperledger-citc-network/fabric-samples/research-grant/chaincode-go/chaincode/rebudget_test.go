@@ -0,0 +1,121 @@
+package chaincode
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+func transfer(from string, to string, amount string) map[string]interface{} {
+	return map[string]interface{}{"from": from, "to": to, "amount": amount}
+}
+
+func (l *ledger) rebudget(grantID string, transfers ...map[string]interface{}) (int, error) {
+	l.t.Helper()
+	input := map[string]interface{}{"grant_id": grantID, "transfer": transfers, "justification": "equipment came in under budget"}
+	var number int
+	err := l.run(awardee, map[string]interface{}{"rebudget_request": input}, func(ctx contractapi.TransactionContextInterface) error {
+		var err error
+		number, err = l.contract.RebudgetRequest(ctx)
+		return err
+	})
+	return number, err
+}
+
+func (l *ledger) setRebudgetThreshold(grantID string, threshold float64) error {
+	l.t.Helper()
+	return l.run(grantor, nil, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.SetRebudgetThreshold(ctx, grantID, threshold)
+		return err
+	})
+}
+
+func (l *ledger) benefits(grantID string) map[string]Money {
+	l.t.Helper()
+	grant, err := l.read(grantor, grantID)
+	l.must(err)
+	amounts := map[string]Money{}
+	for _, benefit := range grant.Benefit {
+		amounts[benefit.Benefit] = benefit.Amount
+	}
+	return amounts
+}
+
+func TestRebudgetWithinThresholdIsApproved(t *testing.T) {
+	l := newLedger(t)
+	l.activeGrant("G1")
+	l.must(l.setRebudgetThreshold("G1", 10))
+
+	_, err := l.rebudget("G1", transfer("Equipment", "Personnel", "60"))
+	l.must(err)
+	if benefits := l.benefits("G1"); benefits["Personnel"] != "660.00" || benefits["Equipment"] != "340.00" {
+		t.Fatalf("got benefits %v", benefits)
+	}
+
+	// Auto-approved transfers add up against the threshold
+	number, err := l.rebudget("G1", transfer("Equipment", "Personnel", "40.01"))
+	l.must(err)
+	grant, err := l.read(grantor, "G1")
+	l.must(err)
+	amendment := grant.Amendments[number-1]
+	if amendment.Status != AmendmentRequested || amendment.Auto_Approved || grant.Budget_Version != 1 {
+		t.Fatalf("got amendment %+v at version %d", amendment, grant.Budget_Version)
+	}
+
+	l.must(l.run(grantor, nil, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.ApproveRebudget(ctx, "G1", number, "")
+		return err
+	}))
+	if benefits := l.benefits("G1"); benefits["Personnel"] != "700.01" || benefits["Equipment"] != "299.99" {
+		t.Fatalf("got benefits %v", benefits)
+	}
+}
+
+func TestRebudgetValidation(t *testing.T) {
+	tests := []struct {
+		name      string
+		transfers []map[string]interface{}
+		code      ErrorCode
+	}{
+		{name: "more than the line", transfers: []map[string]interface{}{transfer("Equipment", "Personnel", "400.01")}, code: CodeBudgetExceeded},
+		{name: "committed spend", transfers: []map[string]interface{}{transfer("Personnel", "Equipment", "500.01")}, code: CodeBudgetExceeded},
+		{name: "unknown line", transfers: []map[string]interface{}{transfer("Travel", "Personnel", "1")}, code: CodeValidation},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			l := newLedger(t)
+			l.activeGrant("G1")
+			_, err := l.reimburse(awardee, "G1", "P1", "AwardeeMSP/bob", item("Personnel", "100"))
+			l.must(err)
+
+			_, err = l.rebudget("G1", test.transfers...)
+			wantCode(t, err, test.code)
+		})
+	}
+}
+
+func TestDeniedRebudget(t *testing.T) {
+	l := newLedger(t)
+	l.activeGrant("G1")
+
+	number, err := l.rebudget("G1", transfer("Equipment", "Personnel", "100"))
+	l.must(err)
+	_, err = l.rebudget("G1", transfer("Equipment", "Personnel", "1"))
+	wantCode(t, err, CodeDuplicate)
+
+	l.must(l.run(grantor, nil, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.DenyRebudget(ctx, "G1", number, "keep the equipment line")
+		return err
+	}))
+	if benefits := l.benefits("G1"); benefits["Personnel"] != "600.00" || benefits["Equipment"] != "400.00" {
+		t.Fatalf("got benefits %v", benefits)
+	}
+}
+
+func TestCoFundedGrantIsNotRebudgeted(t *testing.T) {
+	l := newLedger(t)
+	l.activeCoFundedGrant("G1")
+
+	_, err := l.rebudget("G1", transfer("Equipment", "Personnel", "1"))
+	wantCode(t, err, CodeInvalidState)
+}
@@ -0,0 +1,170 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// grantDocType is the doc_type of grants in the world state. CouchDB queries
+// select on it to leave out payments and the other documents of the chaincode.
+const grantDocType = "grant"
+
+// GrantQueryPage is one page of QueryGrants results. The fields mean the same
+// as in GrantPage. Rich_Query reports whether the peer ran the selector in
+// CouchDB; when false the peer has a LevelDB state database, the selector was
+// evaluated in the chaincode and the bookmark is a state key rather than a
+// CouchDB bookmark.
+type GrantQueryPage struct {
+	Records       []Grant `json:"records"`
+	Fetched_Count int32   `json:"fetchedCount"`
+	Bookmark      string  `json:"bookmark"`
+	Rich_Query    bool    `json:"richQuery"`
+}
+
+// isRichQueryUnsupported reports whether err is the peer refusing a rich query
+// because its state database is LevelDB.
+func isRichQueryUnsupported(err error) bool {
+	return strings.Contains(err.Error(), "not supported for leveldb")
+}
+
+// matchGrantView reports whether the selector matches the stored grant as a
+// caller with access sees it. Summary views are matched after redaction, so a
+// selector can't probe the fields redaction removes.
+func matchGrantView(selector map[string]interface{}, value []byte, access grantAccess) (bool, error) {
+	if access != accessFull {
+		var grant Grant
+		err := json.Unmarshal(value, &grant)
+		if err != nil {
+			return false, errInternal("failed to unmarshal JSON: %v", err)
+		}
+		redactGrant(&grant)
+		value, err = json.Marshal(&grant)
+		if err != nil {
+			return false, errInternal("failed to marshal grant into JSON: %v", err)
+		}
+	}
+
+	var doc interface{}
+	err := json.Unmarshal(value, &doc)
+	if err != nil {
+		return false, errInternal("failed to unmarshal JSON: %v", err)
+	}
+	return matchSelector(selector, doc)
+}
+
+// QueryGrants runs a CouchDB Mango selector over the grants the user may
+// read, one page at a time, with the access and redaction of ReadGrant. The
+// selector is only the "selector" object of a CouchDB query, e.g.
+// {"status":"Active","currency":"USD"}; amounts are stored as decimal strings.
+// On LevelDB peers, and for grants the user reads a summary of, the selector
+// is evaluated in the chaincode, see matchSelector for the operators
+// supported there.
+func (s *SmartContract) QueryGrants(ctx contractapi.TransactionContextInterface, selectorJSON string, pageSize int32, bookmark string) (*GrantQueryPage, error) {
+	caller, err := authorize(ctx, "QueryGrants")
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkPageSize(pageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	var selector map[string]interface{}
+	err = json.Unmarshal([]byte(selectorJSON), &selector)
+	if err != nil {
+		return nil, errValidation("selector must be a JSON object: %v", err)
+	}
+	if selector == nil {
+		return nil, errValidation("selector must be a JSON object")
+	}
+
+	query := map[string]interface{}{
+		"selector": map[string]interface{}{
+			"$and": []interface{}{
+				map[string]interface{}{"doc_type": grantDocType},
+				selector,
+			},
+		},
+	}
+	queryJSON, err := json.Marshal(query)
+	if err != nil {
+		return nil, errInternal("failed to marshal query: %v", err)
+	}
+
+	resultsIterator, metadata, err := ctx.GetStub().GetQueryResultWithPagination(string(queryJSON), pageSize, bookmark)
+	if err != nil {
+		if isRichQueryUnsupported(err) {
+			return scanGrants(ctx, selector, caller, pageSize, bookmark)
+		}
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	page := GrantQueryPage{Records: []Grant{}, Rich_Query: true}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, errInternal("failed to read from world state: %v", err)
+		}
+
+		var grant Grant
+		err = json.Unmarshal(queryResponse.Value, &grant)
+		if err != nil {
+			return nil, errInternal("failed to unmarshal JSON: %v", err)
+		}
+		access := grantAccessOf(caller, &grant)
+		if access == accessNone {
+			continue
+		}
+		if access != accessFull {
+			ok, err := matchGrantView(selector, queryResponse.Value, access)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+		}
+
+		err = setPaymentTotals(ctx, &grant)
+		if err != nil {
+			return nil, err
+		}
+		viewGrant(&grant, access)
+		page.Records = append(page.Records, grant)
+	}
+
+	if metadata != nil {
+		page.Fetched_Count = metadata.FetchedRecordsCount
+		page.Bookmark = metadata.Bookmark
+	}
+	return &page, nil
+}
+
+// scanGrants is the LevelDB path of QueryGrants.
+func scanGrants(ctx contractapi.TransactionContextInterface, selector map[string]interface{}, caller *Caller, pageSize int32, bookmark string) (*GrantQueryPage, error) {
+	grantPage, err := queryGrantPage(ctx, pageSize, bookmark, func(grant *Grant, value []byte) (bool, error) {
+		access := grantAccessOf(caller, grant)
+		if access == accessNone {
+			return false, nil
+		}
+		return matchGrantView(selector, value, access)
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i := range grantPage.Records {
+		viewGrant(&grantPage.Records[i], grantAccessOf(caller, &grantPage.Records[i]))
+	}
+
+	page := GrantQueryPage{
+		Records:       grantPage.Records,
+		Fetched_Count: grantPage.Fetched_Count,
+		Bookmark:      grantPage.Bookmark,
+		Rich_Query:    false,
+	}
+	return &page, nil
+}
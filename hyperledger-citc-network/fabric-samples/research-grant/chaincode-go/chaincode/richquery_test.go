@@ -0,0 +1,144 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"chaincode-go/chaincodetest"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+func (l *ledger) queryGrants(identity *chaincodetest.Identity, selector string) (*GrantQueryPage, error) {
+	l.t.Helper()
+	var page *GrantQueryPage
+	err := l.run(identity, nil, func(ctx contractapi.TransactionContextInterface) error {
+		var err error
+		page, err = l.contract.QueryGrants(ctx, selector, 10, "")
+		return err
+	})
+	return page, err
+}
+
+func TestQueryGrantsOnlyReturnsTheCallersGrants(t *testing.T) {
+	l := newLedger(t)
+	l.activeGrant("G1")
+	l.addSubawardee("G1")
+	l.must(l.initiate(newGrant("G2")))
+
+	tests := []struct {
+		name     string
+		identity *chaincodetest.Identity
+		selector string
+		want     []string
+	}{
+		{"grantor", grantor, `{"currency":"USD"}`, []string{"G1", "G2"}},
+		{"grantor by status", grantor, `{"status":"Draft"}`, []string{"G2"}},
+		{"awardee", awardee, `{"currency":"USD"}`, []string{"G1"}},
+		{"subawardee", subawardee, `{"awardee":{"$elemMatch":{"awardee_type":"Sub"}}}`, []string{"G1"}},
+		{"other grantor", chaincodetest.NewIdentity(GrantorMSP, "eve"), `{"currency":"USD"}`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := l.queryGrants(tt.identity, tt.selector)
+			noError(t, err)
+			if page.Rich_Query {
+				t.Fatalf("the fake ledger ran a rich query")
+			}
+			var got []string
+			for _, grant := range page.Records {
+				got = append(got, grant.ID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got grants %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryGrantsFollowsGrantAccess(t *testing.T) {
+	l := newLedger(t)
+	l.withAgency()
+	grant := coFundedGrant("G1")
+	grant["notes"] = "site visit pending"
+	l.must(l.initiate(grant))
+	l.must(l.assign("G1", testAwardee("bob", "Main", AwardeeMSP)))
+	l.must(l.initiate(newGrant("G2")))
+
+	tests := []struct {
+		name     string
+		identity *chaincodetest.Identity
+		selector string
+		want     []string
+		redacted bool
+	}{
+		{name: "co-funder", identity: agency, selector: `{"currency":"USD"}`, want: []string{"G1"}},
+		{name: "auditor", identity: auditor, selector: `{"currency":"USD"}`, want: []string{"G1", "G2"}, redacted: true},
+		{name: "auditor on a redacted field", identity: auditor, selector: `{"notes":"site visit pending"}`},
+		{name: "co-funder on notes", identity: agency, selector: `{"notes":"site visit pending"}`, want: []string{"G1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := l.queryGrants(tt.identity, tt.selector)
+			noError(t, err)
+			var got []string
+			for _, grant := range page.Records {
+				got = append(got, grant.ID)
+				if grant.Redacted != tt.redacted {
+					t.Fatalf("grant %s redacted = %v, want %v", grant.ID, grant.Redacted, tt.redacted)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got grants %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryGrantsRejectsBadSelectors(t *testing.T) {
+	l := newLedger(t)
+	l.must(l.initiate(newGrant("G1")))
+
+	for _, selector := range []string{`null`, `["status"]`, `{"status":{"$regex":"^Dr"}}`} {
+		_, err := l.queryGrants(grantor, selector)
+		if err == nil {
+			t.Errorf("selector %s was accepted", selector)
+		}
+	}
+}
+
+func TestQueryGrantsSelectsOnlyGrants(t *testing.T) {
+	l := newLedger(t)
+	l.activeGrant("G1")
+
+	_, err := l.queryGrants(grantor, `{"status":"Active"}`)
+	l.must(err)
+	want := `{"selector":{"$and":[{"doc_type":"grant"},{"status":"Active"}]}}`
+	if len(l.stub.Queries) != 1 || l.stub.Queries[0] != want {
+		t.Fatalf("got queries %v, want %s", l.stub.Queries, want)
+	}
+}
+
+func TestCouchDBIndexesSelectOnDocType(t *testing.T) {
+	files, err := filepath.Glob("../META-INF/statedb/couchdb/indexes/*.json")
+	noError(t, err)
+	if len(files) == 0 {
+		t.Fatalf("no CouchDB indexes found")
+	}
+	for _, file := range files {
+		indexJSON, err := ioutil.ReadFile(file)
+		noError(t, err)
+		var index struct {
+			Index struct {
+				Fields []string `json:"fields"`
+			} `json:"index"`
+		}
+		noError(t, json.Unmarshal(indexJSON, &index))
+		if len(index.Index.Fields) == 0 || index.Index.Fields[0] != "doc_type" {
+			t.Errorf("%s indexes %v, want doc_type first", filepath.Base(file), index.Index.Fields)
+		}
+	}
+}
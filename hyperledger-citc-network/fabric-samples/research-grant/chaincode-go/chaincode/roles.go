@@ -0,0 +1,380 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Role is what a user does within their org. It is read from the grant.role
+// attribute of their certificate, which Fabric CA embeds when the user is
+// registered with e.g. --id.attrs 'grant.role=finance:ecert'.
+type Role string
+
+const (
+	RoleProgramOfficer Role = "program_officer"
+	RoleFinance        Role = "finance"
+	RoleAuditor        Role = "auditor"
+	RolePI             Role = "pi"
+	RoleAdmin          Role = "admin"
+)
+
+const roleAttribute = "grant.role"
+
+// parseRole validates a role name supplied by a client.
+func parseRole(role string) (Role, error) {
+	switch Role(role) {
+	case RoleProgramOfficer, RoleFinance, RoleAuditor, RolePI, RoleAdmin:
+		return Role(role), nil
+	}
+	return "", errValidation("unknown role %q", role).with("role", role)
+}
+
+// orgKind is the part an org plays in the network, resolved from its MSP ID
+// through the OrgConfig.
+type orgKind string
+
+const (
+	orgGrantor    orgKind = "grantor"
+	orgAwardee    orgKind = "awardee"
+	orgSubawardee orgKind = "subawardee"
+	orgAuditor    orgKind = "auditor"
+	// anyOrg in a permission matches callers from every org, known or not.
+	anyOrg orgKind = "*"
+)
+
+// OrgConfig maps the orgs of the network to the MSP IDs they use. Until
+// SetOrgConfig stores one, the package defaults GrantorMSP, AwardeeMSP,
+// SubawardeeMSP and AuditorMSP apply. Funder_MSPs lists further agencies
+// that fund grants; they act as grantor orgs. Require_Roles defaults to true:
+// users whose certificate has no grant.role attribute hold no role. A network
+// whose users predate roles may set it to false, so that they hold every role
+// of their org until each has been issued one.
+//
+// collections_config.json names the default MSP IDs in the policies of the
+// awardee collections. A config with other MSP IDs needs those policies
+// changed to match, in a new chaincode definition, or peers will hold the
+// private details for different orgs than collectionMembers reports.
+type OrgConfig struct {
+	Grantor_MSP    string   `json:"grantor_msp"`
+	Funder_MSPs    []string `json:"funder_msps,omitempty" metadata:"funder_msps,optional"`
+	Awardee_MSP    string   `json:"awardee_msp"`
+	Subawardee_MSP string   `json:"subawardee_msp"`
+	Auditor_MSP    string   `json:"auditor_msp"`
+	Require_Roles  bool     `json:"require_roles"`
+}
+
+func defaultOrgConfig() *OrgConfig {
+	return &OrgConfig{
+		Grantor_MSP:    GrantorMSP,
+		Awardee_MSP:    AwardeeMSP,
+		Subawardee_MSP: SubawardeeMSP,
+		Auditor_MSP:    AuditorMSP,
+		Require_Roles:  true,
+	}
+}
+
+// grantorMSPs returns the MSP IDs of every org acting as grantor.
+func (c *OrgConfig) grantorMSPs() []string {
+	return append([]string{c.Grantor_MSP}, c.Funder_MSPs...)
+}
+
+func (c *OrgConfig) orgOf(mspID string) orgKind {
+	for _, grantorMSP := range c.grantorMSPs() {
+		if mspID == grantorMSP {
+			return orgGrantor
+		}
+	}
+	switch mspID {
+	case c.Awardee_MSP:
+		return orgAwardee
+	case c.Subawardee_MSP:
+		return orgSubawardee
+	case c.Auditor_MSP:
+		return orgAuditor
+	}
+	return ""
+}
+
+func (c *OrgConfig) validate() error {
+	seen := map[string]bool{}
+	mspIDs := append(c.grantorMSPs(), c.Awardee_MSP, c.Subawardee_MSP, c.Auditor_MSP)
+	for _, mspID := range mspIDs {
+		if mspID == "" {
+			return errValidation("every org must have an MSP ID")
+		}
+		if seen[mspID] {
+			return errDuplicate("MSP ID %s is configured for more than one org", mspID).with("msp", mspID)
+		}
+		seen[mspID] = true
+	}
+	return nil
+}
+
+const orgConfigObjectType = "config"
+
+func orgConfigKey(ctx contractapi.TransactionContextInterface) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(orgConfigObjectType, []string{"orgs"})
+	if err != nil {
+		return "", errInternal("failed to create config key: %v", err)
+	}
+	return key, nil
+}
+
+// getOrgConfig returns the stored OrgConfig, or the defaults if none is stored.
+func getOrgConfig(ctx contractapi.TransactionContextInterface) (*OrgConfig, error) {
+	key, err := orgConfigKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	configJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, errInternal("failed to read from world state: %v", err)
+	}
+	if configJSON == nil {
+		return defaultOrgConfig(), nil
+	}
+
+	var config OrgConfig
+	err = json.Unmarshal(configJSON, &config)
+	if err != nil {
+		return nil, errInternal("failed to unmarshal JSON: %v", err)
+	}
+	return &config, nil
+}
+
+// permission lets callers from org holding one of roles call a function. No
+// roles means any role, or none. Admins hold every role of their org.
+type permission struct {
+	org   orgKind
+	roles []Role
+}
+
+var (
+	programOfficer = []permission{{orgGrantor, []Role{RoleProgramOfficer}}}
+	grantorFinance = []permission{{orgGrantor, []Role{RoleFinance}}}
+	grantorAdmin   = []permission{{orgGrantor, []Role{RoleAdmin}}}
+	// grantorApprover may approve reimbursements under an approval policy
+	grantorApprover = []permission{{orgGrantor, []Role{RoleFinance, RoleProgramOfficer}}}
+	awardeePI       = []permission{{orgAwardee, []Role{RolePI}}}
+	anyAwardeePI    = []permission{{orgAwardee, []Role{RolePI}}, {orgSubawardee, []Role{RolePI}}}
+	anyAwardee      = []permission{{orgAwardee, []Role{RolePI, RoleFinance}}, {orgSubawardee, []Role{RolePI, RoleFinance}}}
+	anyReader       = []permission{{anyOrg, nil}}
+)
+
+// permissions is who may call each SmartContract function. Queries are open
+// to everyone here; which grants a caller reads is decided by grantAccessOf.
+// A function missing from the table can't be called.
+var permissions = map[string][]permission{
+	"InitLedger":            grantorAdmin,
+	"SetOrgConfig":          grantorAdmin,
+	"MigratePayments":       grantorAdmin,
+	"MigrateMoney":          grantorAdmin,
+	"RebuildIndexes":        grantorAdmin,
+	"MigrateIdentities":     grantorAdmin,
+	"MigratePrivateDetails": grantorAdmin,
+
+	"InitiateGrant":  programOfficer,
+	"AssignGrant":    programOfficer,
+	"UpdateGrant":    programOfficer,
+	"RevokeGrant":    programOfficer,
+	"SuspendGrant":   programOfficer,
+	"ReinstateGrant": programOfficer,
+	"CloseGrant":     programOfficer,
+	"AddAwardee":     programOfficer,
+	"DeleteGrant":    programOfficer,
+
+	"ApproveExtension": programOfficer,
+	"DenyExtension":    programOfficer,
+	"ProposeAmendment": programOfficer,
+	"ApproveRebudget":  programOfficer,
+	"DenyRebudget":     programOfficer,
+
+	"SetApprovalPolicy":      programOfficer,
+	"SetRebudgetThreshold":   programOfficer,
+	"GetPendingApprovals":    grantorApprover,
+	"UpdateGrantEndorsement": programOfficer,
+
+	"AcceptReimbursement": grantorApprover,
+	"RejectReimbursement": grantorApprover,
+	"AcceptRedeem":        grantorFinance,
+	"RejectRedeem":        grantorFinance,
+
+	"AcceptGrant":          awardeePI,
+	"RejectGrant":          awardeePI,
+	"AddSubawardee":        awardeePI,
+	"RequestExtension":     awardeePI,
+	"CountersignAmendment": awardeePI,
+	"DeclineAmendment":     awardeePI,
+	"RebudgetRequest":      awardeePI,
+	"AddProgress":          anyAwardeePI,
+	"RequestReimbursement": anyAwardee,
+	"RedeemTokens":         anyAwardee,
+
+	"ReadGrant":                          anyReader,
+	"GetAllGrants":                       anyReader,
+	"GetAllGrantsUser":                   anyReader,
+	"GetAllApprovedGrants":               anyReader,
+	"GetGrantsByStatus":                  anyReader,
+	"GetGrantBenefits":                   anyReader,
+	"GetPayments":                        anyReader,
+	"GetPaymentByAwardee":                anyReader,
+	"GetProgress":                        anyReader,
+	"GetRemainingAmount":                 anyReader,
+	"GetWallet":                          anyReader,
+	"MyWallet":                           anyReader,
+	"GetAllowedTransitions":              anyReader,
+	"GetGrantHistory":                    anyReader,
+	"GetPaymentHistory":                  anyReader,
+	"ReadAwardeePrivateDetails":          anyReader,
+	"QueryGrants":                        anyReader,
+	"QueryPayments":                      anyReader,
+	"GetOrgConfig":                       anyReader,
+	"GetAllGrantsWithPagination":         anyReader,
+	"GetAllGrantsUserWithPagination":     anyReader,
+	"GetAllApprovedGrantsWithPagination": anyReader,
+	"GetGrantsByStatusWithPagination":    anyReader,
+	"QueryPaymentsWithPagination":        anyReader,
+	"GetFunders":                         anyReader,
+	"GetGrantEndorsement":                anyReader,
+	"GetAmendments":                      anyReader,
+	"GetBudgetAsOf":                      anyReader,
+}
+
+// holdsRole reports whether the caller holds one of roles. Callers without a
+// role attribute hold none, unless the config lets them through by setting
+// Require_Roles to false.
+func holdsRole(caller *Caller, config *OrgConfig, roles []Role) bool {
+	if len(roles) == 0 {
+		return true
+	}
+	if caller.Role == "" {
+		return !config.Require_Roles
+	}
+	if caller.Role == RoleAdmin {
+		return true
+	}
+	for _, role := range roles {
+		if caller.Role == role {
+			return true
+		}
+	}
+	return false
+}
+
+// authorize resolves the caller and checks the permission table for function.
+func authorize(ctx contractapi.TransactionContextInterface, function string) (*Caller, error) {
+	caller, err := getCaller(ctx)
+	if err != nil {
+		return nil, err
+	}
+	config, err := getOrgConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	caller.Org = config.orgOf(caller.MSPID)
+
+	for _, perm := range permissions[function] {
+		if perm.org != anyOrg && perm.org != caller.Org {
+			continue
+		}
+		if holdsRole(caller, config, perm.roles) {
+			return caller, nil
+		}
+	}
+
+	if caller.Role == "" {
+		return nil, errForbidden("User %s from org %v is not authorized to call %s", caller.ID, caller.MSPID, function).with("function", function)
+	}
+	return nil, errForbidden("User %s from org %v with role %s is not authorized to call %s", caller.ID, caller.MSPID, caller.Role, function).
+		with("function", function).
+		with("role", caller.Role)
+}
+
+// checkGrantOwner lets the grant's grantor act on it, and members of the
+// grantor's org holding one of roles act on grants they don't own. Admins
+// always can. Unlike the permission table, this needs an explicit role
+// attribute. Either way the caller must be of the grantor's org; users of
+// other grantor orgs, funders included, never own the grant.
+func checkGrantOwner(caller *Caller, grant *Grant, action string, roles ...Role) error {
+	if caller.Org == orgGrantor && orgName(caller.MSPID) == grant.Grantor {
+		if caller.ID == grant.Grantor_ID || hasExplicitRole(caller, roles) {
+			return nil
+		}
+	}
+	return errForbidden("Grantor %s is not allowed to %s the Grant %s", caller.ID, action, grant.ID).with("grant_id", grant.ID)
+}
+
+// hasExplicitRole reports whether the caller's role attribute is admin or one
+// of roles. Callers without the attribute hold none.
+func hasExplicitRole(caller *Caller, roles []Role) bool {
+	if caller.Role == "" {
+		return false
+	}
+	if caller.Role == RoleAdmin {
+		return true
+	}
+	for _, role := range roles {
+		if caller.Role == role {
+			return true
+		}
+	}
+	return false
+}
+
+// orgName is the org name recorded on grants for an MSP ID, e.g. Grantor for GrantorMSP.
+func orgName(mspID string) string {
+	return strings.Replace(mspID, "MSP", "", 1)
+}
+
+// SetOrgConfig replaces the MSP IDs of the network's orgs and whether role
+// attributes are required. configJSON is a JSON OrgConfig - Grantor admin
+func (s *SmartContract) SetOrgConfig(ctx contractapi.TransactionContextInterface, configJSON string) (bool, error) {
+	caller, err := authorize(ctx, "SetOrgConfig")
+	if err != nil {
+		return false, err
+	}
+
+	// Roles stay required unless the config turns them off explicitly
+	config := OrgConfig{Require_Roles: true}
+	err = json.Unmarshal([]byte(configJSON), &config)
+	if err != nil {
+		return false, errValidation("failed to unmarshal JSON: %v", err)
+	}
+	err = config.validate()
+	if err != nil {
+		return false, err
+	}
+	if config.orgOf(caller.MSPID) != orgGrantor {
+		return false, errValidation("the config must keep org %v as the grantor", caller.MSPID)
+	}
+
+	key, err := orgConfigKey(ctx)
+	if err != nil {
+		return false, err
+	}
+	storedJSON, err := json.Marshal(&config)
+	if err != nil {
+		return false, errInternal("failed to marshal config into JSON: %v", err)
+	}
+	err = ctx.GetStub().PutState(key, storedJSON)
+	if err != nil {
+		return false, errInternal("failed to put config into ledger: %v", err)
+	}
+
+	err = emitConfigEvent(ctx, caller, &config)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// GetOrgConfig returns the org configuration in effect.
+func (s *SmartContract) GetOrgConfig(ctx contractapi.TransactionContextInterface) (*OrgConfig, error) {
+	_, err := authorize(ctx, "GetOrgConfig")
+	if err != nil {
+		return nil, err
+	}
+	return getOrgConfig(ctx)
+}
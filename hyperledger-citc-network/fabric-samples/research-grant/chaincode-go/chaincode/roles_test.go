@@ -0,0 +1,324 @@
+package chaincode
+
+import (
+	"errors"
+	"testing"
+
+	"chaincode-go/chaincodetest"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// notAuthorized reports whether err is the permission table refusing function.
+func notAuthorized(err error, function string) bool {
+	var contractErr *ContractError
+	return errors.As(err, &contractErr) &&
+		contractErr.Code == CodeForbidden &&
+		contractErr.Details["function"] == function
+}
+
+// transactions calls each function that changes the ledger with placeholder
+// arguments; only the permission check is of interest.
+var transactions = map[string]func(s *SmartContract, ctx contractapi.TransactionContextInterface) error{
+	"SetOrgConfig": func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+		_, err := s.SetOrgConfig(ctx, "{}")
+		return err
+	},
+	"MigrateIdentities": func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+		_, err := s.MigrateIdentities(ctx)
+		return err
+	},
+	"MigratePayments": func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+		_, err := s.MigratePayments(ctx, 10, "")
+		return err
+	},
+	"MigrateMoney": func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+		_, err := s.MigrateMoney(ctx)
+		return err
+	},
+	"MigratePrivateDetails": func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+		_, err := s.MigratePrivateDetails(ctx)
+		return err
+	},
+	"RebuildIndexes": func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+		_, err := s.RebuildIndexes(ctx)
+		return err
+	},
+	"InitiateGrant": func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+		_, err := s.InitiateGrant(ctx)
+		return err
+	},
+	"AssignGrant": func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+		_, err := s.AssignGrant(ctx)
+		return err
+	},
+	"UpdateGrant": func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+		_, err := s.UpdateGrant(ctx)
+		return err
+	},
+	"RevokeGrant": func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+		_, err := s.RevokeGrant(ctx, "G1")
+		return err
+	},
+	"SuspendGrant": func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+		_, err := s.SuspendGrant(ctx, "G1")
+		return err
+	},
+	"ReinstateGrant": func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+		_, err := s.ReinstateGrant(ctx, "G1")
+		return err
+	},
+	"CloseGrant": func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+		_, err := s.CloseGrant(ctx, "G1")
+		return err
+	},
+	"AddAwardee": func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+		_, err := s.AddAwardee(ctx)
+		return err
+	},
+	"DeleteGrant": func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+		_, err := s.DeleteGrant(ctx, "G1")
+		return err
+	},
+	"SetApprovalPolicy": func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+		_, err := s.SetApprovalPolicy(ctx, "G1", "")
+		return err
+	},
+	"GetPendingApprovals": func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+		_, err := s.GetPendingApprovals(ctx)
+		return err
+	},
+	"UpdateGrantEndorsement": func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+		_, err := s.UpdateGrantEndorsement(ctx, "G1")
+		return err
+	},
+	"AcceptReimbursement": func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+		_, err := s.AcceptReimbursement(ctx, "G1", "P1")
+		return err
+	},
+	"RejectReimbursement": func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+		_, err := s.RejectReimbursement(ctx, "G1", "P1", string(RejectionOther), "")
+		return err
+	},
+	"AcceptRedeem": func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+		_, err := s.AcceptRedeem(ctx, "G1", "P1")
+		return err
+	},
+	"RejectRedeem": func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+		_, err := s.RejectRedeem(ctx, "G1", "P1", string(RejectionOther), "")
+		return err
+	},
+	"ApproveExtension": func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+		_, err := s.ApproveExtension(ctx, "G1", 1, "")
+		return err
+	},
+	"DenyExtension": func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+		_, err := s.DenyExtension(ctx, "G1", 1, "")
+		return err
+	},
+	"ProposeAmendment": func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+		_, err := s.ProposeAmendment(ctx)
+		return err
+	},
+	"ApproveRebudget": func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+		_, err := s.ApproveRebudget(ctx, "G1", 1, "")
+		return err
+	},
+	"DenyRebudget": func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+		_, err := s.DenyRebudget(ctx, "G1", 1, "")
+		return err
+	},
+	"SetRebudgetThreshold": func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+		_, err := s.SetRebudgetThreshold(ctx, "G1", 10)
+		return err
+	},
+	"AcceptGrant": func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+		_, err := s.AcceptGrant(ctx, "G1")
+		return err
+	},
+	"RejectGrant": func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+		_, err := s.RejectGrant(ctx, "G1")
+		return err
+	},
+	"AddSubawardee": func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+		_, err := s.AddSubawardee(ctx)
+		return err
+	},
+	"AddProgress": func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+		_, err := s.AddProgress(ctx)
+		return err
+	},
+	"RequestReimbursement": func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+		_, err := s.RequestReimbursement(ctx)
+		return err
+	},
+	"RedeemTokens": func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+		_, err := s.RedeemTokens(ctx, "G1", "P1")
+		return err
+	},
+	"RequestExtension": func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+		_, err := s.RequestExtension(ctx)
+		return err
+	},
+	"CountersignAmendment": func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+		_, err := s.CountersignAmendment(ctx, "G1", 1)
+		return err
+	},
+	"DeclineAmendment": func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+		_, err := s.DeclineAmendment(ctx, "G1", 1, "")
+		return err
+	},
+	"RebudgetRequest": func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+		_, err := s.RebudgetRequest(ctx)
+		return err
+	},
+}
+
+func TestTransactionPermissions(t *testing.T) {
+	grantorOnly := []*chaincodetest.Identity{grantor}
+	awardeeOnly := []*chaincodetest.Identity{awardee}
+	awardees := []*chaincodetest.Identity{awardee, subawardee}
+	allowed := map[string][]*chaincodetest.Identity{
+		"AcceptGrant":          awardeeOnly,
+		"RejectGrant":          awardeeOnly,
+		"AddSubawardee":        awardeeOnly,
+		"RequestExtension":     awardeeOnly,
+		"CountersignAmendment": awardeeOnly,
+		"DeclineAmendment":     awardeeOnly,
+		"RebudgetRequest":      awardeeOnly,
+		"AddProgress":          awardees,
+		"RequestReimbursement": awardees,
+		"RedeemTokens":         awardees,
+	}
+
+	for function, call := range transactions {
+		callers, ok := allowed[function]
+		if !ok {
+			callers = grantorOnly
+		}
+		for _, identity := range []*chaincodetest.Identity{grantor, awardee, subawardee, auditor} {
+			permitted := false
+			for _, caller := range callers {
+				permitted = permitted || caller == identity
+			}
+
+			t.Run(function+"/"+identity.MSPID, func(t *testing.T) {
+				l := newLedger(t)
+				l.activeGrant("G1")
+				err := l.run(identity, nil, func(ctx contractapi.TransactionContextInterface) error {
+					return call(l.contract, ctx)
+				})
+				if notAuthorized(err, function) == permitted {
+					t.Fatalf("%s calling %s: got %v, permitted %v", identity.MSPID, function, err, permitted)
+				}
+			})
+		}
+	}
+}
+
+func TestRequiredRoles(t *testing.T) {
+	tests := []struct {
+		function string
+		msp      string
+		role     Role
+		allowed  bool
+	}{
+		{function: "InitiateGrant", msp: GrantorMSP, role: RoleProgramOfficer, allowed: true},
+		{function: "InitiateGrant", msp: GrantorMSP, role: RoleFinance},
+		{function: "InitiateGrant", msp: GrantorMSP, role: RoleAdmin, allowed: true},
+		{function: "InitiateGrant", msp: GrantorMSP},
+		{function: "AcceptReimbursement", msp: GrantorMSP, role: RoleFinance, allowed: true},
+		{function: "AcceptReimbursement", msp: GrantorMSP, role: RoleAuditor},
+		{function: "AcceptRedeem", msp: GrantorMSP, role: RoleProgramOfficer},
+		{function: "SetOrgConfig", msp: GrantorMSP, role: RoleProgramOfficer},
+		{function: "AcceptGrant", msp: AwardeeMSP, role: RolePI, allowed: true},
+		{function: "AcceptGrant", msp: AwardeeMSP, role: RoleFinance},
+		{function: "RequestReimbursement", msp: AwardeeMSP, role: RoleFinance, allowed: true},
+		{function: "RequestReimbursement", msp: SubawardeeMSP, role: RoleAuditor},
+		{function: "AddProgress", msp: SubawardeeMSP, role: RoleFinance},
+	}
+	for _, test := range tests {
+		t.Run(test.function+"/"+test.msp+"/"+string(test.role), func(t *testing.T) {
+			l := newLedger(t)
+			l.activeGrant("G1")
+			l.must(l.run(chaincodetest.NewIdentity(GrantorMSP, "root").WithAttribute(roleAttribute, string(RoleAdmin)), nil, func(ctx contractapi.TransactionContextInterface) error {
+				_, err := l.contract.SetOrgConfig(ctx, `{"grantor_msp":"GrantorMSP","awardee_msp":"AwardeeMSP","subawardee_msp":"SubawardeeMSP","auditor_msp":"AuditorMSP","require_roles":true}`)
+				return err
+			}))
+
+			identity := chaincodetest.NewIdentity(test.msp, "user")
+			if test.role != "" {
+				identity.WithAttribute(roleAttribute, string(test.role))
+			}
+			err := l.run(identity, nil, func(ctx contractapi.TransactionContextInterface) error {
+				return transactions[test.function](l.contract, ctx)
+			})
+			if notAuthorized(err, test.function) == test.allowed {
+				t.Fatalf("got %v, allowed %v", err, test.allowed)
+			}
+		})
+	}
+}
+
+func TestRolesAreRequiredByDefault(t *testing.T) {
+	const orgs = `"grantor_msp":"GrantorMSP","awardee_msp":"AwardeeMSP","subawardee_msp":"SubawardeeMSP","auditor_msp":"AuditorMSP"`
+	tests := []struct {
+		name    string
+		config  string
+		allowed bool
+	}{
+		{name: "default config"},
+		{name: "config without require_roles", config: `{` + orgs + `}`},
+		{name: "config with require_roles false", config: `{` + orgs + `,"require_roles":false}`, allowed: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			l := newLedger(t)
+			if test.config != "" {
+				l.must(l.run(grantor, nil, func(ctx contractapi.TransactionContextInterface) error {
+					_, err := l.contract.SetOrgConfig(ctx, test.config)
+					return err
+				}))
+			}
+
+			err := l.run(chaincodetest.NewIdentity(GrantorMSP, "gina"), nil, func(ctx contractapi.TransactionContextInterface) error {
+				return transactions["InitiateGrant"](l.contract, ctx)
+			})
+			if notAuthorized(err, "InitiateGrant") == test.allowed {
+				t.Fatalf("got %v, allowed %v", err, test.allowed)
+			}
+		})
+	}
+}
+
+func TestGrantOwnerRoles(t *testing.T) {
+	tests := []struct {
+		role    Role
+		allowed bool
+	}{
+		{role: RoleFinance, allowed: true},
+		{role: RoleAdmin, allowed: true},
+		{role: RoleProgramOfficer},
+		{role: ""},
+	}
+	for _, test := range tests {
+		t.Run(string(test.role), func(t *testing.T) {
+			l := newLedger(t)
+			l.activeGrant("G1")
+			_, err := l.reimburse(awardee, "G1", "P1", "AwardeeMSP/bob", item("Personnel", "100"))
+			l.must(err)
+
+			identity := chaincodetest.NewIdentity(GrantorMSP, "gina")
+			if test.role != "" {
+				identity.WithAttribute(roleAttribute, string(test.role))
+			}
+			err = l.run(identity, nil, func(ctx contractapi.TransactionContextInterface) error {
+				_, err := l.contract.AcceptReimbursement(ctx, "G1", "P1")
+				return err
+			})
+			if (err == nil) != test.allowed {
+				t.Fatalf("got %v, allowed %v", err, test.allowed)
+			}
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package chaincode
+
+import (
+	"reflect"
+	"strings"
+)
+
+// matchSelector evaluates a CouchDB Mango selector against a JSON document
+// decoded into interface{} values. It backs QueryGrants on LevelDB peers and
+// covers the combination operators $and, $or, $nor and $not and the condition
+// operators $eq, $ne, $gt, $gte, $lt, $lte, $in, $nin, $exists, $size and
+// $elemMatch. Any other operator is an error rather than a silent mismatch.
+func matchSelector(selector map[string]interface{}, doc interface{}) (bool, error) {
+	for field, condition := range selector {
+		var ok bool
+		var err error
+		switch field {
+		case "$and", "$or", "$nor":
+			ok, err = matchCombination(field, condition, doc)
+		case "$not":
+			sub, isObject := condition.(map[string]interface{})
+			if !isObject {
+				return false, errValidation("$not takes a selector object")
+			}
+			ok, err = matchSelector(sub, doc)
+			ok = !ok
+		default:
+			if strings.HasPrefix(field, "$") {
+				return false, errValidation("selector operator %s is not supported without CouchDB", field)
+			}
+			value, found := lookupField(doc, field)
+			ok, err = matchCondition(value, found, condition)
+		}
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func matchCombination(operator string, condition interface{}, doc interface{}) (bool, error) {
+	selectors, isArray := condition.([]interface{})
+	if !isArray {
+		return false, errValidation("%s takes an array of selectors", operator)
+	}
+
+	var matches int
+	for _, item := range selectors {
+		sub, isObject := item.(map[string]interface{})
+		if !isObject {
+			return false, errValidation("%s takes an array of selectors", operator)
+		}
+		ok, err := matchSelector(sub, doc)
+		if err != nil {
+			return false, err
+		}
+		if ok {
+			matches++
+		}
+	}
+
+	switch operator {
+	case "$and":
+		return matches == len(selectors), nil
+	case "$or":
+		return matches > 0, nil
+	default:
+		return matches == 0, nil
+	}
+}
+
+// matchCondition applies a field condition: an operator object, a nested
+// selector object or a literal the value has to equal.
+func matchCondition(value interface{}, found bool, condition interface{}) (bool, error) {
+	operators, isObject := condition.(map[string]interface{})
+	if !isObject || !hasOperators(operators) {
+		if !isObject {
+			return found && reflect.DeepEqual(value, condition), nil
+		}
+		// {"a": {"b": 1}} selects on a.b
+		if !found {
+			return false, nil
+		}
+		return matchSelector(operators, value)
+	}
+
+	for operator, operand := range operators {
+		ok, err := matchOperator(operator, value, found, operand)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func hasOperators(condition map[string]interface{}) bool {
+	for key := range condition {
+		if strings.HasPrefix(key, "$") {
+			return true
+		}
+	}
+	return false
+}
+
+func matchOperator(operator string, value interface{}, found bool, operand interface{}) (bool, error) {
+	switch operator {
+	case "$exists":
+		want, ok := operand.(bool)
+		if !ok {
+			return false, errValidation("$exists takes a boolean")
+		}
+		return found == want, nil
+	case "$eq":
+		return found && reflect.DeepEqual(value, operand), nil
+	case "$ne":
+		return !found || !reflect.DeepEqual(value, operand), nil
+	case "$gt", "$gte", "$lt", "$lte":
+		if !found {
+			return false, nil
+		}
+		order, comparable := compareValues(value, operand)
+		if !comparable {
+			return false, nil
+		}
+		switch operator {
+		case "$gt":
+			return order > 0, nil
+		case "$gte":
+			return order >= 0, nil
+		case "$lt":
+			return order < 0, nil
+		default:
+			return order <= 0, nil
+		}
+	case "$in", "$nin":
+		candidates, ok := operand.([]interface{})
+		if !ok {
+			return false, errValidation("%s takes an array", operator)
+		}
+		var in bool
+		for _, candidate := range candidates {
+			if found && reflect.DeepEqual(value, candidate) {
+				in = true
+				break
+			}
+		}
+		if operator == "$in" {
+			return in, nil
+		}
+		return !in, nil
+	case "$size":
+		size, ok := operand.(float64)
+		if !ok {
+			return false, errValidation("$size takes a number")
+		}
+		items, isArray := value.([]interface{})
+		return found && isArray && float64(len(items)) == size, nil
+	case "$elemMatch":
+		items, isArray := value.([]interface{})
+		if !found || !isArray {
+			return false, nil
+		}
+		for _, item := range items {
+			ok, err := matchCondition(item, true, operand)
+			if err != nil {
+				return false, err
+			}
+			if ok {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	return false, errValidation("selector operator %s is not supported without CouchDB", operator)
+}
+
+// compareValues orders two numbers or two strings.
+func compareValues(a interface{}, b interface{}) (int, bool) {
+	switch a := a.(type) {
+	case float64:
+		b, ok := b.(float64)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case a < b:
+			return -1, true
+		case a > b:
+			return 1, true
+		}
+		return 0, true
+	case string:
+		b, ok := b.(string)
+		if !ok {
+			return 0, false
+		}
+		return strings.Compare(a, b), true
+	}
+	return 0, false
+}
+
+// lookupField follows a dotted field path through nested objects.
+func lookupField(doc interface{}, path string) (interface{}, bool) {
+	value := doc
+	for _, name := range strings.Split(path, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		value, ok = object[name]
+		if !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
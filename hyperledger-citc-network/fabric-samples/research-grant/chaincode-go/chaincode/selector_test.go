@@ -0,0 +1,55 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestMatchSelector(t *testing.T) {
+	doc := map[string]interface{}{}
+	err := json.Unmarshal([]byte(`{
+		"status": "Active",
+		"sub": 20,
+		"currency": "USD",
+		"awardee": [{"id": "bob", "awardee_type": "Main"}, {"id": "carol", "awardee_type": "Sub"}],
+		"benefit": [{"benefit": "Personnel"}]
+	}`), &doc)
+	noError(t, err)
+
+	tests := []struct {
+		selector string
+		want     bool
+		wantErr  bool
+	}{
+		{selector: `{"status": "Active"}`, want: true},
+		{selector: `{"status": "Draft"}`},
+		{selector: `{"status": {"$ne": "Draft"}, "currency": "USD"}`, want: true},
+		{selector: `{"sub": {"$gt": 10, "$lte": 20}}`, want: true},
+		{selector: `{"sub": {"$lt": 20}}`},
+		{selector: `{"status": {"$in": ["Assigned", "Active"]}}`, want: true},
+		{selector: `{"status": {"$nin": ["Assigned", "Active"]}}`},
+		{selector: `{"notes": {"$exists": false}}`, want: true},
+		{selector: `{"awardee": {"$size": 2}}`, want: true},
+		{selector: `{"awardee": {"$elemMatch": {"id": "carol", "awardee_type": "Sub"}}}`, want: true},
+		{selector: `{"awardee": {"$elemMatch": {"id": "carol", "awardee_type": "Main"}}}`},
+		{selector: `{"$or": [{"status": "Draft"}, {"sub": 20}]}`, want: true},
+		{selector: `{"$and": [{"status": "Active"}, {"sub": 10}]}`},
+		{selector: `{"$nor": [{"status": "Draft"}, {"sub": 10}]}`, want: true},
+		{selector: `{"$not": {"status": "Active"}}`},
+		{selector: `{"status": {"$regex": "^Act"}}`, wantErr: true},
+		{selector: `{"$text": "Active"}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		var selector map[string]interface{}
+		noError(t, json.Unmarshal([]byte(tt.selector), &selector))
+
+		got, err := matchSelector(selector, doc)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("%s: got error %v, want error %v", tt.selector, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Fatalf("%s: got %v, want %v", tt.selector, got, tt.want)
+		}
+	}
+}
@@ -2,10 +2,9 @@ package chaincode
 
 import (
 	"encoding/json"
-	"encoding/base64"
 	"fmt"
-	"time"
 	"strings"
+	"time"
 	//"log"
 
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
@@ -13,9 +12,11 @@ import (
 
 // START CONSTANTS
 
+// Default MSP IDs of the orgs, used until SetOrgConfig stores others
 var GrantorMSP = "GrantorMSP"
 var AwardeeMSP = "AwardeeMSP"
 var SubawardeeMSP = "SubawardeeMSP"
+var AuditorMSP = "AuditorMSP"
 
 // END CONSTANTS
 
@@ -28,71 +29,101 @@ type SmartContract struct {
 // Grant describes details of research grant
 type Grant struct {
 	ID              string 		`json:"ID"`
-	Amount          float64 	`json:"amount"`
+	Amount          Money	 	`json:"amount"`
+	Amendments		[]Amendment	`json:"amendments,omitempty" metadata:"amendments,optional"`
+	Approval_Policy	*ApprovalPolicy	`json:"approval_policy,omitempty" metadata:"approval_policy,optional"`
 	Awardee         []Awardee   `json:"awardee"`
 	Benefit         []Benefit	`json:"benefit"`
-	Cashed_Out      float64     `json:"cashed_out"`
+	Budget_Version	int			`json:"budget_version,omitempty" metadata:"budget_version,optional"`
+	Cashed_Out      Money       `json:"cashed_out"`
+	Closeout_Days	int			`json:"closeout_days,omitempty" metadata:"closeout_days,optional"`
+	Created_At		string		`json:"created_at"`
+	Currency		string		`json:"currency"`
 	Description     string      `json:"description"`
+	Doc_Type		string		`json:"doc_type,omitempty" metadata:"doc_type,optional"`
 	End_Date		string	    `json:"end_date"`
+	Funder			[]Funder	`json:"funder,omitempty" metadata:"funder,optional"`
 	Grantor			string      `json:"grantor"`
 	Grantor_ID		string      `json:"grantor_id"`
 	Notes			string      `json:"notes"`
-	Paid_Amount		float64		`json:"paid_amount"`
-	Payment			[]Payment	`json:"payment"`
+	Paid_Amount		Money		`json:"paid_amount"`
+	Payment			[]Payment	`json:"payment,omitempty" metadata:"payment,optional"`
 	Payment_Type	string 		`json:"payment_type"`
 	Progress		[]Progress	`json:"progress"`
 	Progress_Freq	string 		`json:"progress_freq"`
+	Rebudget_Threshold	float64	`json:"rebudget_threshold,omitempty" metadata:"rebudget_threshold,optional"`
+	Redacted		bool		`json:"redacted,omitempty" metadata:"redacted,optional"`
 	Start_Date		string  	`json:"start_date"`
-	Status			string 		`json:"status"`
+	Status			GrantStatus	`json:"status"`
 	Sub 			float64	 	`json:"sub"`
+	Updated_At		string		`json:"updated_at"`
+	Updated_By		string		`json:"updated_by"`
 }
 
-// Awardee describes details of Awardee and Subawardee
+// Awardee describes details of Awardee and Subawardee. Account_Number and
+// Contact are only set on input, they are stored in a private data collection
+// and replaced by Private_Hash on the grant.
 type Awardee struct {
-	Account_Number  		string 		`json:"account_number"`
-	Contact         		string		`json:"contact"`
+	Account_Number  		string 		`json:"account_number,omitempty" metadata:"account_number,optional"`
+	Contact         		string		`json:"contact,omitempty" metadata:"contact,optional"`
 	ID						string		`json:"id"`
 	Name        			string		`json:"name"`
 	Principal_Investigator  string		`json:"principal_investigator"`
 	Organization			string		`json:"organization"`
 	Awardee_Type        	string		`json:"awardee_type"`
+	Private_Hash			string		`json:"private_hash,omitempty" metadata:"private_hash,optional"`
 }
 
 // Benefit describes details of availed benefits for the research
 type Benefit struct {
 	Benefit    string	`json:"benefit"`
-	Amount     float64	`json:"amount"`
+	Amount     Money	`json:"amount"`
 }
 
 // Payment describes details of payments
 type Payment struct {
 	ID              string 		`json:"ID"`
+	Grant_ID        string 		`json:"grant_id"`
+	Approvals		[]Approval	`json:"approvals,omitempty" metadata:"approvals,optional"`
 	Awardee_ID      string 	    `json:"awardee_id"`
 	Date			string      `json:"date"`
+	Doc_Type		string		`json:"doc_type,omitempty" metadata:"doc_type,optional"`
+	Funder_ID		string		`json:"funder_id,omitempty" metadata:"funder_id,optional"`
+	Incurred_Date	string		`json:"incurred_date,omitempty" metadata:"incurred_date,optional"`
 	Item         	[]Benefit   `json:"item"`
 	Notes			string      `json:"notes"`
-	Status			string 		`json:"status"`
-	Total			float64		`json:"total"`
+	Redacted		bool		`json:"redacted,omitempty" metadata:"redacted,optional"`
+	Rejection		*Rejection	`json:"rejection,omitempty" metadata:"rejection,optional"`
+	Status			PaymentStatus	`json:"status"`
+	Status_Date		string		`json:"status_date"`
+	Total			Money		`json:"total"`
+	Updated_By		string		`json:"updated_by"`
 }
 
 // Progress describes details of research developments
 type Progress struct {   
+	Date			string		`json:"date"`
 	Notes			string      `json:"notes"`
 	Percentage		string 		`json:"percentage"`
 }
 
 type AmountResponse struct {
-	Cashed_Out    		 float64	`json:"cashedOut"`
-	Requested_Amount     float64	`json:"requestedAmount"`
+	Cashed_Out    		 Money		`json:"cashedOut"`
+	Requested_Amount     Money		`json:"requestedAmount"`
 }
 
-type PaymentStatus struct {
+type GrantPayments struct {
 	Grant_ID		string		`json:"grant_id"`
 	Payment			[]Payment	`json:"payment"`
 }
 
 // InitLedger adds a base set of assets to the ledger
 func (s *SmartContract) InitLedger(ctx contractapi.TransactionContextInterface) error {
+	_, err := authorize(ctx, "InitLedger")
+	if err != nil {
+		return err
+	}
+
 	fmt.Println("Research grant ledger is initiated")
 
 	return nil
@@ -100,74 +131,82 @@ func (s *SmartContract) InitLedger(ctx contractapi.TransactionContextInterface)
 
 // Create a new Grant
 func (s *SmartContract) InitiateGrant(ctx contractapi.TransactionContextInterface) (bool, error) {
-	clientID, err := ctx.GetClientIdentity().GetID()
-	if err != nil {
-		return false, fmt.Errorf("failed getting the client's ID: %v", err)
-	}
-
-	data, err := base64.StdEncoding.DecodeString(clientID)
+	caller, err := authorize(ctx, "InitiateGrant")
 	if err != nil {
-		return false, fmt.Errorf("error: %v", err)
+		return false, err
 	}
-	userId := strings.Split(string(data), ",")[0][9:]
+	userId := caller.ID
 
-	clientMSPID, err:= ctx.GetClientIdentity().GetMSPID()
-	if err != nil {
-		return false, fmt.Errorf("failed getting the client's MSPID: %v", err)
-	}
-	if clientMSPID != GrantorMSP {
-		return false, fmt.Errorf("User from org %v is not authorized to initiate grant", clientMSPID)
-	}
+	clientMSPID := caller.MSPID
 
 	// Get new transaction definition details from transient map
 	transientMap, err := ctx.GetStub().GetTransient()
 	if err != nil {
-		return false, fmt.Errorf("error getting transient: %v", err)
+		return false, errInternal("error getting transient: %v", err)
 	}
 
 	// Private records get passed in transient field, instead of func args
-	transientGrantJSON, ok := transientMap["grant"]
-	if !ok {
-		//log error to stdout
-		return false, fmt.Errorf("grant not found in the transient map input")
-	}
-
 	var grant Grant
-
-	json.Unmarshal([]byte(transientGrantJSON), &grant)
+	err = readTransientInput(transientMap, "grant", &grant)
+	if err != nil {
+		return false, err
+	}
 
 	id := grant.ID
-	grant.Grantor = strings.Replace(clientMSPID, "MSP", "", 1)
+	grant.Grantor = orgName(clientMSPID)
 	grant.Grantor_ID = userId
-	grant.Status = "Not Assigned"
+	grant.Status = GrantDraft
 
-	var benefitAmount float64
-	for _, benefit := range grant.Benefit {
-		benefitAmount += benefit.Amount
+	if grant.Currency == "" {
+		grant.Currency = defaultCurrency
 	}
-
-	if benefitAmount != grant.Amount {
-		return false, fmt.Errorf("Total Benefit %.2f doesn't match with the Grant Amount %.2f", benefitAmount, grant.Amount)
+	err = checkBudget(&grant)
+	if err != nil {
+		return false, err
+	}
+	config, err := getOrgConfig(ctx)
+	if err != nil {
+		return false, err
+	}
+	err = checkFunders(&grant, config)
+	if err != nil {
+		return false, err
 	}
+	grant.Cashed_Out = moneyFromMinor(0, grant.Currency)
+	grant.Paid_Amount = moneyFromMinor(0, grant.Currency)
 
 	requestCompositeKey, _ := ctx.GetStub().CreateCompositeKey("grant", []string{id})
 	grantExists, err := ctx.GetStub().GetState(requestCompositeKey)
 	if err != nil {
-		return false, fmt.Errorf("failed to read from world state: %v", err)
+		return false, errInternal("failed to read from world state: %v", err)
 	}
 	if grantExists != nil {
-		return false, fmt.Errorf("the grant %s exists", id)
+		return false, errDuplicate("Grant %s already exists", id).with("grant_id", id)
 	}
 
-	grantJSON, err := json.Marshal(grant)
+	grant.Created_At, err = txTime(ctx)
 	if err != nil {
-		return false,  fmt.Errorf("error marshaling json: %v", err)
+		return false, err
 	}
 
-	err = ctx.GetStub().PutState(requestCompositeKey, grantJSON)
+	err = putGrant(ctx, &grant)
+	if err != nil {
+		return false, err
+	}
+
+	err = putGrantIndexes(ctx, &grant)
+	if err != nil {
+		return false, err
+	}
+
+	err = setGrantEndorsement(ctx, &grant, false)
+	if err != nil {
+		return false, err
+	}
 
+	err = emitGrantEvent(ctx, caller, EventGrantInitiated, &grant, "")
 	if err != nil {
-		return false, fmt.Errorf("failed to put transaction definition into ledger: %v", err)
+		return false, err
 	}
 	
 	return true, nil
@@ -175,483 +214,377 @@ func (s *SmartContract) InitiateGrant(ctx contractapi.TransactionContextInterfac
 
 // ReadGrant returns the grant stored in the world state with given id.
 func (s *SmartContract) ReadGrant(ctx contractapi.TransactionContextInterface, id string) (*Grant, error) {
+	caller, err := authorize(ctx, "ReadGrant")
+	if err != nil {
+		return nil, err
+	}
+
+	grant, access, err := readGrantAs(ctx, caller, id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = setPaymentTotals(ctx, grant)
+	if err != nil {
+		return nil, err
+	}
+
+	viewGrant(grant, access)
+	return grant, nil
+}
+
+// readGrant returns the stored grant without deriving its payment totals, so
+// transactions that only touch payment records don't read the payment range.
+func readGrant(ctx contractapi.TransactionContextInterface, id string) (*Grant, error) {
 	requestCompositeKey, _ := ctx.GetStub().CreateCompositeKey("grant", []string{id})
 	grantJSON, err := ctx.GetStub().GetState(requestCompositeKey)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read from world state: %v", err)
+		return nil, errInternal("failed to read from world state: %v", err)
 	}
 	if grantJSON == nil {
-		return nil, fmt.Errorf("the asset %s does not exist", id)
+		return nil, grantNotFound(id)
 	}
 
 	var grant Grant
 	err = json.Unmarshal(grantJSON, &grant)
 	if err != nil {
-		return nil, err
+		return nil, errInternal("failed to unmarshal JSON: %v", err)
 	}
 
 	return &grant, nil
 }
 
-// Assign grant to awardee - Grantor
-func (s *SmartContract) AssignGrant(ctx contractapi.TransactionContextInterface) (bool, error) {
-	clientID, err := ctx.GetClientIdentity().GetID()
+// putGrant stamps updated_at and updated_by with the transaction time and
+// submitter, and doc_type, and writes the grant to the world state.
+func putGrant(ctx contractapi.TransactionContextInterface, grant *Grant) error {
+	now, err := txTime(ctx)
 	if err != nil {
-		return false, fmt.Errorf("failed getting the client's ID: %v", err)
+		return err
 	}
-
-	data, err := base64.StdEncoding.DecodeString(clientID)
+	caller, err := getCaller(ctx)
 	if err != nil {
-		return false, fmt.Errorf("error: %v", err)
+		return err
 	}
-	userId := strings.Split(string(data), ",")[0][9:]
+	grant.Doc_Type = grantDocType
+	grant.Updated_At = now
+	grant.Updated_By = caller.ID
 
-	clientMSPID, err:= ctx.GetClientIdentity().GetMSPID()
+	grantJSON, err := json.Marshal(grant)
 	if err != nil {
-		return false, fmt.Errorf("failed getting the client's MSPID: %v", err)
+		return errInternal("failed to marshal grant into JSON: %v", err)
 	}
-	if clientMSPID != GrantorMSP {
-		return false, fmt.Errorf("User from org %v is not authorized to assign grant", clientMSPID)
+
+	requestCompositeKey, _ := ctx.GetStub().CreateCompositeKey("grant", []string{grant.ID})
+	err = ctx.GetStub().PutState(requestCompositeKey, grantJSON)
+	if err != nil {
+		return errInternal("failed to put grant into ledger: %v", err)
 	}
+	return nil
+}
 
-	type assignTransientInput struct {
-		Grant_ID		string		`json:"grant_id"`
-		Awardee         []Awardee   `json:"awardee"`
-		Status			string		`json:"status"`
+// Assign grant to awardee - Grantor
+func (s *SmartContract) AssignGrant(ctx contractapi.TransactionContextInterface) (bool, error) {
+	caller, err := authorize(ctx, "AssignGrant")
+	if err != nil {
+		return false, err
 	}
 
 	// Get new transaction definition details from transient map
 	transientMap, err := ctx.GetStub().GetTransient()
 	if err != nil {
-		return false, fmt.Errorf("error getting transient: %v", err)
+		return false, errInternal("error getting transient: %v", err)
 	}
 
 	// Private records get passed in transient field, instead of func args
-	transientGrantJSON, ok := transientMap["assign_grant"]
-	if !ok {
-		//log error to stdout
-		return false, fmt.Errorf("assign_grant not found in the transient map input")
-	}
-
 	var assignGrantInput assignTransientInput
+	err = readTransientInput(transientMap, "assign_grant", &assignGrantInput)
+	if err != nil {
+		return false, err
+	}
 
-	err = json.Unmarshal(transientGrantJSON, &assignGrantInput)
+	salt, err := transientSalt(transientMap)
 	if err != nil {
-		return false, fmt.Errorf("failed to unmarshal JSON: %v", err)
+		return false, err
 	}
 
-	assignGrantInput.Status = "Pending"
-	for i := 0; i < len(assignGrantInput.Awardee); i++ {
-        if len(assignGrantInput.Awardee[i].Name) == 0 {
-			return false, fmt.Errorf("Name field must be a non-empty string")
-		}
-		if len(assignGrantInput.Awardee[i].ID) == 0 {
-			return false, fmt.Errorf("ID field must be a non-empty string")
-		}
-		if len(assignGrantInput.Awardee[i].Contact) == 0 {
-			return false, fmt.Errorf("Contact field must be a non-empty string")
-		}
-		if len(assignGrantInput.Awardee[i].Principal_Investigator) == 0 {
-			return false, fmt.Errorf("Principal_Investigator field must be a non-empty string")
-		}
-		if len(assignGrantInput.Awardee[i].Account_Number) == 0 {
-			return false, fmt.Errorf("Account_Number field must be a non-empty string")
-		}
-		if len(assignGrantInput.Awardee[i].Organization) == 0 {
-			return false, fmt.Errorf("Organization field must be a non-empty string")
-		}
+	config, err := getOrgConfig(ctx)
+	if err != nil {
+		return false, err
+	}
+	seen := map[string]bool{}
+	for i := range assignGrantInput.Awardee {
 		assignGrantInput.Awardee[i].Organization = strings.Title(strings.ToLower(strings.Replace(assignGrantInput.Awardee[i].Organization, "MSP", "", 1)))
-		if len(assignGrantInput.Awardee[i].Awardee_Type) == 0 {
-			return false, fmt.Errorf("Awardee_Type field must be a non-empty string")
+		err = qualifyAwardee(&assignGrantInput.Awardee[i], config)
+		if err != nil {
+			return false, err
+		}
+		if seen[assignGrantInput.Awardee[i].ID] {
+			return false, errDuplicate("Awardee %s is listed more than once", assignGrantInput.Awardee[i].ID).with("awardee_id", assignGrantInput.Awardee[i].ID)
 		}
-    }
+		seen[assignGrantInput.Awardee[i].ID] = true
+	}
 
 
-	grant, err := s.ReadGrant(ctx, assignGrantInput.Grant_ID)
+	grant, err := readGrant(ctx, assignGrantInput.Grant_ID)
 	if err != nil {
-		return false, fmt.Errorf("Grant %s does not exist", assignGrantInput.Grant_ID)
+		return false, err
 	}
 
-	if grant.Status != "Not Assigned" {
-		return false, fmt.Errorf("Grant %s is in %s status", grant.ID, grant.Status)	
+	err = checkGrantOwner(caller, grant, "assign")
+	if err != nil {
+		return false, err
 	}
 
-	if grant.Grantor_ID != userId {
-		return false, fmt.Errorf("User %s from org %v is not authorized to assign grant for this grant %s",userId, clientMSPID, grant.ID)
+	previous := grant.Status
+	err = transitionGrant(grant, GrantAssigned)
+	if err != nil {
+		return false, err
 	}
-
-	assignGrant := Grant{
-		ID:             grant.ID,
-		Amount:         grant.Amount,
-		Awardee:        assignGrantInput.Awardee,
-		Benefit:        grant.Benefit,
-		Cashed_Out:     grant.Cashed_Out,
-		Description:    grant.Description,
-		End_Date:		grant.End_Date,
-		Grantor:		grant.Grantor,
-		Grantor_ID:		grant.Grantor_ID,
-		Notes:			grant.Notes,
-		Paid_Amount:	grant.Paid_Amount,
-		Payment:		grant.Payment,
-		Payment_Type:	grant.Payment_Type,
-		Progress:		grant.Progress,
-		Progress_Freq:	grant.Progress_Freq,
-		Start_Date:		grant.Start_Date,
-		Status:			assignGrantInput.Status,
-		Sub: 			grant.Sub,
+	for i := range assignGrantInput.Awardee {
+		err = putAwardeePrivateDetails(ctx, grant.ID, &assignGrantInput.Awardee[i], salt)
+		if err != nil {
+			return false, err
+		}
 	}
-
-	grantJSONasBytes, err := json.Marshal(assignGrant)
+	err = deleteGrantIndexes(ctx, grant)
 	if err != nil {
-		return false, fmt.Errorf("failed to marshal grant into JSON: %v", err)
+		return false, err
 	}
+	grant.Awardee = assignGrantInput.Awardee
 
-	requestCompositeKey, _ := ctx.GetStub().CreateCompositeKey("grant", []string{grant.ID})
-	err = ctx.GetStub().PutState(requestCompositeKey, grantJSONasBytes)
-
+	err = putGrant(ctx, grant)
 	if err != nil {
-		return false, fmt.Errorf("failed to put transaction definition into ledger: %v", err)
+		return false, err
 	}
-	return true, nil
-
-}
 
-// Awardee accept grant
-func (s *SmartContract) AcceptGrant(ctx contractapi.TransactionContextInterface, id string) (bool, error) {
-	clientID, err := ctx.GetClientIdentity().GetID()
+	err = putGrantIndexes(ctx, grant)
 	if err != nil {
-		return false, fmt.Errorf("failed getting the client's ID: %v", err)
+		return false, err
 	}
 
-	data, err := base64.StdEncoding.DecodeString(clientID)
+	err = setGrantEndorsement(ctx, grant, false)
 	if err != nil {
-		return false, fmt.Errorf("error: %v", err)
+		return false, err
 	}
-	userId := strings.Split(string(data), ",")[0][9:]
 
-	clientMSPID, err:= ctx.GetClientIdentity().GetMSPID()
+	err = emitGrantEvent(ctx, caller, EventGrantAssigned, grant, previous)
 	if err != nil {
-		return false, fmt.Errorf("failed getting the client's MSPID: %v", err)
-	}
-	if clientMSPID != AwardeeMSP {
-		return false, fmt.Errorf("User from org %v is not authorized to accept grant", clientMSPID)
+		return false, err
 	}
+	return true, nil
 
-	requestCompositeKey, _ := ctx.GetStub().CreateCompositeKey("grant", []string{id})
-	grant, err := s.ReadGrant(ctx, id)
-	if err != nil {
-		return false, fmt.Errorf("Grant %s does not exist", id)
-	}
+}
 
-	if grant.Status == "Revoked" {
-		return false, fmt.Errorf("Grant %s is revoked", grant.ID)	
+// Awardee accept grant
+func (s *SmartContract) AcceptGrant(ctx contractapi.TransactionContextInterface, id string) (bool, error) {
+	caller, err := authorize(ctx, "AcceptGrant")
+	if err != nil {
+		return false, err
 	}
+	userId := caller.ID
 
-	if grant.Status != "Pending" {
-		return false, fmt.Errorf("Grant %s is in %s status. It should be in assigned to an awardee", grant.ID, grant.Status)	
+	grant, err := readGrant(ctx, id)
+	if err != nil {
+		return false, err
 	}
 
-	
 	if !checkAwardee(grant.Awardee, userId) {
-		return false, fmt.Errorf("Awardee %s is not assigned in the Grant %s", userId, grant.ID)	
+		return false, awardeeNotAssigned(userId, grant)	
 	}
 
 	if checkSubAwardee(grant.Awardee, userId) {
-		return false, fmt.Errorf("Awardee %s is not allowed to accept this Grant %s", userId, grant.ID)	
+		return false, errForbidden("Awardee %s is not allowed to accept this Grant %s", userId, grant.ID).with("grant_id", grant.ID)	
 	}
 
-	approveGrant := Grant{
-		ID:             grant.ID,
-		Amount:         grant.Amount,
-		Awardee:        grant.Awardee,
-		Benefit:        grant.Benefit,
-		Cashed_Out:     grant.Cashed_Out,
-		Description:    grant.Description,
-		End_Date:		grant.End_Date,
-		Grantor:		grant.Grantor,
-		Grantor_ID:		grant.Grantor_ID,
-		Notes:			grant.Notes,
-		Paid_Amount:	grant.Paid_Amount,
-		Payment:		grant.Payment,
-		Payment_Type:	grant.Payment_Type,
-		Progress:		grant.Progress,
-		Progress_Freq:	grant.Progress_Freq,
-		Start_Date:		grant.Start_Date,
-		Status:			"Approved",
-		Sub: 			grant.Sub,
+	previous := grant.Status
+	err = transitionGrant(grant, GrantActive)
+	if err != nil {
+		return false, err
 	}
 
-	grantJSON, err := json.Marshal(approveGrant)
+	err = putGrant(ctx, grant)
 	if err != nil {
 		return false, err
 	}
 
-	err = ctx.GetStub().PutState(requestCompositeKey, grantJSON)
-
+	err = emitGrantEvent(ctx, caller, EventGrantAccepted, grant, previous)
 	if err != nil {
-		return false, fmt.Errorf("failed to put transaction definition into ledger: %v", err)
+		return false, err
 	}
 	return true, nil
 }
 
 // Awardee reject grant
 func (s *SmartContract) RejectGrant(ctx contractapi.TransactionContextInterface, id string) (bool, error) {
-	clientID, err := ctx.GetClientIdentity().GetID()
-	if err != nil {
-		return false, fmt.Errorf("failed getting the client's ID: %v", err)
-	}
-
-	data, err := base64.StdEncoding.DecodeString(clientID)
+	caller, err := authorize(ctx, "RejectGrant")
 	if err != nil {
-		return false, fmt.Errorf("error: %v", err)
+		return false, err
 	}
-	userId := strings.Split(string(data), ",")[0][9:]
+	userId := caller.ID
 
-	clientMSPID, err:= ctx.GetClientIdentity().GetMSPID()
-	if err != nil {
-		return false, fmt.Errorf("failed getting the client's MSPID: %v", err)
-	}
-	if clientMSPID != AwardeeMSP {
-		return false, fmt.Errorf("User from org %v is not authorized to reject grant", clientMSPID)
-	}
-	requestCompositeKey, _ := ctx.GetStub().CreateCompositeKey("grant", []string{id})
-	grant, err := s.ReadGrant(ctx, id)
+	grant, err := readGrant(ctx, id)
 	if err != nil {
-		return false, fmt.Errorf("Grant %s does not exist", id)
-	}
-
-	if grant.Status == "Revoked" {
-		return false, fmt.Errorf("Grant %s is revoked", grant.ID)	
+		return false, err
 	}
 
 	if !checkAwardee(grant.Awardee, userId) {
-		return false, fmt.Errorf("Awardee %s is not assigned in the Grant %s", userId, grant.ID)	
-	}
-
-	if grant.Status != "Pending" {
-		return false, fmt.Errorf("Grant %s is not in Pending status", grant.ID)	
+		return false, awardeeNotAssigned(userId, grant)	
 	}
 
-
-	rejectGrant := Grant{
-		ID:             grant.ID,
-		Amount:         grant.Amount,
-		Awardee:        grant.Awardee,
-		Benefit:        grant.Benefit,
-		Cashed_Out:     grant.Cashed_Out,
-		Description:    grant.Description,
-		End_Date:		grant.End_Date,
-		Grantor:		grant.Grantor,
-		Grantor_ID:		grant.Grantor_ID,
-		Notes:			grant.Notes,
-		Paid_Amount:	grant.Paid_Amount,
-		Payment:		grant.Payment,
-		Payment_Type:	grant.Payment_Type,
-		Progress:		grant.Progress,
-		Progress_Freq:	grant.Progress_Freq,
-		Start_Date:		grant.Start_Date,
-		Status:			"Rejected",
-		Sub: 			grant.Sub,
+	previous := grant.Status
+	err = transitionGrant(grant, GrantRejected)
+	if err != nil {
+		return false, err
 	}
 
-	grantJSON, err := json.Marshal(rejectGrant)
+	err = putGrant(ctx, grant)
 	if err != nil {
 		return false, err
 	}
 
-	err = ctx.GetStub().PutState(requestCompositeKey, grantJSON)
-
+	err = emitGrantEvent(ctx, caller, EventGrantRejected, grant, previous)
 	if err != nil {
-		return false, fmt.Errorf("failed to put transaction definition into ledger: %v", err)
+		return false, err
 	}
 	return true, nil
 }
 
 // Awardee reject grant
 func (s *SmartContract) RevokeGrant(ctx contractapi.TransactionContextInterface, id string) (bool, error) {
-	clientID, err := ctx.GetClientIdentity().GetID()
+	caller, err := authorize(ctx, "RevokeGrant")
 	if err != nil {
-		return false, fmt.Errorf("failed getting the client's ID: %v", err)
+		return false, err
 	}
 
-	data, err := base64.StdEncoding.DecodeString(clientID)
+	grant, err := readGrant(ctx, id)
 	if err != nil {
-		return false, fmt.Errorf("error: %v", err)
+		return false, err
 	}
-	userId := strings.Split(string(data), ",")[0][9:]
 
-	clientMSPID, err:= ctx.GetClientIdentity().GetMSPID()
-	if err != nil {
-		return false, fmt.Errorf("failed getting the client's MSPID: %v", err)
-	}
-	if clientMSPID != GrantorMSP {
-		return false, fmt.Errorf("User from org %v is not authorized to revoke grant", clientMSPID)
-	}
-	requestCompositeKey, _ := ctx.GetStub().CreateCompositeKey("grant", []string{id})
-	grant, err := s.ReadGrant(ctx, id)
+	err = checkGrantOwner(caller, grant, "revoke")
 	if err != nil {
-		return false, fmt.Errorf("Grant %s does not exist", id)
-	}
-
-	if grant.Grantor_ID != userId {
-		return false, fmt.Errorf("Grantor %s is not allowed to revoke the Grant %s", userId, grant.ID)	
+		return false, err
 	}
 
-	revokeGrant := Grant{
-		ID:             grant.ID,
-		Amount:         grant.Amount,
-		Awardee:        grant.Awardee,
-		Benefit:        grant.Benefit,
-		Cashed_Out:     grant.Cashed_Out,
-		Description:    grant.Description,
-		End_Date:		grant.End_Date,
-		Grantor:		grant.Grantor,
-		Grantor_ID:		grant.Grantor_ID,
-		Notes:			grant.Notes,
-		Paid_Amount:	grant.Paid_Amount,
-		Payment:		grant.Payment,
-		Payment_Type:	grant.Payment_Type,
-		Progress:		grant.Progress,
-		Progress_Freq:	grant.Progress_Freq,
-		Start_Date:		grant.Start_Date,
-		Status:			"Revoked",
-		Sub: 			grant.Sub,
+	previous := grant.Status
+	err = transitionGrant(grant, GrantRevoked)
+	if err != nil {
+		return false, err
 	}
 
-	grantJSON, err := json.Marshal(revokeGrant)
+	err = putGrant(ctx, grant)
 	if err != nil {
 		return false, err
 	}
 
-	err = ctx.GetStub().PutState(requestCompositeKey, grantJSON)
-
+	err = emitGrantEvent(ctx, caller, EventGrantRevoked, grant, previous)
 	if err != nil {
-		return false, fmt.Errorf("failed to put transaction definition into ledger: %v", err)
+		return false, err
 	}
 	return true, nil
 }
 
 // Update Grant
 func (s *SmartContract) UpdateGrant(ctx contractapi.TransactionContextInterface) (bool, error) {
-	clientID, err := ctx.GetClientIdentity().GetID()
-	if err != nil {
-		return false, fmt.Errorf("failed getting the client's ID: %v", err)
-	}
-
-	data, err := base64.StdEncoding.DecodeString(clientID)
+	caller, err := authorize(ctx, "UpdateGrant")
 	if err != nil {
-		return false, fmt.Errorf("error: %v", err)
+		return false, err
 	}
-	userId := strings.Split(string(data), ",")[0][9:]
 
-	clientMSPID, err:= ctx.GetClientIdentity().GetMSPID()
-	if err != nil {
-		return false, fmt.Errorf("failed getting the client's MSPID: %v", err)
-	}
-	if clientMSPID != GrantorMSP {
-		return false, fmt.Errorf("User from org %v is not authorized to update grant", clientMSPID)
-	}
 	// Get new transaction definition details from transient map
 	transientMap, err := ctx.GetStub().GetTransient()
 	if err != nil {
-		return false, fmt.Errorf("error getting transient: %v", err)
+		return false, errInternal("error getting transient: %v", err)
 	}
 
 	// Private records get passed in transient field, instead of func args
-	transientGrantJSON, ok := transientMap["update_grant"]
-	if !ok {
-		//log error to stdout
-		return false, fmt.Errorf("grant not found in the transient map input")
+	var updateInput grantUpdateTransientInput
+	err = readTransientInput(transientMap, "update_grant", &updateInput)
+	if err != nil {
+		return false, err
 	}
-
-	var updatedGrant Grant
-
-	json.Unmarshal([]byte(transientGrantJSON), &updatedGrant)
+	updatedGrant := Grant(updateInput)
 
 	id := updatedGrant.ID
 
-	grant, err := s.ReadGrant(ctx, id)
+	grant, err := readGrant(ctx, id)
 	if err != nil {
 		return false, err
 	}
-	if grant == nil {
-		return false, fmt.Errorf("the grant %s does not exist", id)
-	}
 
-	if grant.Status == "Revoked" {
-		return false, fmt.Errorf("Grant %s is revoked", updatedGrant.ID)	
+	// Once accepted, the budget only changes by amendment
+	if grant.Status == GrantActive || grant.Status == GrantSuspended {
+		return false, errInvalidState("Grant %s was accepted, its budget can only change by amendment with ProposeAmendment", grant.ID).
+			with("grant_id", grant.ID).
+			with("status", grant.Status).
+			with("function", "ProposeAmendment")
 	}
-
-	if grant.Grantor_ID != userId {
-		return false, fmt.Errorf("Grantor %s is not allowed to update the Grant %s", userId, updatedGrant.ID)	
+	err = checkGrantStatus(grant, GrantDraft, GrantAssigned)
+	if err != nil {
+		return false, err
 	}
 
-	var benefitAmount float64
-	for _, benefit := range updatedGrant.Benefit {
-		benefitAmount += benefit.Amount
+	err = checkGrantOwner(caller, grant, "update")
+	if err != nil {
+		return false, err
 	}
 
-	if benefitAmount != updatedGrant.Amount {
-		return false, fmt.Errorf("Total Benefit %.2f doesn't match with the Grant Amount %.2f", benefitAmount, updatedGrant.Amount)
+	err = updatePeriod(grant, &updatedGrant)
+	if err != nil {
+		return false, err
 	}
 
-	updateGrant := Grant{
-		ID:             grant.ID,
-		Amount:         updatedGrant.Amount,
-		Awardee:        grant.Awardee,
-		Benefit:        updatedGrant.Benefit,
-		Cashed_Out:     grant.Cashed_Out,
-		Description:    grant.Description,
-		End_Date:		grant.End_Date,
-		Grantor:		grant.Grantor,
-		Grantor_ID:		grant.Grantor_ID,
-		Notes:			grant.Notes,
-		Paid_Amount:	grant.Paid_Amount,
-		Payment:		grant.Payment,
-		Payment_Type:	grant.Payment_Type,
-		Progress:		grant.Progress,
-		Progress_Freq:	grant.Progress_Freq,
-		Start_Date:		grant.Start_Date,
-		Status:			grant.Status,
-		Sub: 			grant.Sub,
+	budget, err := checkNewBudget(ctx, grant, &updatedGrant)
+	if err != nil {
+		return false, err
 	}
 
-	requestCompositeKey, _ := ctx.GetStub().CreateCompositeKey("grant", []string{id})
-
-	grantJSON, err := json.Marshal(updateGrant)
+	err = putGrantBudget(ctx, grant, budget)
 	if err != nil {
-		return false,  fmt.Errorf("error marshaling json: %v", err)
+		return false, err
 	}
 
-	err = ctx.GetStub().PutState(requestCompositeKey, grantJSON)
-
+	err = emitGrantEvent(ctx, caller, EventGrantUpdated, grant, grant.Status)
 	if err != nil {
-		return false, fmt.Errorf("failed to put grant into ledger: %v", err)
+		return false, err
 	}
 	return true, nil
 }
 
-// GetAllGrants returns all grants found in world state
+// GetAllGrants returns all grants found in world state that the user may read
 func (s *SmartContract) GetAllGrants(ctx contractapi.TransactionContextInterface) ([]*Grant, error) {
-	//requestPartialCompositeKey, _ := ctx.GetStub().CreateCompositeKey("grant", []string{id})
-	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey("grant", []string{})
+	caller, err := authorize(ctx, "GetAllGrants")
 	if err != nil {
 		return nil, err
 	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey("grant", []string{})
+	if err != nil {
+		return nil, errInternal("failed to query the world state: %v", err)
+	}
 	defer resultsIterator.Close()
 
 	var grants []*Grant
 	for resultsIterator.HasNext() {
 		queryResponse, err := resultsIterator.Next()
 		if err != nil {
-			return nil, err
+			return nil, errInternal("failed to read from world state: %v", err)
 		}
 
 		var grant Grant
 		err = json.Unmarshal(queryResponse.Value, &grant)
+		if err != nil {
+			return nil, errInternal("failed to unmarshal JSON: %v", err)
+		}
+		access := grantAccessOf(caller, &grant)
+		if access == accessNone {
+			continue
+		}
+		err = setPaymentTotals(ctx, &grant)
 		if err != nil {
 			return nil, err
 		}
+		viewGrant(&grant, access)
 		grants = append(grants, &grant)
 	}
 
@@ -661,85 +594,84 @@ func (s *SmartContract) GetAllGrants(ctx contractapi.TransactionContextInterface
 
 // Request Reimbursement by awardee
 func (s *SmartContract) RequestReimbursement(ctx contractapi.TransactionContextInterface) (string, error) {
-	clientID, err := ctx.GetClientIdentity().GetID()
+	caller, err := authorize(ctx, "RequestReimbursement")
 	if err != nil {
-		return "", fmt.Errorf("failed getting the client's ID: %v", err)
-	}
-
-	data, err := base64.StdEncoding.DecodeString(clientID)
-	if err != nil {
-		return "", fmt.Errorf("error: %v", err)
+		return "", err
 	}
-	userId := strings.Split(string(data), ",")[0][9:]
+	userId := caller.ID
 
-	clientMSPID, err:= ctx.GetClientIdentity().GetMSPID()
+	formattedTime, err := txTime(ctx)
 	if err != nil {
-		return "", fmt.Errorf("failed getting the client's MSPID: %v", err)
+		return "", err
 	}
-	
 
-	dt := time.Now()
-	formattedTime := dt.Format("01-02-2006 15:04:05")
-	type reimbursementTransientInput struct {
-		ID				string		`json:"ID"`
-		Grant_ID		string		`json:"grant_id"`
-		Awardee_ID      string   	`json:"awardee_id"`
-		Date		    string   	`json:"date"`
-		Notes			string		`json:"notes"`
-		Item			[]Benefit	`json:"item"`
-	}
 	// Get new transaction definition details from transient map
 	transientMap, err := ctx.GetStub().GetTransient()
 	if err != nil {
-		return "", fmt.Errorf("error getting transient: %v", err)
+		return "", errInternal("error getting transient: %v", err)
 	}
 
 	// Private records get passed in transient field, instead of func args
-	transientReimbursementJSON, ok := transientMap["request_reimbursement"]
-	if !ok {
-		//log error to stdout
-		return "", fmt.Errorf("grant not found in the transient map input")
-	}
-
 	var reimbursementInput reimbursementTransientInput
-
-	err = json.Unmarshal(transientReimbursementJSON, &reimbursementInput)
+	err = readTransientInput(transientMap, "request_reimbursement", &reimbursementInput)
 	if err != nil {
-		return "", fmt.Errorf("failed to unmarshal JSON: %v", err)
+		return "", err
 	}
 
 	id := reimbursementInput.Grant_ID
 
-	grant, err := s.ReadGrant(ctx, id)
+	grant, err := readGrant(ctx, id)
 	if err != nil {
 		return "", err
 	}
-	if grant == nil {
-		return "", fmt.Errorf("the asset %s does not exist", id)
+
+	reimbursementInput.Awardee_ID, err = grantAwardeeID(grant, reimbursementInput.Awardee_ID)
+	if err != nil {
+		return "", err
 	}
-	if grant.Status == "Revoked" {
-		return "", fmt.Errorf("Grant %s is revoked", grant.ID)	
+	if !checkAwardee(grant.Awardee, reimbursementInput.Awardee_ID) && !checkSubAwardee(grant.Awardee, reimbursementInput.Awardee_ID) {
+		return "", awardeeNotAssigned(reimbursementInput.Awardee_ID, grant)	
 	}
 
-	if !checkAwardee(grant.Awardee, reimbursementInput.Awardee_ID) && !checkSubAwardee(grant.Awardee, reimbursementInput.Awardee_ID) {
-		return "", fmt.Errorf("Awardee %s is not assigned in the Grant %s", reimbursementInput.Awardee_ID, grant.ID)	
+	err = checkGrantStatus(grant, GrantActive)
+	if err != nil {
+		return "", err
 	}
 
-	if grant.Status != "Approved" {
-		return "", fmt.Errorf("Grant %s is not approved by the Awardee %s", grant.ID, userId)	
+	// Costs are taken as incurred on the day of the request unless dated
+	now, err := txTimestamp(ctx)
+	if err != nil {
+		return "", err
+	}
+	incurred := now
+	if reimbursementInput.Date != "" {
+		incurred, err = parseDate(reimbursementInput.Date)
+		if err != nil {
+			return "", errValidation("invalid date: %v", err).with("date", reimbursementInput.Date)
+		}
+	}
+	err = checkReimbursementPeriod(grant, now, incurred)
+	if err != nil {
+		return "", err
 	}
 	
-	if clientMSPID != AwardeeMSP && clientMSPID != SubawardeeMSP {
-
-		return "", fmt.Errorf("User from org %v is not authorized to request reimbursement", clientMSPID)
+	err = checkPaymentsMigrated(grant)
+	if err != nil {
+		return "", err
 	}
 
-	if checkPayment(grant.Payment, reimbursementInput.ID) {
-		return "", fmt.Errorf("Payment with ID %s already exists in the Grant %s", reimbursementInput.ID, grant.ID)	
+	exists, err := paymentExists(ctx, grant.ID, reimbursementInput.ID)
+	if err != nil {
+		return "", err
+	}
+	if exists {
+		return "", errDuplicate("Payment with ID %s already exists in the Grant %s", reimbursementInput.ID, grant.ID).
+			with("grant_id", grant.ID).
+			with("payment_id", reimbursementInput.ID)	
 	}
 
 	if reimbursementInput.Awardee_ID != userId {
-		return "", fmt.Errorf("User %s is not allowed to request reimbursement for %s", userId, reimbursementInput.Awardee_ID)
+		return "", errForbidden("User %s is not allowed to request reimbursement for %s", userId, reimbursementInput.Awardee_ID).with("awardee_id", reimbursementInput.Awardee_ID)
 	}
 	
 	var flag bool
@@ -751,14 +683,23 @@ func (s *SmartContract) RequestReimbursement(ctx contractapi.TransactionContextI
 		} 
 
 	if !flag {
-		return "", fmt.Errorf("the awardee %s does not exist in the Grant %s", reimbursementInput.Awardee_ID, id)
+		return "", errNotFound("the awardee %s does not exist in the Grant %s", reimbursementInput.Awardee_ID, id).with("grant_id", id).with("awardee_id", reimbursementInput.Awardee_ID)
 	}
 
-	var paid_amount float64
-	var itemMap  = make(map[string]float64)
-	for _, item := range reimbursementInput.Item {
-		itemMap[item.Benefit] = item.Amount
-		paid_amount += item.Amount
+	currency := grantCurrency(grant)
+
+	var paid_amount int64
+	var itemMap  = make(map[string]int64)
+	var requested []benefitRequest
+	for i, item := range reimbursementInput.Item {
+		amount, err := item.Amount.minor(currency)
+		if err != nil {
+			return "", invalidAmount(err, "invalid amount for %s benefit", item.Benefit)
+		}
+		reimbursementInput.Item[i].Amount = moneyFromMinor(amount, currency)
+		itemMap[item.Benefit] = amount
+		requested = append(requested, benefitRequest{benefit: item.Benefit, amount: amount})
+		paid_amount += amount
 	}
 
 	var awardee_type string
@@ -768,40 +709,61 @@ func (s *SmartContract) RequestReimbursement(ctx contractapi.TransactionContextI
 		}
 	}
 
-	var benefitMap  = make(map[string]float64)
+	var benefitMap  = make(map[string]int64)
 	for _, benefit := range grant.Benefit {
-		benefitMap[benefit.Benefit] = benefit.Amount
+		amount, err := benefit.Amount.minor(currency)
+		if err != nil {
+			return "", storedAmountInvalid(err, "Grant %s has an invalid amount for %s benefit", grant.ID, benefit.Benefit)
+		}
+		benefitMap[benefit.Benefit] = amount
 	}
 	fmt.Println(benefitMap)
 
-	var benefitAmountMapMain  = make(map[string]float64)
-	var benefitAmountMapSub  = make(map[string]float64)
+	var benefitAmountMapMain  = make(map[string]int64)
+	var benefitAmountMapSub  = make(map[string]int64)
 	for _, benefit := range grant.Benefit {
-		benefitAmountMapMain[benefit.Benefit] = 0.0
-		benefitAmountMapSub[benefit.Benefit] = 0.0
+		benefitAmountMapMain[benefit.Benefit] = 0
+		benefitAmountMapSub[benefit.Benefit] = 0
+	}
+
+	payments, err := getGrantPayments(ctx, grant.ID)
+	if err != nil {
+		return "", err
 	}
 
-	var payment_amount float64
-	for _, payment := range grant.Payment {
-		if payment.Status != "Requested" && payment.Status != "Accepted" && payment.Status != "Pending-redeem" && payment.Status != "Accept_redeem" {
+	var payment_amount int64
+	for _, payment := range payments {
+		if !payment.Status.committed() {
 			continue
 		}
 		for _, item := range payment.Item {
+			amount, err := item.Amount.minor(currency)
+			if err != nil {
+				return "", storedAmountInvalid(err, "Payment %s has an invalid amount", payment.ID)
+			}
 			if checkAwardee(grant.Awardee, payment.Awardee_ID) {
-				benefitAmountMapMain[item.Benefit] = benefitAmountMapMain[item.Benefit] + item.Amount
+				benefitAmountMapMain[item.Benefit] = benefitAmountMapMain[item.Benefit] + amount
 			} else {
-				benefitAmountMapSub[item.Benefit] = benefitAmountMapSub[item.Benefit] + item.Amount
+				benefitAmountMapSub[item.Benefit] = benefitAmountMapSub[item.Benefit] + amount
 			}
 			
 		}
-		payment_amount += payment.Total
+		total, err := payment.Total.minor(currency)
+		if err != nil {
+			return "", storedAmountInvalid(err, "Payment %s has an invalid amount", payment.ID)
+		}
+		payment_amount += total
 	}
 
+	grantAmount, err := grant.Amount.minor(currency)
+	if err != nil {
+		return "", storedAmountInvalid(err, "Grant %s has an invalid amount", grant.ID)
+	}
 
 	flag = false
-	var message string
-	var totalBenefitAmount float64
-	var totalBenefitAmountForItem float64
+	var budgetErr *ContractError
+	var totalBenefitAmount int64
+	var totalBenefitAmountForItem int64
 	for key, value := range itemMap {
 		totalBenefitAmount += value	
 		totalBenefitAmountForItem = benefitAmountMapMain[key] + benefitAmountMapSub[key] + value	
@@ -809,1252 +771,814 @@ func (s *SmartContract) RequestReimbursement(ctx contractapi.TransactionContextI
 			benefitAmountMapMain[key] = benefitAmountMapMain[key] + value 
 			flag = checkBenefitAmount(totalBenefitAmountForItem, benefitMap[key], benefitAmountMapMain[key], "Main", 100.00)
 			if !flag{
-				balanceAmount := 0.0
+				var balanceAmount int64
 				if benefitMap[key]-totalBenefitAmountForItem+value < 0 {
 					balanceAmount = 0
 				}else {
 					balanceAmount = benefitMap[key]-totalBenefitAmountForItem+value
 				}
-				message = fmt.Sprintf("Requested value of %.2f exceeds the allocated amount of %.2f. Remaining amount available for %s benefit is %.2f", value, benefitMap[key], key, balanceAmount)
+				budgetErr = errBudgetExceeded("Requested value of %s exceeds the allocated amount of %s. Remaining amount available for %s benefit is %s", moneyFromMinor(value, currency), moneyFromMinor(benefitMap[key], currency), key, moneyFromMinor(balanceAmount, currency)).
+					with("benefit", key).
+					with("requested", moneyFromMinor(value, currency)).
+					with("allocated", moneyFromMinor(benefitMap[key], currency)).
+					with("remaining", moneyFromMinor(balanceAmount, currency))
 				break
 			}
 			} else if awardee_type == "Sub" {
 			benefitAmountMapSub[key] = benefitAmountMapSub[key] + value
 			flag = checkBenefitAmount(totalBenefitAmountForItem, benefitMap[key], benefitAmountMapSub[key], "Sub", grant.Sub)
 			if !flag{
-				balanceAmount := 0.0
-				if (grant.Sub*benefitMap[key]/100)-totalBenefitAmountForItem+value < 0 {
+				var balanceAmount int64
+				if percentOf(benefitMap[key], grant.Sub)-totalBenefitAmountForItem+value < 0 {
 					balanceAmount = 0
 				}else {
-					balanceAmount = (grant.Sub*benefitMap[key]/100)-totalBenefitAmountForItem+value
+					balanceAmount = percentOf(benefitMap[key], grant.Sub)-totalBenefitAmountForItem+value
 				}
-				message = fmt.Sprintf("Requested value of %.2f exceeds the allocated %.2f percentage of the total amount (%.2f). Remaining amount available for %s benefit for subawardee is %.2f.", value, grant.Sub, benefitMap[key], key, balanceAmount)
+				budgetErr = errBudgetExceeded("Requested value of %s exceeds the allocated %.2f percentage of the total amount (%s). Remaining amount available for %s benefit for subawardee is %s.", moneyFromMinor(value, currency), grant.Sub, moneyFromMinor(benefitMap[key], currency), key, moneyFromMinor(balanceAmount, currency)).
+					with("benefit", key).
+					with("requested", moneyFromMinor(value, currency)).
+					with("allocated", moneyFromMinor(percentOf(benefitMap[key], grant.Sub), currency)).
+					with("sub_percentage", grant.Sub).
+					with("remaining", moneyFromMinor(balanceAmount, currency))
 				break
 			}
 		}
 	}
 
 	
-	if payment_amount + totalBenefitAmount > grant.Amount {
-		return "", fmt.Errorf("Already requested and paid amount adds upto %.2f. The requested total amount of %.2f exceeds Grant's amount of %.2f", payment_amount, paid_amount, grant.Amount)
+	if payment_amount + totalBenefitAmount > grantAmount {
+		remaining := grantAmount - payment_amount
+		if remaining < 0 {
+			remaining = 0
+		}
+		return "", errBudgetExceeded("Already requested and paid amount adds upto %s. The requested total amount of %s exceeds Grant's amount of %s", moneyFromMinor(payment_amount, currency), moneyFromMinor(paid_amount, currency), moneyFromMinor(grantAmount, currency)).
+			with("requested", moneyFromMinor(paid_amount, currency)).
+			with("committed", moneyFromMinor(payment_amount, currency)).
+			with("amount", moneyFromMinor(grantAmount, currency)).
+			with("remaining", moneyFromMinor(remaining, currency))
 	}
 
 
 	if !flag {
-		return "", fmt.Errorf("%s", message)
+		if budgetErr == nil {
+			budgetErr = errInvalidState("Awardee %s of the Grant %s has no awardee type", reimbursementInput.Awardee_ID, grant.ID)
+		}
+		return "", budgetErr
+	}
+
+	config, err := getOrgConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	funder, err := chooseFunder(grant, grantFunders(grant, config), payments, reimbursementInput.Funder_ID, requested)
+	if err != nil {
+		return "", err
 	}
 
 	payment := Payment{
 		ID:				reimbursementInput.ID,
+		Grant_ID:		grant.ID,
 		Awardee_ID:     reimbursementInput.Awardee_ID,
 		Date:			formattedTime,
+		Funder_ID:		funder.Funder_ID,
+		Incurred_Date:	incurred.Format(time.RFC3339),
 		Item:         	reimbursementInput.Item,
 		Notes:			reimbursementInput.Notes,
-		Status:			"Requested",
-		Total:			totalBenefitAmount,
+		Status:			PaymentRequested,
+		Status_Date:	formattedTime,
+		Total:			moneyFromMinor(totalBenefitAmount, currency),
 	}
 
-	grant.Payment = append(grant.Payment, payment)
-
-	grantJSON, err := json.Marshal(grant)
+	err = putPayment(ctx, &payment)
 	if err != nil {
 		return "", err
 	}
 
-	requestCompositeKey, _ := ctx.GetStub().CreateCompositeKey("grant", []string{id})
-	err = ctx.GetStub().PutState(requestCompositeKey, grantJSON)
+	err = setPaymentEndorsement(ctx, grant, &payment)
+	if err != nil {
+		return "", err
+	}
 
+	err = emitPaymentEvent(ctx, caller, EventReimbursementRequested, grant, &payment, "")
 	if err != nil {
-		return "", fmt.Errorf("failed to put transaction definition into ledger: %v", err)
+		return "", err
 	}
 	
 	return fmt.Sprintf("Reimbursement Request for the Payment %s is successful", payment.ID), nil
 }
 
-// Grantor accept reimbursement
+// Approve a reimbursement; it is accepted once the grant's approval policy is
+// satisfied, which for most payments is the first approval by its funder
 func (s *SmartContract) AcceptReimbursement(ctx contractapi.TransactionContextInterface, grant_id string, payment_id string) (bool, error) {
-	clientID, err := ctx.GetClientIdentity().GetID()
+	caller, err := authorize(ctx, "AcceptReimbursement")
 	if err != nil {
-		return false, fmt.Errorf("failed getting the client's ID: %v", err)
+		return false, err
 	}
 
-	data, err := base64.StdEncoding.DecodeString(clientID)
+	grant, err := readGrant(ctx, grant_id)
 	if err != nil {
-		return false, fmt.Errorf("error: %v", err)
+		return false, err
 	}
-	userId := strings.Split(string(data), ",")[0][9:]
 
-	clientMSPID, err:= ctx.GetClientIdentity().GetMSPID()
+	err = checkGrantStatus(grant, GrantActive, GrantSuspended, GrantClosed)
 	if err != nil {
-		return false, fmt.Errorf("failed getting the client's MSPID: %v", err)
-	}
-	if clientMSPID != GrantorMSP {
-		return false, fmt.Errorf("User from org %v is not authorized to accept reimbursement", clientMSPID)
+		return false, err
 	}
 
-	requestCompositeKey, _ := ctx.GetStub().CreateCompositeKey("grant", []string{grant_id})
-	grant, err := s.ReadGrant(ctx, grant_id)
+	err = checkPaymentsMigrated(grant)
 	if err != nil {
-		return false, fmt.Errorf("Grant %s does not exist", grant_id)
+		return false, err
 	}
 
-	if grant.Status == "Revoked" {
-		return false, fmt.Errorf("Grant %s is revoked", grant.ID)	
+	payment, err := readPayment(ctx, grant.ID, payment_id)
+	if err != nil {
+		return false, err
 	}
 
-	if grant.Grantor_ID != userId {
-		return false, fmt.Errorf("User %s from org %v is not authorized to accept reimbursement for this grant %s",userId, clientMSPID, grant.ID)
+	funder, err := paymentFunder(ctx, grant, payment)
+	if err != nil {
+		return false, err
 	}
-
-	if !checkPayment(grant.Payment, payment_id) {
-		return false, fmt.Errorf("Payment %s doesn't exist in the Grant %s", payment_id, grant.ID)	
+	policy, err := paymentPolicy(grant, payment)
+	if err != nil {
+		return false, err
 	}
-
-	paid_amount := grant.Paid_Amount
-	var updatedPayment []Payment
-	for _, payment := range grant.Payment {
-		if payment.ID == payment_id {
-			if payment.Status != "Requested" {
-				return false, fmt.Errorf("Payment %s is Not Requested", payment_id)	
-			}
-			payment.Status = "Accepted"
-			paid_amount += payment.Total
-		}
-		updatedPayment = append(updatedPayment, payment)
+	err = checkApprover(caller, funder, payment, policy, "accept reimbursement")
+	if err != nil {
+		return false, err
 	}
 
-	updatedGrant := Grant{
-		ID:             grant.ID,
-		Amount:         grant.Amount,
-		Awardee:        grant.Awardee,
-		Benefit:        grant.Benefit,
-		Cashed_Out:     grant.Cashed_Out,
-		Description:    grant.Description,
-		End_Date:		grant.End_Date,
-		Grantor:		grant.Grantor,
-		Grantor_ID:		grant.Grantor_ID,
-		Notes:			grant.Notes,
-		Paid_Amount:	paid_amount,
-		Payment:		updatedPayment,
-		Payment_Type:	grant.Payment_Type,
-		Progress:		grant.Progress,
-		Progress_Freq:	grant.Progress_Freq,
-		Start_Date:		grant.Start_Date,
-		Status:			grant.Status,
-		Sub: 			grant.Sub,
+	previous := payment.Status
+	accepted, err := approvePayment(ctx, caller, payment, policy)
+	if err != nil {
+		return false, err
 	}
 
-	grantJSON, err := json.Marshal(updatedGrant)
+	err = putPayment(ctx, payment)
 	if err != nil {
 		return false, err
 	}
 
-	err = ctx.GetStub().PutState(requestCompositeKey, grantJSON)
-
+	event := EventReimbursementApproved
+	if accepted {
+		event = EventReimbursementAccepted
+	}
+	err = emitPaymentEvent(ctx, caller, event, grant, payment, previous)
 	if err != nil {
-		return false, fmt.Errorf("failed to put transaction definition into ledger: %v", err)
+		return false, err
 	}
 	return true, nil
 }
 
-// Grantor reject reimbursement
-func (s *SmartContract) RejectReimbursement(ctx contractapi.TransactionContextInterface, grant_id string, payment_id string, msg string) (bool, error) {
-	clientID, err := ctx.GetClientIdentity().GetID()
+// Funder of the payment reject reimbursement with a rejection code and reason
+func (s *SmartContract) RejectReimbursement(ctx contractapi.TransactionContextInterface, grant_id string, payment_id string, code string, reason string) (bool, error) {
+	caller, err := authorize(ctx, "RejectReimbursement")
 	if err != nil {
-		return false, fmt.Errorf("failed getting the client's ID: %v", err)
+		return false, err
 	}
+	userId := caller.ID
 
-	data, err := base64.StdEncoding.DecodeString(clientID)
+	grant, err := readGrant(ctx, grant_id)
 	if err != nil {
-		return false, fmt.Errorf("error: %v", err)
+		return false, err
 	}
-	userId := strings.Split(string(data), ",")[0][9:]
 
-	clientMSPID, err:= ctx.GetClientIdentity().GetMSPID()
+	err = checkGrantStatus(grant, GrantActive, GrantSuspended, GrantClosed)
 	if err != nil {
-		return false, fmt.Errorf("failed getting the client's MSPID: %v", err)
-	}
-	if clientMSPID != GrantorMSP {
-		return false, fmt.Errorf("User from org %v is not authorized to reject reimbursement", clientMSPID)
+		return false, err
 	}
 
-	requestCompositeKey, _ := ctx.GetStub().CreateCompositeKey("grant", []string{grant_id})
-	grant, err := s.ReadGrant(ctx, grant_id)
+	err = checkPaymentsMigrated(grant)
 	if err != nil {
-		return false, fmt.Errorf("Grant %s does not exist", grant_id)
+		return false, err
 	}
 
-	if grant.Status == "Revoked" {
-		return false, fmt.Errorf("Grant %s is revoked", grant.ID)	
+	payment, err := readPayment(ctx, grant.ID, payment_id)
+	if err != nil {
+		return false, err
 	}
 
-	if grant.Grantor_ID != userId {
-		return false, fmt.Errorf("User %s from org %v is not authorized to reject reimbursement for this grant %s",userId, clientMSPID, grant.ID)
+	funder, err := paymentFunder(ctx, grant, payment)
+	if err != nil {
+		return false, err
 	}
-
-	if !checkPayment(grant.Payment, payment_id) {
-		return false, fmt.Errorf("Payment %s doesn't exist in the Grant %s", payment_id, grant.ID)	
+	policy, err := paymentPolicy(grant, payment)
+	if err != nil {
+		return false, err
 	}
-
-	var updatedPayment []Payment
-	for _, payment := range grant.Payment {
-		if payment.ID == payment_id {
-			if payment.Status != "Requested" {
-				return false, fmt.Errorf("Payment %s is Not Requested", payment_id)	
-			}
-			payment.Status = msg
-		}
-		updatedPayment = append(updatedPayment, payment)
+	err = checkApprover(caller, funder, payment, policy, "reject reimbursement")
+	if err != nil {
+		return false, err
 	}
 
-	updatedGrant := Grant{
-		ID:             grant.ID,
-		Amount:         grant.Amount,
-		Awardee:        grant.Awardee,
-		Benefit:        grant.Benefit,
-		Cashed_Out:     grant.Cashed_Out,
-		Description:    grant.Description,
-		End_Date:		grant.End_Date,
-		Grantor:		grant.Grantor,
-		Grantor_ID:		grant.Grantor_ID,
-		Notes:			grant.Notes,
-		Paid_Amount:	grant.Paid_Amount,
-		Payment:		updatedPayment,
-		Payment_Type:	grant.Payment_Type,
-		Progress:		grant.Progress,
-		Progress_Freq:	grant.Progress_Freq,
-		Start_Date:		grant.Start_Date,
-		Status:			grant.Status,
-		Sub: 			grant.Sub,
+	previous := payment.Status
+	err = rejectPayment(ctx, payment, PaymentRejected, code, reason, userId)
+	if err != nil {
+		return false, err
 	}
 
-	grantJSON, err := json.Marshal(updatedGrant)
+	err = putPayment(ctx, payment)
 	if err != nil {
 		return false, err
 	}
 
-	err = ctx.GetStub().PutState(requestCompositeKey, grantJSON)
-
+	err = emitPaymentEvent(ctx, caller, EventReimbursementRejected, grant, payment, previous)
 	if err != nil {
-		return false, fmt.Errorf("failed to put transaction definition into ledger: %v", err)
+		return false, err
 	}
 	return true, nil
 }
 
 // Awardee redeem tokens
 func (s *SmartContract) RedeemTokens(ctx contractapi.TransactionContextInterface, grant_id string, payment_id string) (bool, error) {
-	clientID, err := ctx.GetClientIdentity().GetID()
+	caller, err := authorize(ctx, "RedeemTokens")
 	if err != nil {
-		return false, fmt.Errorf("failed getting the client's ID: %v", err)
-	}
-
-	data, err := base64.StdEncoding.DecodeString(clientID)
-	if err != nil {
-		return false, fmt.Errorf("error: %v", err)
+		return false, err
 	}
-	userId := strings.Split(string(data), ",")[0][9:]
+	userId := caller.ID
 
-	clientMSPID, err:= ctx.GetClientIdentity().GetMSPID()
+	grant, err := readGrant(ctx, grant_id)
 	if err != nil {
-		return false, fmt.Errorf("failed getting the client's MSPID: %v", err)
-	}
-	if clientMSPID != AwardeeMSP && clientMSPID != SubawardeeMSP {
-		return false, fmt.Errorf("User from org %v is not authorized to redeem tokens", clientMSPID)
+		return false, err
 	}
 
-	requestCompositeKey, _ := ctx.GetStub().CreateCompositeKey("grant", []string{grant_id})
-	grant, err := s.ReadGrant(ctx, grant_id)
+	err = checkGrantStatus(grant, GrantActive)
 	if err != nil {
-		return false, fmt.Errorf("Grant %s does not exist", grant_id)
-	}
-
-	if grant.Status == "Revoked" {
-		return false, fmt.Errorf("Grant %s is revoked", grant.ID)	
+		return false, err
 	}
 
 	if !checkAwardee(grant.Awardee, userId) && !checkSubAwardee(grant.Awardee, userId) {
-		return false, fmt.Errorf("Awardee %s is not assigned in the Grant %s", userId, grant.ID)	
+		return false, awardeeNotAssigned(userId, grant)	
 	}
 
-	if !checkPayment(grant.Payment, payment_id) {
-		return false, fmt.Errorf("Payment %s doesn't exist in the Grant %s", payment_id, grant.ID)	
+	err = checkPaymentsMigrated(grant)
+	if err != nil {
+		return false, err
 	}
 
-	var updatedPayment []Payment
-	for _, payment := range grant.Payment {
-		if payment.ID == payment_id {
-			if payment.Awardee_ID != userId {
-				return false, fmt.Errorf("Awardee %s is not allowed to redeem tokens for this payment %s", userId, payment_id)	
-			}
-			if payment.Status != "Accepted" {
-				return false, fmt.Errorf("Reimbursement for payment %s has Not Accepted by the Grantor", payment_id)	
-			}
-			payment.Status = "Pending-redeem"
-		}
-		updatedPayment = append(updatedPayment, payment)
+	payment, err := readPayment(ctx, grant.ID, payment_id)
+	if err != nil {
+		return false, err
 	}
 
-	updatedGrant := Grant{
-		ID:             grant.ID,
-		Amount:         grant.Amount,
-		Awardee:        grant.Awardee,
-		Benefit:        grant.Benefit,
-		Cashed_Out:     grant.Cashed_Out,
-		Description:    grant.Description,
-		End_Date:		grant.End_Date,
-		Grantor:		grant.Grantor,
-		Grantor_ID:		grant.Grantor_ID,
-		Notes:			grant.Notes,
-		Paid_Amount:	grant.Paid_Amount,
-		Payment:		updatedPayment,
-		Payment_Type:	grant.Payment_Type,
-		Progress:		grant.Progress,
-		Progress_Freq:	grant.Progress_Freq,
-		Start_Date:		grant.Start_Date,
-		Status:			grant.Status,
-		Sub: 			grant.Sub,
+	if payment.Awardee_ID != userId {
+		return false, errForbidden("Awardee %s is not allowed to redeem tokens for this payment %s", userId, payment_id).with("payment_id", payment_id)	
 	}
-
-	grantJSON, err := json.Marshal(updatedGrant)
+	previous := payment.Status
+	err = transitionPayment(ctx, payment, PaymentPendingRedeem)
 	if err != nil {
 		return false, err
 	}
 
-	err = ctx.GetStub().PutState(requestCompositeKey, grantJSON)
+	err = putPayment(ctx, payment)
+	if err != nil {
+		return false, err
+	}
 
+	err = emitPaymentEvent(ctx, caller, EventRedeemRequested, grant, payment, previous)
 	if err != nil {
-		return false, fmt.Errorf("failed to put transaction definition into ledger: %v", err)
+		return false, err
 	}
 	return true, nil
 }
 
-// Grantor accept redeem
+// Funder of the payment accept redeem
 func (s *SmartContract) AcceptRedeem(ctx contractapi.TransactionContextInterface, grant_id string, payment_id string) (bool, error) {
-	clientID, err := ctx.GetClientIdentity().GetID()
-	if err != nil {
-		return false, fmt.Errorf("failed getting the client's ID: %v", err)
-	}
-
-	data, err := base64.StdEncoding.DecodeString(clientID)
+	caller, err := authorize(ctx, "AcceptRedeem")
 	if err != nil {
-		return false, fmt.Errorf("error: %v", err)
+		return false, err
 	}
-	userId := strings.Split(string(data), ",")[0][9:]
 	
-	clientMSPID, err:= ctx.GetClientIdentity().GetMSPID()
+	grant, err := readGrant(ctx, grant_id)
 	if err != nil {
-		return false, fmt.Errorf("failed getting the client's MSPID: %v", err)
-	}
-	if clientMSPID != GrantorMSP {
-		return false, fmt.Errorf("User from org %v is not authorized to accept redeem", clientMSPID)
+		return false, err
 	}
 
-	requestCompositeKey, _ := ctx.GetStub().CreateCompositeKey("grant", []string{grant_id})
-	grant, err := s.ReadGrant(ctx, grant_id)
+	err = checkGrantStatus(grant, GrantActive, GrantSuspended, GrantClosed)
 	if err != nil {
-		return false, fmt.Errorf("Grant %s does not exist", grant_id)
+		return false, err
 	}
 
-	if grant.Status == "Revoked" {
-		return false, fmt.Errorf("Grant %s is revoked", grant.ID)	
+	err = checkPaymentsMigrated(grant)
+	if err != nil {
+		return false, err
 	}
 
-	if grant.Grantor_ID != userId {
-		return false, fmt.Errorf("Grantor %s is not allowed to accept redeem for the Grant %s", userId, grant.ID)	
+	payment, err := readPayment(ctx, grant.ID, payment_id)
+	if err != nil {
+		return false, err
 	}
 
-	if !checkPayment(grant.Payment, payment_id) {
-		return false, fmt.Errorf("Payment %s doesn't exist in the Grant %s", payment_id, grant.ID)	
+	funder, err := paymentFunder(ctx, grant, payment)
+	if err != nil {
+		return false, err
 	}
-
-	var updatedPayment []Payment
-	var cashedOut float64
-	var paid_amount float64
-	for _, payment := range grant.Payment {
-		if payment.ID == payment_id {
-			if payment.Status != "Pending-redeem" {
-				return false, fmt.Errorf("Payment %s is not in Pending-redeem status", payment_id)	
-			}
-			payment.Status = "Accept_redeem"
-			cashedOut = grant.Cashed_Out + payment.Total
-			paid_amount = payment.Total
-
-		}
-		updatedPayment = append(updatedPayment, payment)
+	err = checkPaymentFunder(caller, funder, payment, "accept redeem", RoleFinance)
+	if err != nil {
+		return false, err
 	}
 
-	updatedGrant := Grant{
-		ID:             grant.ID,
-		Amount:         grant.Amount,
-		Awardee:        grant.Awardee,
-		Benefit:        grant.Benefit,
-		Cashed_Out:     cashedOut,
-		Description:    grant.Description,
-		End_Date:		grant.End_Date,
-		Grantor:		grant.Grantor,
-		Grantor_ID:		grant.Grantor_ID,
-		Notes:			grant.Notes,
-		Paid_Amount:	grant.Paid_Amount - paid_amount,
-		Payment:		updatedPayment,
-		Payment_Type:	grant.Payment_Type,
-		Progress:		grant.Progress,
-		Progress_Freq:	grant.Progress_Freq,
-		Start_Date:		grant.Start_Date,
-		Status:			grant.Status,
-		Sub: 			grant.Sub,
+	previous := payment.Status
+	err = transitionPayment(ctx, payment, PaymentRedeemed)
+	if err != nil {
+		return false, err
 	}
 
-	grantJSON, err := json.Marshal(updatedGrant)
+	err = putPayment(ctx, payment)
 	if err != nil {
 		return false, err
 	}
 
-	err = ctx.GetStub().PutState(requestCompositeKey, grantJSON)
-
+	err = emitPaymentEvent(ctx, caller, EventRedeemAccepted, grant, payment, previous)
 	if err != nil {
-		return false, fmt.Errorf("failed to put transaction definition into ledger: %v", err)
+		return false, err
 	}
 	return true, nil
 }
 
-// Grantor reject redeem
-func (s *SmartContract) RejectRedeem(ctx contractapi.TransactionContextInterface, grant_id string, payment_id string, msg string) (bool, error) {
-	clientID, err := ctx.GetClientIdentity().GetID()
+// Funder of the payment reject redeem with a rejection code and reason
+func (s *SmartContract) RejectRedeem(ctx contractapi.TransactionContextInterface, grant_id string, payment_id string, code string, reason string) (bool, error) {
+	caller, err := authorize(ctx, "RejectRedeem")
 	if err != nil {
-		return false, fmt.Errorf("failed getting the client's ID: %v", err)
+		return false, err
 	}
+	userId := caller.ID
 
-	data, err := base64.StdEncoding.DecodeString(clientID)
+	grant, err := readGrant(ctx, grant_id)
 	if err != nil {
-		return false, fmt.Errorf("error: %v", err)
+		return false, err
 	}
-	userId := strings.Split(string(data), ",")[0][9:]
 
-	clientMSPID, err:= ctx.GetClientIdentity().GetMSPID()
+	err = checkGrantStatus(grant, GrantActive, GrantSuspended, GrantClosed)
 	if err != nil {
-		return false, fmt.Errorf("failed getting the client's MSPID: %v", err)
-	}
-	if clientMSPID != GrantorMSP {
-		return false, fmt.Errorf("User from org %v is not authorized to reject redeem", clientMSPID)
+		return false, err
 	}
 
-	requestCompositeKey, _ := ctx.GetStub().CreateCompositeKey("grant", []string{grant_id})
-	grant, err := s.ReadGrant(ctx, grant_id)
+	err = checkPaymentsMigrated(grant)
 	if err != nil {
-		return false, fmt.Errorf("Grant %s does not exist", grant_id)
-	}
-
-	if grant.Status == "Revoked" {
-		return false, fmt.Errorf("Grant %s is revoked", grant.ID)	
+		return false, err
 	}
 
-	if grant.Grantor_ID != userId {
-		return false, fmt.Errorf("Grantor %s is not allowed to accept redeem for the Grant %s", userId, grant.ID)	
+	payment, err := readPayment(ctx, grant.ID, payment_id)
+	if err != nil {
+		return false, err
 	}
 
-	if !checkPayment(grant.Payment, payment_id) {
-		return false, fmt.Errorf("Payment %s doesn't exist in the Grant %s", payment_id, grant.ID)	
+	funder, err := paymentFunder(ctx, grant, payment)
+	if err != nil {
+		return false, err
 	}
-
-	var updatedPayment []Payment
-	var paid_amount float64
-	for _, payment := range grant.Payment {
-		if payment.ID == payment_id {
-			if payment.Status != "Pending-redeem" {
-				return false, fmt.Errorf("Payment %s is not in Pending-redeem status", payment_id)	
-			}
-			payment.Status = msg
-			paid_amount = payment.Total
-		}
-		updatedPayment = append(updatedPayment, payment)
+	err = checkPaymentFunder(caller, funder, payment, "reject redeem", RoleFinance)
+	if err != nil {
+		return false, err
 	}
 
-	updatedGrant := Grant{
-		ID:             grant.ID,
-		Amount:         grant.Amount,
-		Awardee:        grant.Awardee,
-		Benefit:        grant.Benefit,
-		Cashed_Out:     grant.Cashed_Out,
-		Description:    grant.Description,
-		End_Date:		grant.End_Date,
-		Grantor:		grant.Grantor,
-		Grantor_ID:		grant.Grantor_ID,
-		Notes:			grant.Notes,
-		Paid_Amount:	grant.Paid_Amount - paid_amount,
-		Payment:		updatedPayment,
-		Payment_Type:	grant.Payment_Type,
-		Progress:		grant.Progress,
-		Progress_Freq:	grant.Progress_Freq,
-		Start_Date:		grant.Start_Date,
-		Status:			grant.Status,
-		Sub: 			grant.Sub,
+	previous := payment.Status
+	err = rejectPayment(ctx, payment, PaymentRedeemRejected, code, reason, userId)
+	if err != nil {
+		return false, err
 	}
 
-	grantJSON, err := json.Marshal(updatedGrant)
+	err = putPayment(ctx, payment)
 	if err != nil {
 		return false, err
 	}
 
-	err = ctx.GetStub().PutState(requestCompositeKey, grantJSON)
-
+	err = emitPaymentEvent(ctx, caller, EventRedeemRejected, grant, payment, previous)
 	if err != nil {
-		return false, fmt.Errorf("failed to put transaction definition into ledger: %v", err)
+		return false, err
 	}
 	return true, nil
 }
 
 // Grantor add Awardees
 func (s *SmartContract) AddAwardee(ctx contractapi.TransactionContextInterface) (bool, error) {
-	clientID, err := ctx.GetClientIdentity().GetID()
-	if err != nil {
-		return false, fmt.Errorf("failed getting the client's ID: %v", err)
-	}
-
-	data, err := base64.StdEncoding.DecodeString(clientID)
+	caller, err := authorize(ctx, "AddAwardee")
 	if err != nil {
-		return false, fmt.Errorf("error: %v", err)
-	}
-	userId := strings.Split(string(data), ",")[0][9:]
-
-	clientMSPID, err:= ctx.GetClientIdentity().GetMSPID()
-	if err != nil {
-		return false, fmt.Errorf("failed getting the client's MSPID: %v", err)
-	}
-	if clientMSPID != GrantorMSP {
-		return false, fmt.Errorf("User from org %v is not authorized to add awardee", clientMSPID)
-	}
-
-
-	type awardeeTransientInput struct {
-		Grant_ID		string		`json:"grant_id"`
-		Awardee         Awardee   	`json:"awardee"`
+		return false, err
 	}
 
 	// Get new transaction definition details from transient map
 	transientMap, err := ctx.GetStub().GetTransient()
 	if err != nil {
-		return false, fmt.Errorf("error getting transient: %v", err)
+		return false, errInternal("error getting transient: %v", err)
 	}
 
 	// Private records get passed in transient field, instead of func args
-	transientGrantJSON, ok := transientMap["add_awardee"]
-	if !ok {
-		//log error to stdout
-		return false, fmt.Errorf("add_awardee not found in the transient map input")
+	var awardeeInput awardeeTransientInput
+	err = readTransientInput(transientMap, "add_awardee", &awardeeInput)
+	if err != nil {
+		return false, err
 	}
 
-	var awardeeInput awardeeTransientInput
-	err = json.Unmarshal(transientGrantJSON, &awardeeInput)
+	salt, err := transientSalt(transientMap)
 	if err != nil {
-		return false, fmt.Errorf("failed to unmarshal JSON: %v", err)
+		return false, err
 	}
 
 	awardeeInput.Awardee.Awardee_Type = "Main"
-
-	requestCompositeKey, _ := ctx.GetStub().CreateCompositeKey("grant", []string{awardeeInput.Grant_ID})
-	grant, err := s.ReadGrant(ctx, awardeeInput.Grant_ID)
+	config, err := getOrgConfig(ctx)
 	if err != nil {
-		return false, fmt.Errorf("Grant %s does not exist", awardeeInput.Grant_ID)
+		return false, err
+	}
+	err = qualifyAwardee(&awardeeInput.Awardee, config)
+	if err != nil {
+		return false, err
 	}
 
-	if grant.Status == "Revoked" {
-		return false, fmt.Errorf("Grant %s is revoked", grant.ID)	
+	grant, err := readGrant(ctx, awardeeInput.Grant_ID)
+	if err != nil {
+		return false, err
 	}
 
-	if grant.Grantor_ID != userId {
-		return false, fmt.Errorf("Grantor %s is not allowed to add awardee in the Grant %s", userId, grant.ID)	
+	err = checkGrantStatus(grant, GrantAssigned, GrantActive, GrantSuspended)
+	if err != nil {
+		return false, err
 	}
 
-	if checkAwardee(grant.Awardee, awardeeInput.Awardee.ID) {
-		return false, fmt.Errorf("Awardee %s is already exists in the Grant %s", awardeeInput.Awardee.ID, grant.ID)	
+	err = checkGrantOwner(caller, grant, "add awardee in")
+	if err != nil {
+		return false, err
 	}
 
-	updatedGrant := Grant{
-		ID:             grant.ID,
-		Amount:         grant.Amount,
-		Awardee:        append(grant.Awardee, awardeeInput.Awardee),
-		Benefit:        grant.Benefit,
-		Cashed_Out:     grant.Cashed_Out,
-		Description:    grant.Description,
-		End_Date:		grant.End_Date,
-		Grantor:		grant.Grantor,
-		Grantor_ID:		grant.Grantor_ID,
-		Notes:			grant.Notes,
-		Paid_Amount:	grant.Paid_Amount,
-		Payment:		grant.Payment,
-		Payment_Type:	grant.Payment_Type,
-		Progress:		grant.Progress,
-		Progress_Freq:	grant.Progress_Freq,
-		Start_Date:		grant.Start_Date,
-		Status:			grant.Status,
-		Sub: 			grant.Sub,
+	if checkAwardee(grant.Awardee, awardeeInput.Awardee.ID) {
+		return false, errDuplicate("Awardee %s is already exists in the Grant %s", awardeeInput.Awardee.ID, grant.ID).
+			with("grant_id", grant.ID).
+			with("awardee_id", awardeeInput.Awardee.ID)	
 	}
 
-	grantJSON, err := json.Marshal(updatedGrant)
+	err = putAwardeePrivateDetails(ctx, grant.ID, &awardeeInput.Awardee, salt)
 	if err != nil {
 		return false, err
 	}
+	grant.Awardee = append(grant.Awardee, awardeeInput.Awardee)
 
-	err = ctx.GetStub().PutState(requestCompositeKey, grantJSON)
-
+	err = putGrant(ctx, grant)
 	if err != nil {
-		return false, fmt.Errorf("failed to put transaction definition into ledger: %v", err)
+		return false, err
 	}
-	return true, nil
-}
 
-// Awardee add SubAwardees
-func (s *SmartContract) AddSubawardee(ctx contractapi.TransactionContextInterface) (bool, error) {
-	clientID, err := ctx.GetClientIdentity().GetID()
+	err = putGrantIndexes(ctx, grant)
 	if err != nil {
-		return false, fmt.Errorf("failed getting the client's ID: %v", err)
+		return false, err
 	}
 
-	data, err := base64.StdEncoding.DecodeString(clientID)
+	err = setGrantEndorsement(ctx, grant, true)
 	if err != nil {
-		return false, fmt.Errorf("error: %v", err)
+		return false, err
 	}
-	userId := strings.Split(string(data), ",")[0][9:]
 
-	clientMSPID, err:= ctx.GetClientIdentity().GetMSPID()
+	err = emitAwardeeEvent(ctx, caller, EventAwardeeAdded, grant, awardeeInput.Awardee.ID)
 	if err != nil {
-		return false, fmt.Errorf("failed getting the client's MSPID: %v", err)
-	}
-	if clientMSPID != AwardeeMSP {
-		return false, fmt.Errorf("User from org %v is not authorized to add subawardee", clientMSPID)
+		return false, err
 	}
+	return true, nil
+}
 
-
-	type subAwardeeTransientInput struct {
-		Grant_ID		string		`json:"grant_id"`
-		Awardee_ID		string 		`json:"awardee_id"`
-		Awardee         Awardee   	`json:"awardee"`
+// Awardee add SubAwardees
+func (s *SmartContract) AddSubawardee(ctx contractapi.TransactionContextInterface) (bool, error) {
+	caller, err := authorize(ctx, "AddSubawardee")
+	if err != nil {
+		return false, err
 	}
+	userId := caller.ID
 
 	// Get new transaction definition details from transient map
 	transientMap, err := ctx.GetStub().GetTransient()
 	if err != nil {
-		return false, fmt.Errorf("error getting transient: %v", err)
+		return false, errInternal("error getting transient: %v", err)
 	}
 
 	// Private records get passed in transient field, instead of func args
-	transientGrantJSON, ok := transientMap["add_subawardee"]
-	if !ok {
-		//log error to stdout
-		return false, fmt.Errorf("add_subawardee not found in the transient map input")
+	var subAwardeeInput subAwardeeTransientInput
+	err = readTransientInput(transientMap, "add_subawardee", &subAwardeeInput)
+	if err != nil {
+		return false, err
 	}
 
-	var subAwardeeInput subAwardeeTransientInput
-	err = json.Unmarshal(transientGrantJSON, &subAwardeeInput)
+	salt, err := transientSalt(transientMap)
 	if err != nil {
-		return false, fmt.Errorf("failed to unmarshal JSON: %v", err)
+		return false, err
 	}
 
 	subAwardeeInput.Awardee.Awardee_Type = "Sub"
-
-	requestCompositeKey, _ := ctx.GetStub().CreateCompositeKey("grant", []string{subAwardeeInput.Grant_ID})
-	grant, err := s.ReadGrant(ctx, subAwardeeInput.Grant_ID)
+	config, err := getOrgConfig(ctx)
 	if err != nil {
-		return false, fmt.Errorf("Grant %s does not exist", subAwardeeInput.Grant_ID)
+		return false, err
+	}
+	err = qualifyAwardee(&subAwardeeInput.Awardee, config)
+	if err != nil {
+		return false, err
 	}
 
-	if grant.Status == "Revoked" {
-		return false, fmt.Errorf("Grant %s is revoked", grant.ID)	
+	grant, err := readGrant(ctx, subAwardeeInput.Grant_ID)
+	if err != nil {
+		return false, err
 	}
 
 	if !checkAwardee(grant.Awardee, userId) {
-		return false, fmt.Errorf("Awardee %s is not assigned in the Grant %s", userId, grant.ID)	
+		return false, awardeeNotAssigned(userId, grant)	
 	}
 
 	if checkSubAwardee(grant.Awardee, subAwardeeInput.Awardee.ID) {
-		return false, fmt.Errorf("Awardee %s is already exists in the Grant %s", subAwardeeInput.Awardee.ID, grant.ID)	
-	}
-
-	if grant.Status != "Approved" {
-		return false, fmt.Errorf("Grant %s is not approved by the Awardee %s", grant.ID, userId)	
+		return false, errDuplicate("Awardee %s is already exists in the Grant %s", subAwardeeInput.Awardee.ID, grant.ID).
+			with("grant_id", grant.ID).
+			with("awardee_id", subAwardeeInput.Awardee.ID)	
 	}
 
-	updatedGrant := Grant{
-		ID:             grant.ID,
-		Amount:         grant.Amount,
-		Awardee:        append(grant.Awardee, subAwardeeInput.Awardee),
-		Benefit:        grant.Benefit,
-		Cashed_Out:     grant.Cashed_Out,
-		Description:    grant.Description,
-		End_Date:		grant.End_Date,
-		Grantor:		grant.Grantor,
-		Grantor_ID:		grant.Grantor_ID,
-		Notes:			grant.Notes,
-		Paid_Amount:	grant.Paid_Amount,
-		Payment:		grant.Payment,
-		Payment_Type:	grant.Payment_Type,
-		Progress:		grant.Progress,
-		Progress_Freq:	grant.Progress_Freq,
-		Start_Date:		grant.Start_Date,
-		Status:			grant.Status,
-		Sub: 			grant.Sub,
+	err = checkGrantStatus(grant, GrantActive)
+	if err != nil {
+		return false, err
 	}
 
-	grantJSON, err := json.Marshal(updatedGrant)
+	err = putAwardeePrivateDetails(ctx, grant.ID, &subAwardeeInput.Awardee, salt)
 	if err != nil {
 		return false, err
 	}
+	grant.Awardee = append(grant.Awardee, subAwardeeInput.Awardee)
 
-	err = ctx.GetStub().PutState(requestCompositeKey, grantJSON)
-
+	err = putGrant(ctx, grant)
 	if err != nil {
-		return false, fmt.Errorf("failed to put transaction definition into ledger: %v", err)
+		return false, err
 	}
-	return true, nil
-
-}
 
-// Awardee add Progress
-func (s *SmartContract) AddProgress(ctx contractapi.TransactionContextInterface) (bool, error) {
-	clientID, err := ctx.GetClientIdentity().GetID()
+	err = putGrantIndexes(ctx, grant)
 	if err != nil {
-		return false, fmt.Errorf("failed getting the client's ID: %v", err)
+		return false, err
 	}
 
-	data, err := base64.StdEncoding.DecodeString(clientID)
+	err = setGrantEndorsement(ctx, grant, true)
 	if err != nil {
-		return false, fmt.Errorf("error: %v", err)
+		return false, err
 	}
-	userId := strings.Split(string(data), ",")[0][9:]
 
-	clientMSPID, err:= ctx.GetClientIdentity().GetMSPID()
+	err = emitAwardeeEvent(ctx, caller, EventSubawardeeAdded, grant, subAwardeeInput.Awardee.ID)
 	if err != nil {
-		return false, fmt.Errorf("failed getting the client's MSPID: %v", err)
-	}
-	if clientMSPID != AwardeeMSP && clientMSPID != SubawardeeMSP {
-		return false, fmt.Errorf("User from org %v is not authorized to add progress", clientMSPID)
+		return false, err
 	}
+	return true, nil
 
+}
 
-	type progressTransientInput struct {
-		Grant_ID		string			`json:"grant_id"`
-		Progress		Progress 		`json:"progress"`	
+// Awardee add Progress
+func (s *SmartContract) AddProgress(ctx contractapi.TransactionContextInterface) (bool, error) {
+	caller, err := authorize(ctx, "AddProgress")
+	if err != nil {
+		return false, err
 	}
+	userId := caller.ID
 
 	// Get new transaction definition details from transient map
 	transientMap, err := ctx.GetStub().GetTransient()
 	if err != nil {
-		return false, fmt.Errorf("error getting transient: %v", err)
+		return false, errInternal("error getting transient: %v", err)
 	}
 
 	// Private records get passed in transient field, instead of func args
-	transientGrantJSON, ok := transientMap["add_progress"]
-	if !ok {
-		//log error to stdout
-		return false, fmt.Errorf("add_progress not found in the transient map input")
-	}
-
 	var progressInput progressTransientInput
-	err = json.Unmarshal(transientGrantJSON, &progressInput)
+	err = readTransientInput(transientMap, "add_progress", &progressInput)
 	if err != nil {
-		return false, fmt.Errorf("failed to unmarshal JSON: %v", err)
+		return false, err
 	}
 
-	requestCompositeKey, _ := ctx.GetStub().CreateCompositeKey("grant", []string{progressInput.Grant_ID})
-	grant, err := s.ReadGrant(ctx, progressInput.Grant_ID)
+	grant, err := readGrant(ctx, progressInput.Grant_ID)
 	if err != nil {
-		return false, fmt.Errorf("Grant %s does not exist", progressInput.Grant_ID)
-	}
-
-	if grant.Status == "Revoked" {
-		return false, fmt.Errorf("Grant %s is revoked", grant.ID)	
+		return false, err
 	}
 
 	if !checkAwardee(grant.Awardee, userId) && !checkSubAwardee(grant.Awardee, userId) {
-		return false, fmt.Errorf("Awardee %s is not assigned in the Grant %s", userId, grant.ID)	
+		return false, awardeeNotAssigned(userId, grant)	
 	}
 
-	if grant.Status != "Approved" {
-		return false, fmt.Errorf("Grant %s is not approved by the Awardee %s", grant.ID, userId)	
+	err = checkGrantStatus(grant, GrantActive)
+	if err != nil {
+		return false, err
 	}
 
-	updatedGrant := Grant{
-		ID:             grant.ID,
-		Amount:         grant.Amount,
-		Awardee:        grant.Awardee,
-		Benefit:        grant.Benefit,
-		Cashed_Out:     grant.Cashed_Out,
-		Description:    grant.Description,
-		End_Date:		grant.End_Date,
-		Grantor:		grant.Grantor,
-		Grantor_ID:		grant.Grantor_ID,
-		Notes:			grant.Notes,
-		Paid_Amount:	grant.Paid_Amount,
-		Payment:		grant.Payment,
-		Payment_Type:	grant.Payment_Type,
-		Progress:		append(grant.Progress, progressInput.Progress),
-		Progress_Freq:	grant.Progress_Freq,
-		Start_Date:		grant.Start_Date,
-		Status:			grant.Status,
-		Sub: 			grant.Sub,
+	now, err := txTimestamp(ctx)
+	if err != nil {
+		return false, err
 	}
-
-	grantJSON, err := json.Marshal(updatedGrant)
+	err = checkProgressPeriod(grant, now)
 	if err != nil {
 		return false, err
 	}
+	progressInput.Progress.Date = now.Format(time.RFC3339)
+	grant.Progress = append(grant.Progress, progressInput.Progress)
 
-	err = ctx.GetStub().PutState(requestCompositeKey, grantJSON)
+	err = putGrant(ctx, grant)
+	if err != nil {
+		return false, err
+	}
 
+	err = emitGrantEvent(ctx, caller, EventProgressAdded, grant, grant.Status)
 	if err != nil {
-		return false, fmt.Errorf("failed to put transaction definition into ledger: %v", err)
+		return false, err
 	}
 	return true, nil
 
 }
 
 // Get Wallet with Specified Status
-func (s *SmartContract) GetWallet(ctx contractapi.TransactionContextInterface, grant_id string, awardee_id string, status string) (float64, error) {
-	
-	grant, err := s.ReadGrant(ctx, grant_id)
+func (s *SmartContract) GetWallet(ctx contractapi.TransactionContextInterface, grant_id string, awardee_id string, status string) (Money, error) {
+	caller, err := authorize(ctx, "GetWallet")
+	if err != nil {
+		return "", err
+	}
+
+	paymentStatus, err := parsePaymentStatus(status)
+	if err != nil {
+		return "", err
+	}
+
+	grant, _, err := readGrantAs(ctx, caller, grant_id)
+	if err != nil {
+		return "", err
+	}
+
+	if grant.Status == GrantRevoked {
+		return "", errInvalidState("Grant %s is revoked", grant.ID).with("grant_id", grant.ID).with("status", grant.Status)	
+	}
+
+	awardee_id, err = grantAwardeeID(grant, awardee_id)
 	if err != nil {
-		return 0.0, fmt.Errorf("Grant %s does not exist", grant_id)
+		return "", err
 	}
 
-	if grant.Status == "Revoked" {
-		return 0.0, fmt.Errorf("Grant %s is revoked", grant.ID)	
+	payments, err := getGrantPayments(ctx, grant.ID)
+	if err != nil {
+		return "", err
 	}
 
-	var totalAmount float64
-	for _, payment := range grant.Payment{
-		if payment.Awardee_ID == awardee_id && payment.Status == status{
-			totalAmount += payment.Total
+	currency := grantCurrency(grant)
+	var totalAmount int64
+	for _, payment := range payments {
+		if payment.Awardee_ID == awardee_id && payment.Status == paymentStatus {
+			total, err := payment.Total.minor(currency)
+			if err != nil {
+				return "", storedAmountInvalid(err, "Payment %s has an invalid amount", payment.ID)
+			}
+			totalAmount += total
 		}
 	}
 
-	return totalAmount, nil
+	return moneyFromMinor(totalAmount, currency), nil
 }
 
 // Get Wallet with Specified Status
 func (s *SmartContract) MyWallet(ctx contractapi.TransactionContextInterface, grant_id string) (*AmountResponse, error) {
 
-	clientID, err := ctx.GetClientIdentity().GetID()
+	caller, err := authorize(ctx, "MyWallet")
 	if err != nil {
-		return nil, fmt.Errorf("failed getting the client's ID: %v", err)
+		return nil, err
 	}
+	userId := caller.ID
 
-	data, err := base64.StdEncoding.DecodeString(clientID)
+	grant, err := readGrant(ctx, grant_id)
 	if err != nil {
-		return nil, fmt.Errorf("error: %v", err)
+		return nil, err
 	}
-	userId := strings.Split(string(data), ",")[0][9:]
 
-	grant, err := s.ReadGrant(ctx, grant_id)
-	if err != nil {
-		return nil, fmt.Errorf("Grant %s does not exist", grant_id)
+	if grant.Status == GrantRevoked {
+		return nil, errInvalidState("Grant %s is revoked", grant.ID).with("grant_id", grant.ID).with("status", grant.Status)	
 	}
 
-	if grant.Status == "Revoked" {
-		return nil, fmt.Errorf("Grant %s is revoked", grant.ID)	
+	if !checkAwardee(grant.Awardee, userId) && !checkSubAwardee(grant.Awardee, userId) {
+		return nil, awardeeNotAssigned(userId, grant)	
 	}
 
-	if !checkAwardee(grant.Awardee, userId) && !checkSubAwardee(grant.Awardee, userId) {
-		return nil, fmt.Errorf("Awardee %s is not assigned in the Grant %s", userId, grant.ID)	
+	payments, err := getGrantPayments(ctx, grant.ID)
+	if err != nil {
+		return nil, err
 	}
 
-	var cashedOut float64
-	var requestedAmount float64
-	for _, payment := range grant.Payment {
+	currency := grantCurrency(grant)
+	var cashedOut int64
+	var requestedAmount int64
+	for _, payment := range payments {
 		if payment.Awardee_ID == userId {
-			if payment.Status == "Accepted" || payment.Status == "Pending-redeem" {
-				requestedAmount += payment.Total
-			} else if payment.Status == "Accept_redeem" {
-				cashedOut += payment.Total
+			total, err := payment.Total.minor(currency)
+			if err != nil {
+				return nil, storedAmountInvalid(err, "Payment %s has an invalid amount", payment.ID)
+			}
+			if payment.Status == PaymentAccepted || payment.Status == PaymentPendingRedeem {
+				requestedAmount += total
+			} else if payment.Status == PaymentRedeemed {
+				cashedOut += total
 			}
 		}
 	}
 
 
 	response := AmountResponse {
-		Cashed_Out:			cashedOut,
-		Requested_Amount:	requestedAmount,
+		Cashed_Out:			moneyFromMinor(cashedOut, currency),
+		Requested_Amount:	moneyFromMinor(requestedAmount, currency),
 	}
 	return &response, nil
 }
 
-// GetAllGrants for specific user returns all grants for grantors and awardee found in world state
+// GetAllGrantsUser returns all grants the user takes part in as grantor, awardee or subawardee
 func (s *SmartContract) GetAllGrantsUser(ctx contractapi.TransactionContextInterface) ([]Grant, error) {
-	//requestPartialCompositeKey, _ := ctx.GetStub().CreateCompositeKey("grant", []string{id})
-	clientID, err := ctx.GetClientIdentity().GetID()
-	if err != nil {
-		return nil, fmt.Errorf("failed getting the client's ID: %v", err)
-	}
-
-	data, err := base64.StdEncoding.DecodeString(clientID)
-	if err != nil {
-		return nil, fmt.Errorf("error: %v", err)
-	}
-	userId := strings.Split(string(data), ",")[0][9:]
-
-	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey("grant", []string{})
+	caller, err := authorize(ctx, "GetAllGrantsUser")
 	if err != nil {
 		return nil, err
 	}
-	defer resultsIterator.Close()
-
-	var grants []Grant
-	for resultsIterator.HasNext() {
-		queryResponse, err := resultsIterator.Next()
-		if err != nil {
-			return nil, err
-		}
 
-		var grant Grant
-		err = json.Unmarshal(queryResponse.Value, &grant)
-		if err != nil {
-			return nil, err
-		}
-
-		if checkAwardee(grant.Awardee, userId) || grant.Grantor_ID == userId || checkSubAwardee(grant.Awardee, userId) {
-			grants = append(grants, grant)
-		}
-		
-	}
-
-	if len(grants)  == 0 {
-		return []Grant{}, nil
-	} else {
-		return grants, nil
-	}
-	
+	return getUserGrants(ctx, caller, func(grant *Grant) bool {
+		return true
+	})
 }
 
 // GetAllApprovedGrants for specific user returns all approved grants for awardee found in world state
 func (s *SmartContract) GetAllApprovedGrants(ctx contractapi.TransactionContextInterface) ([]Grant, error) {
-	clientID, err := ctx.GetClientIdentity().GetID()
-	if err != nil {
-		return nil, fmt.Errorf("failed getting the client's ID: %v", err)
-	}
-
-	data, err := base64.StdEncoding.DecodeString(clientID)
-	if err != nil {
-		return nil, fmt.Errorf("error: %v", err)
-	}
-	userId := strings.Split(string(data), ",")[0][9:]
-
-	//requestPartialCompositeKey, _ := ctx.GetStub().CreateCompositeKey("grant", []string{id})
-	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey("grant", []string{})
+	caller, err := authorize(ctx, "GetAllApprovedGrants")
 	if err != nil {
 		return nil, err
 	}
-	defer resultsIterator.Close()
-
-	var grants []Grant
-	for resultsIterator.HasNext() {
-		queryResponse, err := resultsIterator.Next()
-		if err != nil {
-			return nil, err
-		}
-
-		var grant Grant
-		err = json.Unmarshal(queryResponse.Value, &grant)
-		if err != nil {
-			return nil, err
-		}
-
-		if (grant.Grantor_ID == userId || checkAwardee(grant.Awardee, userId) || checkSubAwardee(grant.Awardee, userId)) && grant.Status == "Approved" {
-			grants = append(grants, grant)
-		}
-		
-	}
 
-	if len(grants)  == 0 {
-		return []Grant{}, nil
-	} else {
-		return grants, nil
-	}
-	
+	return getUserGrants(ctx, caller, func(grant *Grant) bool {
+		return grant.Status == GrantActive
+	})
 }
 
 // GetGrantsByStatus returns all grants with specific status
 func (s *SmartContract) GetGrantsByStatus(ctx contractapi.TransactionContextInterface, status string) ([]Grant, error) {
-	clientID, err := ctx.GetClientIdentity().GetID()
-	if err != nil {
-		return nil, fmt.Errorf("failed getting the client's ID: %v", err)
-	}
-
-	data, err := base64.StdEncoding.DecodeString(clientID)
+	caller, err := authorize(ctx, "GetGrantsByStatus")
 	if err != nil {
-		return nil, fmt.Errorf("error: %v", err)
+		return nil, err
 	}
-	userId := strings.Split(string(data), ",")[0][9:]
 
-	//requestPartialCompositeKey, _ := ctx.GetStub().CreateCompositeKey("grant", []string{id})
-	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey("grant", []string{})
+	grantStatus, err := parseGrantStatus(status)
 	if err != nil {
 		return nil, err
 	}
-	defer resultsIterator.Close()
-
-	var grants []Grant
-	for resultsIterator.HasNext() {
-		queryResponse, err := resultsIterator.Next()
-		if err != nil {
-			return nil, err
-		}
-
-		var grant Grant
-		err = json.Unmarshal(queryResponse.Value, &grant)
-		if err != nil {
-			return nil, err
-		}
-
-		if grant.Status == status && (checkAwardee(grant.Awardee, userId) || checkSubAwardee(grant.Awardee, userId) || grant.Grantor_ID == userId) {
-			grants = append(grants, grant)
-		}
-		
-	}
 
-	if len(grants)  == 0 {
-		return []Grant{}, nil
-	} else {
-		return grants, nil
-	}
-	
+	return getUserGrants(ctx, caller, func(grant *Grant) bool {
+		return grant.Status == grantStatus
+	})
 }
 
 // GetGrantBenefits returns the benefits assigned for a grant with given id.
 func (s *SmartContract) GetGrantBenefits(ctx contractapi.TransactionContextInterface, id string) ([]Benefit, error) {
-	requestCompositeKey, _ := ctx.GetStub().CreateCompositeKey("grant", []string{id})
-	grantJSON, err := ctx.GetStub().GetState(requestCompositeKey)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read from world state: %v", err)
-	}
-	if grantJSON == nil {
-		return nil, fmt.Errorf("the grant %s does not exist", id)
-	}
-
-	var grant Grant
-	var benefit []Benefit
-	err = json.Unmarshal(grantJSON, &grant)
+	caller, err := authorize(ctx, "GetGrantBenefits")
 	if err != nil {
 		return nil, err
 	}
 
-	benefitJSON, err := json.Marshal(grant.Benefit)
-	if err != nil {
-		return nil,  fmt.Errorf("error marshaling json: %v", err)
-	}
-
-	err = json.Unmarshal(benefitJSON, &benefit)
+	grant, _, err := readGrantAs(ctx, caller, id)
 	if err != nil {
 		return nil, err
 	}
 
-	return benefit, nil
+	return grant.Benefit, nil
 }
 
 // GetPayments returns all the payments in a grant with given id.
 func (s *SmartContract) GetPayments(ctx contractapi.TransactionContextInterface, id string) ([]Payment, error) {
-	requestCompositeKey, _ := ctx.GetStub().CreateCompositeKey("grant", []string{id})
-	grantJSON, err := ctx.GetStub().GetState(requestCompositeKey)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read from world state: %v", err)
-	}
-	if grantJSON == nil {
-		return nil, fmt.Errorf("the Grant %s does not exist", id)
-	}
-
-	var grant Grant
-	var payment []Payment
-	err = json.Unmarshal(grantJSON, &grant)
+	caller, err := authorize(ctx, "GetPayments")
 	if err != nil {
 		return nil, err
 	}
 
-	paymentJSON, err := json.Marshal(grant.Payment)
+	_, access, err := readGrantAs(ctx, caller, id)
 	if err != nil {
-		return nil,  fmt.Errorf("error marshaling json: %v", err)
+		return nil, err
 	}
 
-	err = json.Unmarshal(paymentJSON, &payment)
+	payments, err := getGrantPayments(ctx, id)
 	if err != nil {
 		return nil, err
 	}
-
-	return payment, nil
+	return viewPayments(payments, access), nil
 }
 
-// GetPaymentByStatus returns all the payments with given status in a grant for the user.
-func (s *SmartContract) GetPaymentByStatus(ctx contractapi.TransactionContextInterface, status string) ([]PaymentStatus, error) {
-    // convert string to json string
-
-    jsonString := strings.ReplaceAll(status, "'", "\"")
-
-    var statusStringArray []string
-    json.Unmarshal([]byte(jsonString), &statusStringArray)
-
-	var response PaymentStatus
-	var responses []PaymentStatus
-	var payments []Payment
-	
-
-	clientID, err := ctx.GetClientIdentity().GetID()
-	if err != nil {
-		return nil, fmt.Errorf("failed getting the client's ID: %v", err)
-	}
-
-	data, err := base64.StdEncoding.DecodeString(clientID)
-	if err != nil {
-		return nil, fmt.Errorf("error: %v", err)
-	}
-	userId := strings.Split(string(data), ",")[0][9:]
-
-	// clientMSPID, err:= ctx.GetClientIdentity().GetMSPID()
-	// if err != nil {
-	// 	return nil, fmt.Errorf("failed getting the client's MSPID: %v", err)
-	// }
-	// if clientMSPID != GrantorMSP {
-	// 	return nil, fmt.Errorf("User from org %v is not authorized to read grant payments", clientMSPID)
-	// }
-
-	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey("grant", []string{})
+// GetPaymentByAwardee returns all the payments with given awardee id in a grant.
+func (s *SmartContract) GetPaymentByAwardee(ctx contractapi.TransactionContextInterface, id string, awardeeId string) ([]Payment, error) {
+	caller, err := authorize(ctx, "GetPaymentByAwardee")
 	if err != nil {
 		return nil, err
 	}
-	defer resultsIterator.Close()
-
-	for resultsIterator.HasNext() {
-		queryResponse, err := resultsIterator.Next()
-		if err != nil {
-			return nil, err
-		}
-
-		var grant Grant
-		err = json.Unmarshal(queryResponse.Value, &grant)
-		if err != nil {
-			return nil, err
-		}
-
-		for _, payment := range grant.Payment {
-			for _, paymentStatus := range statusStringArray {
-				if payment.Status == paymentStatus && (checkAwardee(grant.Awardee, userId) || checkSubAwardee(grant.Awardee, userId) || grant.Grantor_ID == userId) {
-					payments = append(payments, payment)
-				}
-			}
-			
-		}
-
-		if len(payments) != 0 {
-			response = PaymentStatus {
-				Grant_ID:	grant.ID,
-				Payment:	payments,	
-			}
-	
-			responses = append(responses, response)
-		}
-		
-		
-	}
-
-	
-	if len(responses)  == 0 {
-		var nullResponse []PaymentStatus
-		nullResponse = append(nullResponse, PaymentStatus{
-		Grant_ID: "",
-		Payment:  make([]Payment, 0),
-	})
-		return nullResponse, nil
-	} else {
-		return responses, nil
-	}
-	
-	
-}
-
-// GetPaymentByStatusForAllGrants returns all the payments with given status in a grant.
-func (s *SmartContract) GetPaymentByStatusForAllGrants(ctx contractapi.TransactionContextInterface, status []string) ([]PaymentStatus, error) {
-	
-	var response PaymentStatus
-	var responses []PaymentStatus
-	var payments []Payment
 
-	// clientMSPID, err:= ctx.GetClientIdentity().GetMSPID()
-	// if err != nil {
-	// 	return nil, fmt.Errorf("failed getting the client's MSPID: %v", err)
-	// }
-	// if clientMSPID != GrantorMSP {
-	// 	return nil, fmt.Errorf("User from org %v is not authorized to read grant payments", clientMSPID)
-	// }
-
-	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey("grant", []string{})
+	grant, access, err := readGrantAs(ctx, caller, id)
 	if err != nil {
 		return nil, err
 	}
-	defer resultsIterator.Close()
-
-	for resultsIterator.HasNext() {
-		queryResponse, err := resultsIterator.Next()
-		if err != nil {
-			return nil, err
-		}
-
-		var grant Grant
-		err = json.Unmarshal(queryResponse.Value, &grant)
-		if err != nil {
-			return nil, err
-		}
-
-		for _, payment := range grant.Payment {
-			for _, paymentStatus := range status {
-				if payment.Status == paymentStatus {
-					payments = append(payments, payment)
-				}
-			}
-			
-		}
-
-		if len(payments) != 0 {
-			response = PaymentStatus {
-				Grant_ID:	grant.ID,
-				Payment:	payments,	
-			}
-	
-			responses = append(responses, response)
-		}
-		
-	}
-
-	if len(responses)  == 0 {
-		var nullResponse []PaymentStatus
-		nullResponse = append(nullResponse, PaymentStatus{
-		Grant_ID: "",
-		Payment:  make([]Payment, 0),
-	})
-		return nullResponse, nil
-	} else {
-		return responses, nil
-	}
-}
 
-// GetPaymentByAwardee returns all the payments with given awardee id in a grant.
-func (s *SmartContract) GetPaymentByAwardee(ctx contractapi.TransactionContextInterface, id string, awardeeId string) ([]Payment, error) {
-	requestCompositeKey, _ := ctx.GetStub().CreateCompositeKey("grant", []string{id})
-	grantJSON, err := ctx.GetStub().GetState(requestCompositeKey)
+	awardeeId, err = grantAwardeeID(grant, awardeeId)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read from world state: %v", err)
+		return nil, err
 	}
-	if grantJSON == nil {
-		return nil, fmt.Errorf("the Grant %s does not exist", id)
+	if !checkAwardee(grant.Awardee, awardeeId) && !checkSubAwardee(grant.Awardee, awardeeId) {
+		return nil, awardeeNotAssigned(awardeeId, grant)	
 	}
 
-	var grant Grant
-	err = json.Unmarshal(grantJSON, &grant)
+	grantPayments, err := getGrantPayments(ctx, grant.ID)
 	if err != nil {
 		return nil, err
 	}
 
-	if !checkAwardee(grant.Awardee, awardeeId) && !checkSubAwardee(grant.Awardee, awardeeId) {
-		return nil, fmt.Errorf("Awardee %s is not assigned in the Grant %s", awardeeId, grant.ID)	
-	}
-
 	var payments []Payment
-	for _, payment := range grant.Payment {
+	for _, payment := range grantPayments {
 		if payment.Awardee_ID == awardeeId {
 			payments = append(payments, payment)
 		}
@@ -2063,93 +1587,117 @@ func (s *SmartContract) GetPaymentByAwardee(ctx contractapi.TransactionContextIn
 	if len(payments)  == 0 {
 		return []Payment{}, nil
 	} else {
-		return payments, nil
+		return viewPayments(payments, access), nil
 	}
 	
 }
 
 // GetProgress returns all the progresses in a grant with given id.
 func (s *SmartContract) GetProgress(ctx contractapi.TransactionContextInterface, id string) ([]Progress, error) {
-	requestCompositeKey, _ := ctx.GetStub().CreateCompositeKey("grant", []string{id})
-	grantJSON, err := ctx.GetStub().GetState(requestCompositeKey)
+	caller, err := authorize(ctx, "GetProgress")
 	if err != nil {
-		return nil, fmt.Errorf("failed to read from world state: %v", err)
-	}
-	if grantJSON == nil {
-		return nil, fmt.Errorf("the asset %s does not exist", id)
+		return nil, err
 	}
 
-	var grant Grant
-	err = json.Unmarshal(grantJSON, &grant)
+	grant, access, err := readGrantAs(ctx, caller, id)
 	if err != nil {
 		return nil, err
 	}
 
+	if access != accessFull {
+		return redactProgress(grant.Progress), nil
+	}
 	return grant.Progress, nil
 }
 
 // Get Remaining Amount
-func (s *SmartContract) GetRemainingAmount(ctx contractapi.TransactionContextInterface, grant_id string) (float64, error) {
-	
-	grant, err := s.ReadGrant(ctx, grant_id)
+func (s *SmartContract) GetRemainingAmount(ctx contractapi.TransactionContextInterface, grant_id string) (Money, error) {
+	caller, err := authorize(ctx, "GetRemainingAmount")
 	if err != nil {
-		return 0.0, fmt.Errorf("Grant %s does not exist", grant_id)
+		return "", err
 	}
 
-	if grant.Status == "Revoked" {
-		return 0.0, fmt.Errorf("Grant %s is revoked", grant.ID)	
+	grant, _, err := readGrantAs(ctx, caller, grant_id)
+	if err != nil {
+		return "", err
 	}
 
-	var remainingAmount float64
-	remainingAmount = grant.Amount - grant.Cashed_Out
+	err = setPaymentTotals(ctx, grant)
+	if err != nil {
+		return "", err
+	}
 
-	return remainingAmount, nil
+	if grant.Status == GrantRevoked {
+		return "", errInvalidState("Grant %s is revoked", grant.ID).with("grant_id", grant.ID).with("status", grant.Status)	
+	}
+
+	currency := grantCurrency(grant)
+	amount, err := grant.Amount.minor(currency)
+	if err != nil {
+		return "", storedAmountInvalid(err, "Grant %s has an invalid amount", grant.ID)
+	}
+	cashedOut, err := grant.Cashed_Out.minor(currency)
+	if err != nil {
+		return "", storedAmountInvalid(err, "Grant %s has an invalid amount", grant.ID)
+	}
+
+	return moneyFromMinor(amount-cashedOut, currency), nil
 }
 
 
 // DeleteAsset deletes an given asset from the world state.
 func (s *SmartContract) DeleteGrant(ctx contractapi.TransactionContextInterface, id string) (bool, error) {
-	clientID, err := ctx.GetClientIdentity().GetID()
+	caller, err := authorize(ctx, "DeleteGrant")
 	if err != nil {
-		return false, fmt.Errorf("failed getting the client's ID: %v", err)
+		return false, err
 	}
 
-	data, err := base64.StdEncoding.DecodeString(clientID)
+	grant, err := readGrant(ctx, id)
 	if err != nil {
-		return false, fmt.Errorf("error: %v", err)
+		return false, err
 	}
-	userId := strings.Split(string(data), ",")[0][9:]
 
-	clientMSPID, err:= ctx.GetClientIdentity().GetMSPID()
+	err = checkGrantOwner(caller, grant, "delete")
 	if err != nil {
-		return false, fmt.Errorf("failed getting the client's MSPID: %v", err)
+		return false, err
 	}
-	if clientMSPID != GrantorMSP {
-		return false, fmt.Errorf("User from org %v is not authorized to initiate grant", clientMSPID)
+
+	// Grants that were ever accepted keep their payment history, they can only be revoked or closed
+	err = checkGrantStatus(grant, GrantDraft, GrantRejected)
+	if err != nil {
+		return false, err
 	}
-	
-	grant, err := s.ReadGrant(ctx, id)
+
+	err = deleteAwardeePrivateDetails(ctx, grant)
 	if err != nil {
-		return false, fmt.Errorf("failed to read from world state: %v", err)
+		return false, err
 	}
-	if grant == nil {
-		return false, fmt.Errorf("the grant %s doesn't exist", id)
+
+	err = deleteGrantPayments(ctx, id)
+	if err != nil {
+		return false, err
 	}
 
-	if grant.Grantor_ID != userId {
-		return false, fmt.Errorf("User %s from org %v is not authorized to assign grant for this grant %s",userId, clientMSPID, grant.ID)
+	err = deleteGrantIndexes(ctx, grant)
+	if err != nil {
+		return false, err
 	}
 
 	requestCompositeKey, _ := ctx.GetStub().CreateCompositeKey("grant", []string{id})
 	err = ctx.GetStub().DelState(requestCompositeKey)
 	if err != nil {
-		return false, fmt.Errorf("Deleting Grant failed", err)
+		return false, errInternal("Deleting Grant failed: %v", err)
+	}
+
+	err = emitGrantEvent(ctx, caller, EventGrantDeleted, grant, grant.Status)
+	if err != nil {
+		return false, err
 	}
 
 	return true, nil
 }
 
-func checkBenefitAmount(itemAmount float64, benefitAmount float64, totalBenefit float64, awardeeType string, percentage float64) (bool) {
+func checkBenefitAmount(itemAmount int64, benefitAmount int64, totalBenefit int64, awardeeType string, percentage float64) (bool) {
 	switch awardeeType {
 		case "Main":
 			if itemAmount <= benefitAmount && totalBenefit <= benefitAmount {
@@ -2158,7 +1706,7 @@ func checkBenefitAmount(itemAmount float64, benefitAmount float64, totalBenefit
 				return false
 			}
 		case "Sub":
-			if itemAmount <= benefitAmount && totalBenefit <= (percentage*benefitAmount)/100 {
+			if itemAmount <= benefitAmount && totalBenefit <= percentOf(benefitAmount, percentage) {
 				return true
 			} else {
 				return false
@@ -2192,17 +1740,6 @@ func checkSubAwardee(awardees []Awardee, userId string) (bool) {
 	return flag
 }
 
-func checkPayment(payments []Payment, payment_id string) (bool) {
-	flag := false
-	for _, payment := range payments {
-		if payment.ID == payment_id {
-			flag = true
-			break
-		}
-	}
-	return flag
-}
-
 
 
 
@@ -0,0 +1,761 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"chaincode-go/chaincodetest"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// The tests run the contract against the in-memory ledger of chaincodetest.
+// Each test starts from an empty ledger.
+
+// Roles are required, so each test user carries one; the grantor is an admin
+// and holds every role of the grantor org.
+var (
+	grantor    = chaincodetest.NewIdentity(GrantorMSP, "alice").WithAttribute(roleAttribute, string(RoleAdmin))
+	awardee    = chaincodetest.NewIdentity(AwardeeMSP, "bob").WithAttribute(roleAttribute, string(RolePI))
+	subawardee = chaincodetest.NewIdentity(SubawardeeMSP, "carol").WithAttribute(roleAttribute, string(RolePI))
+	auditor    = chaincodetest.NewIdentity(AuditorMSP, "dave").WithAttribute(roleAttribute, string(RoleAuditor))
+)
+
+const testSalt = "0123456789abcdef"
+
+type ledger struct {
+	t        *testing.T
+	stub     *chaincodetest.Stub
+	contract *SmartContract
+}
+
+func newLedger(t *testing.T) *ledger {
+	t.Helper()
+	return &ledger{t: t, stub: chaincodetest.NewStub(), contract: new(SmartContract)}
+}
+
+// run calls fn as identity in one transaction with transient inputs
+// marshaled to JSON, committing unless it fails.
+func (l *ledger) run(identity *chaincodetest.Identity, transient map[string]interface{}, fn func(ctx contractapi.TransactionContextInterface) error) error {
+	l.t.Helper()
+	transientMap := map[string][]byte{}
+	for key, value := range transient {
+		if raw, ok := value.(string); ok {
+			transientMap[key] = []byte(raw)
+			continue
+		}
+		valueJSON, err := json.Marshal(value)
+		if err != nil {
+			l.t.Fatalf("marshal %s: %v", key, err)
+		}
+		transientMap[key] = valueJSON
+	}
+	ctx := chaincodetest.NewContext(l.stub, identity)
+	return l.stub.Run(transientMap, func() error {
+		return fn(ctx)
+	})
+}
+
+// must fails the test if err is not nil.
+func (l *ledger) must(err error) {
+	l.t.Helper()
+	if err != nil {
+		l.t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// noError fails the test if err is not nil.
+func noError(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// wantCode fails the test unless err is a ContractError with code.
+func wantCode(t *testing.T, err error, code ErrorCode) {
+	t.Helper()
+	var contractErr *ContractError
+	if !errors.As(err, &contractErr) {
+		t.Fatalf("got error %v, want a %s ContractError", err, code)
+	}
+	if contractErr.Code != code {
+		t.Fatalf("got %s error %s, want %s", contractErr.Code, contractErr.Message, code)
+	}
+}
+
+// newGrant returns a grant input of 1000 USD over Personnel and Equipment
+// running through 2022.
+func newGrant(id string) map[string]interface{} {
+	return map[string]interface{}{
+		"ID":       id,
+		"amount":   "1000",
+		"currency": "USD",
+		"benefit": []map[string]interface{}{
+			{"benefit": "Personnel", "amount": "600"},
+			{"benefit": "Equipment", "amount": "400"},
+		},
+		"sub":        20,
+		"start_date": "2022-01-01",
+		"end_date":   "2022-12-31",
+	}
+}
+
+func (l *ledger) initiate(grant map[string]interface{}) error {
+	l.t.Helper()
+	return l.run(grantor, map[string]interface{}{"grant": grant}, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.InitiateGrant(ctx)
+		return err
+	})
+}
+
+func testAwardee(id string, awardeeType string, organization string) map[string]interface{} {
+	return map[string]interface{}{
+		"id":                     id,
+		"name":                   "University of " + id,
+		"principal_investigator": id,
+		"organization":           organization,
+		"contact":                id + "@example.org",
+		"account_number":         "ACC-" + id,
+		"awardee_type":           awardeeType,
+	}
+}
+
+func (l *ledger) assign(id string, awardees ...map[string]interface{}) error {
+	l.t.Helper()
+	input := map[string]interface{}{"grant_id": id, "awardee": awardees}
+	return l.run(grantor, map[string]interface{}{"assign_grant": input, "salt": testSalt}, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.AssignGrant(ctx)
+		return err
+	})
+}
+
+// activeGrant creates the grant id, assigned to bob and accepted.
+func (l *ledger) activeGrant(id string) {
+	l.t.Helper()
+	l.must(l.initiate(newGrant(id)))
+	l.must(l.assign(id, testAwardee("bob", "Main", AwardeeMSP)))
+	l.must(l.run(awardee, nil, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.AcceptGrant(ctx, id)
+		return err
+	}))
+}
+
+// addSubawardee has bob add carol as subawardee of the grant.
+func (l *ledger) addSubawardee(id string) {
+	l.t.Helper()
+	input := map[string]interface{}{"grant_id": id, "awardee": testAwardee("carol", "Sub", SubawardeeMSP)}
+	l.must(l.run(awardee, map[string]interface{}{"add_subawardee": input, "salt": testSalt}, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.AddSubawardee(ctx)
+		return err
+	}))
+}
+
+// reimburse requests a reimbursement as identity for the awardee with the items.
+func (l *ledger) reimburse(identity *chaincodetest.Identity, grantID string, paymentID string, awardeeID string, items ...map[string]interface{}) (string, error) {
+	l.t.Helper()
+	input := map[string]interface{}{"ID": paymentID, "grant_id": grantID, "awardee_id": awardeeID, "item": items}
+	var status string
+	err := l.run(identity, map[string]interface{}{"request_reimbursement": input}, func(ctx contractapi.TransactionContextInterface) error {
+		var err error
+		status, err = l.contract.RequestReimbursement(ctx)
+		return err
+	})
+	return status, err
+}
+
+// payment reads the payment of the grant as the grantor.
+func (l *ledger) payment(grantID string, paymentID string) Payment {
+	l.t.Helper()
+	var payments []Payment
+	l.must(l.run(grantor, nil, func(ctx contractapi.TransactionContextInterface) error {
+		var err error
+		payments, err = l.contract.GetPayments(ctx, grantID)
+		return err
+	}))
+	for _, payment := range payments {
+		if payment.ID == paymentID {
+			return payment
+		}
+	}
+	l.t.Fatalf("payment %s of %s not found", paymentID, grantID)
+	return Payment{}
+}
+
+func item(benefit string, amount string) map[string]interface{} {
+	return map[string]interface{}{"benefit": benefit, "amount": amount}
+}
+
+// read reads the grant as identity.
+func (l *ledger) read(identity *chaincodetest.Identity, id string) (*Grant, error) {
+	l.t.Helper()
+	var grant *Grant
+	err := l.run(identity, nil, func(ctx contractapi.TransactionContextInterface) error {
+		var err error
+		grant, err = l.contract.ReadGrant(ctx, id)
+		return err
+	})
+	return grant, err
+}
+
+// contractCall is one transaction of a table-driven test.
+type contractCall struct {
+	name      string
+	identity  *chaincodetest.Identity
+	transient map[string]interface{}
+	call      func(s *SmartContract, ctx contractapi.TransactionContextInterface) error
+}
+
+// TestTransactionAuthorization submits every mutating transaction from an org
+// that may not call it.
+func TestTransactionAuthorization(t *testing.T) {
+	tests := []contractCall{
+		{"InitiateGrant as awardee", awardee, map[string]interface{}{"grant": newGrant("G2")}, func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.InitiateGrant(ctx)
+			return err
+		}},
+		{"InitiateGrant as subawardee", subawardee, map[string]interface{}{"grant": newGrant("G2")}, func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.InitiateGrant(ctx)
+			return err
+		}},
+		{"AssignGrant as awardee", awardee, map[string]interface{}{"assign_grant": map[string]interface{}{"grant_id": "G1"}, "salt": testSalt}, func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.AssignGrant(ctx)
+			return err
+		}},
+		{"AcceptGrant as grantor", grantor, nil, func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.AcceptGrant(ctx, "G1")
+			return err
+		}},
+		{"AcceptGrant as subawardee", subawardee, nil, func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.AcceptGrant(ctx, "G1")
+			return err
+		}},
+		{"RejectGrant as grantor", grantor, nil, func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.RejectGrant(ctx, "G1")
+			return err
+		}},
+		{"RevokeGrant as awardee", awardee, nil, func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.RevokeGrant(ctx, "G1")
+			return err
+		}},
+		{"UpdateGrant as awardee", awardee, map[string]interface{}{"update_grant": newGrant("G1")}, func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.UpdateGrant(ctx)
+			return err
+		}},
+		{"RequestReimbursement as grantor", grantor, map[string]interface{}{"request_reimbursement": map[string]interface{}{"ID": "P2", "grant_id": "G1", "awardee_id": "AwardeeMSP/bob", "item": []map[string]interface{}{item("Personnel", "10")}}}, func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.RequestReimbursement(ctx)
+			return err
+		}},
+		{"AcceptReimbursement as awardee", awardee, nil, func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.AcceptReimbursement(ctx, "G1", "P1")
+			return err
+		}},
+		{"AcceptReimbursement as subawardee", subawardee, nil, func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.AcceptReimbursement(ctx, "G1", "P1")
+			return err
+		}},
+		{"RejectReimbursement as awardee", awardee, nil, func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.RejectReimbursement(ctx, "G1", "P1", "MISSING_DOCUMENTATION", "no receipts")
+			return err
+		}},
+		{"RedeemTokens as grantor", grantor, nil, func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.RedeemTokens(ctx, "G1", "P1")
+			return err
+		}},
+		{"AcceptRedeem as awardee", awardee, nil, func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.AcceptRedeem(ctx, "G1", "P1")
+			return err
+		}},
+		{"RejectRedeem as subawardee", subawardee, nil, func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.RejectRedeem(ctx, "G1", "P1", "MISSING_DOCUMENTATION", "no receipts")
+			return err
+		}},
+		{"AddAwardee as awardee", awardee, map[string]interface{}{"add_awardee": map[string]interface{}{"grant_id": "G1", "awardee": testAwardee("erin", "Main", AwardeeMSP)}, "salt": testSalt}, func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.AddAwardee(ctx)
+			return err
+		}},
+		{"AddSubawardee as grantor", grantor, map[string]interface{}{"add_subawardee": map[string]interface{}{"grant_id": "G1", "awardee": testAwardee("carol", "Sub", SubawardeeMSP)}, "salt": testSalt}, func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.AddSubawardee(ctx)
+			return err
+		}},
+		{"AddSubawardee as subawardee", subawardee, map[string]interface{}{"add_subawardee": map[string]interface{}{"grant_id": "G1", "awardee": testAwardee("carol", "Sub", SubawardeeMSP)}, "salt": testSalt}, func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.AddSubawardee(ctx)
+			return err
+		}},
+		{"AddProgress as grantor", grantor, map[string]interface{}{"add_progress": map[string]interface{}{"grant_id": "G1", "progress": map[string]interface{}{"percentage": "10"}}}, func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.AddProgress(ctx)
+			return err
+		}},
+		{"SuspendGrant as awardee", awardee, nil, func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.SuspendGrant(ctx, "G1")
+			return err
+		}},
+		{"ReinstateGrant as subawardee", subawardee, nil, func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.ReinstateGrant(ctx, "G1")
+			return err
+		}},
+		{"CloseGrant as awardee", awardee, nil, func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.CloseGrant(ctx, "G1")
+			return err
+		}},
+		{"MigrateMoney as awardee", awardee, nil, func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.MigrateMoney(ctx)
+			return err
+		}},
+		{"MigratePayments as subawardee", subawardee, nil, func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.MigratePayments(ctx, 10, "")
+			return err
+		}},
+		{"RebuildIndexes as awardee", awardee, nil, func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.RebuildIndexes(ctx)
+			return err
+		}},
+		{"DeleteGrant as awardee", awardee, nil, func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.DeleteGrant(ctx, "G1")
+			return err
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newLedger(t)
+			l.activeGrant("G1")
+			_, err := l.reimburse(awardee, "G1", "P1", "AwardeeMSP/bob", item("Personnel", "100"))
+			noError(t, err)
+
+			err = l.run(tt.identity, tt.transient, func(ctx contractapi.TransactionContextInterface) error {
+				return tt.call(l.contract, ctx)
+			})
+			wantCode(t, err, CodeForbidden)
+		})
+	}
+}
+
+// TestGrantTransactions walks a grant through its lifecycle one transaction
+// at a time; each step must succeed or fail as listed.
+func TestGrantTransactions(t *testing.T) {
+	otherGrantor := chaincodetest.NewIdentity(GrantorMSP, "eve").WithAttribute(roleAttribute, string(RoleProgramOfficer))
+	reimbursement := func(paymentID string, awardeeID string, items ...map[string]interface{}) map[string]interface{} {
+		input := map[string]interface{}{"ID": paymentID, "grant_id": "G1", "awardee_id": awardeeID, "item": items}
+		return map[string]interface{}{"request_reimbursement": input}
+	}
+
+	steps := []struct {
+		contractCall
+		wantErr ErrorCode
+	}{
+		{contractCall{"InitiateGrant", grantor, map[string]interface{}{"grant": newGrant("G1")}, func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.InitiateGrant(ctx)
+			return err
+		}}, ""},
+		{contractCall{"InitiateGrant twice", grantor, map[string]interface{}{"grant": newGrant("G1")}, func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.InitiateGrant(ctx)
+			return err
+		}}, CodeDuplicate},
+		{contractCall{"InitiateGrant with an unbalanced budget", grantor, map[string]interface{}{"grant": map[string]interface{}{"ID": "G2", "amount": "10", "currency": "USD", "benefit": []map[string]interface{}{item("Personnel", "9")}}}, func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.InitiateGrant(ctx)
+			return err
+		}}, CodeValidation},
+		{contractCall{"AcceptGrant before it is assigned", awardee, nil, func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.AcceptGrant(ctx, "G1")
+			return err
+		}}, CodeForbidden},
+		{contractCall{"AssignGrant by another grantor", otherGrantor, map[string]interface{}{"assign_grant": map[string]interface{}{"grant_id": "G1", "awardee": []map[string]interface{}{testAwardee("bob", "Main", AwardeeMSP)}}, "salt": testSalt}, func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.AssignGrant(ctx)
+			return err
+		}}, CodeForbidden},
+		{contractCall{"AssignGrant without a salt", grantor, map[string]interface{}{"assign_grant": map[string]interface{}{"grant_id": "G1", "awardee": []map[string]interface{}{testAwardee("bob", "Main", AwardeeMSP)}}}, func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.AssignGrant(ctx)
+			return err
+		}}, CodeValidation},
+		{contractCall{"AssignGrant", grantor, map[string]interface{}{"assign_grant": map[string]interface{}{"grant_id": "G1", "awardee": []map[string]interface{}{testAwardee("bob", "Main", AwardeeMSP)}}, "salt": testSalt}, func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.AssignGrant(ctx)
+			return err
+		}}, ""},
+		{contractCall{"AcceptGrant", awardee, nil, func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.AcceptGrant(ctx, "G1")
+			return err
+		}}, ""},
+		{contractCall{"RejectGrant once active", awardee, nil, func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.RejectGrant(ctx, "G1")
+			return err
+		}}, CodeInvalidState},
+		{contractCall{"AddSubawardee", awardee, map[string]interface{}{"add_subawardee": map[string]interface{}{"grant_id": "G1", "awardee": testAwardee("carol", "Sub", SubawardeeMSP)}, "salt": testSalt}, func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.AddSubawardee(ctx)
+			return err
+		}}, ""},
+		{contractCall{"AddAwardee", grantor, map[string]interface{}{"add_awardee": map[string]interface{}{"grant_id": "G1", "awardee": testAwardee("erin", "Main", AwardeeMSP)}, "salt": testSalt}, func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.AddAwardee(ctx)
+			return err
+		}}, ""},
+		{contractCall{"RequestReimbursement", awardee, reimbursement("P1", "AwardeeMSP/bob", item("Personnel", "100")), func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.RequestReimbursement(ctx)
+			return err
+		}}, ""},
+		{contractCall{"RequestReimbursement with a used ID", awardee, reimbursement("P1", "AwardeeMSP/bob", item("Personnel", "100")), func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.RequestReimbursement(ctx)
+			return err
+		}}, CodeDuplicate},
+		{contractCall{"RequestReimbursement for someone else", awardee, reimbursement("P2", "SubawardeeMSP/carol", item("Personnel", "10")), func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.RequestReimbursement(ctx)
+			return err
+		}}, CodeForbidden},
+		{contractCall{"RequestReimbursement over the benefit", awardee, reimbursement("P2", "AwardeeMSP/bob", item("Equipment", "401")), func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.RequestReimbursement(ctx)
+			return err
+		}}, CodeBudgetExceeded},
+		{contractCall{"RequestReimbursement over the subaward share", subawardee, reimbursement("P2", "SubawardeeMSP/carol", item("Personnel", "121")), func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.RequestReimbursement(ctx)
+			return err
+		}}, CodeBudgetExceeded},
+		{contractCall{"RequestReimbursement by the subawardee", subawardee, reimbursement("P2", "SubawardeeMSP/carol", item("Equipment", "50")), func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.RequestReimbursement(ctx)
+			return err
+		}}, ""},
+		{contractCall{"RedeemTokens before acceptance", awardee, nil, func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.RedeemTokens(ctx, "G1", "P1")
+			return err
+		}}, CodeInvalidState},
+		{contractCall{"AcceptReimbursement by another grantor", otherGrantor, nil, func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.AcceptReimbursement(ctx, "G1", "P1")
+			return err
+		}}, CodeForbidden},
+		{contractCall{"AcceptReimbursement", grantor, nil, func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.AcceptReimbursement(ctx, "G1", "P1")
+			return err
+		}}, ""},
+		{contractCall{"RejectReimbursement without a known code", grantor, nil, func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.RejectReimbursement(ctx, "G1", "P2", "because", "")
+			return err
+		}}, CodeValidation},
+		{contractCall{"RejectReimbursement", grantor, nil, func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.RejectReimbursement(ctx, "G1", "P2", "MISSING_DOCUMENTATION", "no receipts")
+			return err
+		}}, ""},
+		{contractCall{"RedeemTokens by the subawardee", subawardee, nil, func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.RedeemTokens(ctx, "G1", "P1")
+			return err
+		}}, CodeForbidden},
+		{contractCall{"RedeemTokens", awardee, nil, func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.RedeemTokens(ctx, "G1", "P1")
+			return err
+		}}, ""},
+		{contractCall{"RejectRedeem", grantor, nil, func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.RejectRedeem(ctx, "G1", "P1", "OTHER", "closed account")
+			return err
+		}}, ""},
+		{contractCall{"RedeemTokens once the redeem was rejected", awardee, nil, func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.RedeemTokens(ctx, "G1", "P1")
+			return err
+		}}, CodeInvalidState},
+		{contractCall{"RequestReimbursement again", awardee, reimbursement("P3", "AwardeeMSP/bob", item("Personnel", "100")), func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.RequestReimbursement(ctx)
+			return err
+		}}, ""},
+		{contractCall{"AcceptReimbursement again", grantor, nil, func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.AcceptReimbursement(ctx, "G1", "P3")
+			return err
+		}}, ""},
+		{contractCall{"RedeemTokens again", awardee, nil, func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.RedeemTokens(ctx, "G1", "P3")
+			return err
+		}}, ""},
+		{contractCall{"AcceptRedeem", grantor, nil, func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.AcceptRedeem(ctx, "G1", "P3")
+			return err
+		}}, ""},
+		{contractCall{"AddProgress", subawardee, map[string]interface{}{"add_progress": map[string]interface{}{"grant_id": "G1", "progress": map[string]interface{}{"notes": "kickoff", "percentage": "10"}}}, func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.AddProgress(ctx)
+			return err
+		}}, ""},
+		{contractCall{"SuspendGrant", grantor, nil, func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.SuspendGrant(ctx, "G1")
+			return err
+		}}, ""},
+		{contractCall{"RequestReimbursement while suspended", awardee, reimbursement("P4", "AwardeeMSP/bob", item("Personnel", "10")), func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.RequestReimbursement(ctx)
+			return err
+		}}, CodeInvalidState},
+		{contractCall{"ReinstateGrant", grantor, nil, func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.ReinstateGrant(ctx, "G1")
+			return err
+		}}, ""},
+		{contractCall{"DeleteGrant once accepted", grantor, nil, func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.DeleteGrant(ctx, "G1")
+			return err
+		}}, CodeInvalidState},
+		{contractCall{"CloseGrant", grantor, nil, func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.CloseGrant(ctx, "G1")
+			return err
+		}}, ""},
+		{contractCall{"RevokeGrant once closed", grantor, nil, func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.RevokeGrant(ctx, "G1")
+			return err
+		}}, CodeInvalidState},
+	}
+
+	l := newLedger(t)
+	for _, step := range steps {
+		err := l.run(step.identity, step.transient, func(ctx contractapi.TransactionContextInterface) error {
+			return step.call(l.contract, ctx)
+		})
+		if step.wantErr == "" {
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %v", step.name, err)
+			}
+			continue
+		}
+		var contractErr *ContractError
+		if !errors.As(err, &contractErr) || contractErr.Code != step.wantErr {
+			t.Fatalf("%s: got error %v, want a %s error", step.name, err, step.wantErr)
+		}
+	}
+
+	grant, err := l.read(grantor, "G1")
+	noError(t, err)
+	if grant.Status != GrantClosed || grant.Cashed_Out != "100.00" || len(grant.Awardee) != 3 || len(grant.Progress) != 1 {
+		t.Fatalf("got grant %+v", grant)
+	}
+}
+
+// TestGrantDeletion deletes draft and rejected grants, with their payments
+// and private details.
+func TestGrantDeletion(t *testing.T) {
+	tests := []struct {
+		name   string
+		reject bool
+	}{
+		{"draft", false},
+		{"rejected", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newLedger(t)
+			l.must(l.initiate(newGrant("G1")))
+			if tt.reject {
+				l.must(l.assign("G1", testAwardee("bob", "Main", AwardeeMSP)))
+				l.must(l.run(awardee, nil, func(ctx contractapi.TransactionContextInterface) error {
+					_, err := l.contract.RejectGrant(ctx, "G1")
+					return err
+				}))
+			}
+
+			err := l.run(grantor, nil, func(ctx contractapi.TransactionContextInterface) error {
+				_, err := l.contract.DeleteGrant(ctx, "G1")
+				return err
+			})
+			noError(t, err)
+
+			_, err = l.read(grantor, "G1")
+			wantCode(t, err, CodeNotFound)
+			key, err := l.stub.CreateCompositeKey(awardeePrivateObjectType, []string{"G1", "AwardeeMSP/bob"})
+			noError(t, err)
+			if details := l.stub.PrivateState(AwardeeCollection, key); details != nil {
+				t.Fatalf("private details %s were left behind", details)
+			}
+		})
+	}
+}
+
+// TestQueries reads a grant with a redeemed payment of bob, a requested
+// payment of carol and a progress report through every query function.
+func TestQueries(t *testing.T) {
+	l := newLedger(t)
+	l.activeGrant("G1")
+	l.addSubawardee("G1")
+	l.must(l.initiate(newGrant("G2")))
+	_, err := l.reimburse(awardee, "G1", "P1", "AwardeeMSP/bob", item("Personnel", "100"))
+	l.must(err)
+	_, err = l.reimburse(subawardee, "G1", "P2", "SubawardeeMSP/carol", item("Equipment", "25.50"))
+	l.must(err)
+	l.must(l.run(grantor, nil, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.AcceptReimbursement(ctx, "G1", "P1")
+		return err
+	}))
+	l.must(l.run(awardee, nil, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.RedeemTokens(ctx, "G1", "P1")
+		return err
+	}))
+	l.must(l.run(grantor, nil, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.AcceptRedeem(ctx, "G1", "P1")
+		return err
+	}))
+	progress := map[string]interface{}{"grant_id": "G1", "progress": map[string]interface{}{"notes": "kickoff", "percentage": "10"}}
+	l.must(l.run(awardee, map[string]interface{}{"add_progress": progress}, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.AddProgress(ctx)
+		return err
+	}))
+
+	tests := []struct {
+		name     string
+		identity *chaincodetest.Identity
+		query    func(s *SmartContract, ctx contractapi.TransactionContextInterface) (interface{}, error)
+		want     string
+	}{
+		{"ReadGrant", grantor, func(s *SmartContract, ctx contractapi.TransactionContextInterface) (interface{}, error) {
+			grant, err := s.ReadGrant(ctx, "G1")
+			if err != nil {
+				return nil, err
+			}
+			return []interface{}{grant.Status, grant.Cashed_Out}, nil
+		}, `["Active","100.00"]`},
+		{"GetAllGrants", grantor, func(s *SmartContract, ctx contractapi.TransactionContextInterface) (interface{}, error) {
+			grants, err := s.GetAllGrants(ctx)
+			return len(grants), err
+		}, `2`},
+		{"GetAllGrantsUser as subawardee", subawardee, func(s *SmartContract, ctx contractapi.TransactionContextInterface) (interface{}, error) {
+			grants, err := s.GetAllGrantsUser(ctx)
+			return len(grants), err
+		}, `1`},
+		{"GetAllApprovedGrants as grantor", grantor, func(s *SmartContract, ctx contractapi.TransactionContextInterface) (interface{}, error) {
+			grants, err := s.GetAllApprovedGrants(ctx)
+			return len(grants), err
+		}, `1`},
+		{"GetGrantsByStatus", grantor, func(s *SmartContract, ctx contractapi.TransactionContextInterface) (interface{}, error) {
+			grants, err := s.GetGrantsByStatus(ctx, "Draft")
+			if err != nil || len(grants) != 1 {
+				return len(grants), err
+			}
+			return grants[0].ID, nil
+		}, `"G2"`},
+		{"GetGrantsByStatus with a legacy status", grantor, func(s *SmartContract, ctx contractapi.TransactionContextInterface) (interface{}, error) {
+			grants, err := s.GetGrantsByStatus(ctx, "Approved")
+			return len(grants), err
+		}, `1`},
+		{"GetGrantBenefits", awardee, func(s *SmartContract, ctx contractapi.TransactionContextInterface) (interface{}, error) {
+			return s.GetGrantBenefits(ctx, "G1")
+		}, `[{"benefit":"Personnel","amount":"600.00"},{"benefit":"Equipment","amount":"400.00"}]`},
+		{"GetPayments", grantor, func(s *SmartContract, ctx contractapi.TransactionContextInterface) (interface{}, error) {
+			payments, err := s.GetPayments(ctx, "G1")
+			return len(payments), err
+		}, `2`},
+		{"QueryPayments", awardee, func(s *SmartContract, ctx contractapi.TransactionContextInterface) (interface{}, error) {
+			responses, err := s.QueryPayments(ctx, `{"statuses":["Requested"]}`)
+			if err != nil || len(responses) != 1 || len(responses[0].Payment) != 1 {
+				return responses, err
+			}
+			return responses[0].Payment[0].ID, nil
+		}, `"P2"`},
+		{"GetPaymentByAwardee", grantor, func(s *SmartContract, ctx contractapi.TransactionContextInterface) (interface{}, error) {
+			payments, err := s.GetPaymentByAwardee(ctx, "G1", "SubawardeeMSP/carol")
+			if err != nil || len(payments) != 1 {
+				return payments, err
+			}
+			return payments[0].Total, nil
+		}, `"25.50"`},
+		{"GetProgress", subawardee, func(s *SmartContract, ctx contractapi.TransactionContextInterface) (interface{}, error) {
+			progress, err := s.GetProgress(ctx, "G1")
+			return len(progress), err
+		}, `1`},
+		{"GetRemainingAmount", grantor, func(s *SmartContract, ctx contractapi.TransactionContextInterface) (interface{}, error) {
+			return s.GetRemainingAmount(ctx, "G1")
+		}, `"900.00"`},
+		{"GetWallet", grantor, func(s *SmartContract, ctx contractapi.TransactionContextInterface) (interface{}, error) {
+			return s.GetWallet(ctx, "G1", "AwardeeMSP/bob", "Accept_redeem")
+		}, `"100.00"`},
+		{"MyWallet", awardee, func(s *SmartContract, ctx contractapi.TransactionContextInterface) (interface{}, error) {
+			return s.MyWallet(ctx, "G1")
+		}, `{"cashedOut":"100.00","requestedAmount":"0.00"}`},
+		{"GetAllowedTransitions", grantor, func(s *SmartContract, ctx contractapi.TransactionContextInterface) (interface{}, error) {
+			return s.GetAllowedTransitions(ctx, "G2")
+		}, `[{"status":"Assigned","function":"AssignGrant"}]`},
+		{"GetGrantHistory", grantor, func(s *SmartContract, ctx contractapi.TransactionContextInterface) (interface{}, error) {
+			versions, err := s.GetGrantHistory(ctx, "G1")
+			var statuses []GrantStatus
+			for _, version := range versions {
+				statuses = append(statuses, version.Grant.Status)
+			}
+			return statuses, err
+		}, `["Draft","Assigned","Active","Active","Active"]`},
+		{"GetPaymentHistory", awardee, func(s *SmartContract, ctx contractapi.TransactionContextInterface) (interface{}, error) {
+			changes, err := s.GetPaymentHistory(ctx, "G1", "P1")
+			var statuses []PaymentStatus
+			for _, change := range changes {
+				statuses = append(statuses, change.Status)
+			}
+			return statuses, err
+		}, `["Requested","Accepted","Pending-redeem","Accept_redeem"]`},
+		{"ReadAwardeePrivateDetails", subawardee, func(s *SmartContract, ctx contractapi.TransactionContextInterface) (interface{}, error) {
+			details, err := s.ReadAwardeePrivateDetails(ctx, "G1", "SubawardeeMSP/carol")
+			if err != nil {
+				return nil, err
+			}
+			return []string{details.Account_Number, details.Contact}, nil
+		}, `["ACC-carol","carol@example.org"]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got interface{}
+			err := l.run(tt.identity, nil, func(ctx contractapi.TransactionContextInterface) error {
+				var err error
+				got, err = tt.query(l.contract, ctx)
+				return err
+			})
+			noError(t, err)
+			gotJSON, err := json.Marshal(got)
+			noError(t, err)
+			if string(gotJSON) != tt.want {
+				t.Fatalf("got %s, want %s", gotJSON, tt.want)
+			}
+		})
+	}
+}
+
+// TestQueryFailures covers the lookups of grants, payments and private
+// details that are missing or not the caller's.
+func TestQueryFailures(t *testing.T) {
+	l := newLedger(t)
+	l.activeGrant("G1")
+
+	tests := []struct {
+		name     string
+		identity *chaincodetest.Identity
+		query    func(s *SmartContract, ctx contractapi.TransactionContextInterface) error
+		want     ErrorCode
+	}{
+		{"ReadGrant of a missing grant", grantor, func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.ReadGrant(ctx, "G9")
+			return err
+		}, CodeNotFound},
+		{"GetPayments of a missing grant", grantor, func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.GetPayments(ctx, "G9")
+			return err
+		}, CodeNotFound},
+		{"GetPaymentByAwardee of a stranger", grantor, func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.GetPaymentByAwardee(ctx, "G1", "mallory")
+			return err
+		}, CodeForbidden},
+		{"GetWallet with an unknown status", grantor, func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.GetWallet(ctx, "G1", "AwardeeMSP/bob", "Paid")
+			return err
+		}, CodeValidation},
+		{"MyWallet of a stranger", subawardee, func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.MyWallet(ctx, "G1")
+			return err
+		}, CodeForbidden},
+		{"GetGrantsByStatus with an unknown status", grantor, func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.GetGrantsByStatus(ctx, "Approved-ish")
+			return err
+		}, CodeValidation},
+		{"GetPaymentHistory of a missing payment", grantor, func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.GetPaymentHistory(ctx, "G1", "P9")
+			return err
+		}, CodeNotFound},
+		{"ReadAwardeePrivateDetails outside the collection", subawardee, func(s *SmartContract, ctx contractapi.TransactionContextInterface) error {
+			_, err := s.ReadAwardeePrivateDetails(ctx, "G1", "AwardeeMSP/bob")
+			return err
+		}, CodeForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := l.run(tt.identity, nil, func(ctx contractapi.TransactionContextInterface) error {
+				return tt.query(l.contract, ctx)
+			})
+			wantCode(t, err, tt.want)
+		})
+	}
+}
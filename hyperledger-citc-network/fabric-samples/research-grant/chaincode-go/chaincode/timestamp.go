@@ -0,0 +1,69 @@
+package chaincode
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// legacyTimeLayout is the peer-local format RequestReimbursement used to stamp
+// payments with before timestamps were taken from the transaction header.
+const legacyTimeLayout = "01-02-2006 15:04:05"
+
+// txTimestamp returns the client-supplied transaction timestamp in UTC. Every
+// endorsing peer sees the same value, unlike time.Now().
+func txTimestamp(ctx contractapi.TransactionContextInterface) (time.Time, error) {
+	ts, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return time.Time{}, errInternal("failed to read the transaction timestamp: %v", err)
+	}
+	if ts == nil {
+		return time.Time{}, errInternal("transaction has no timestamp")
+	}
+
+	return time.Unix(ts.GetSeconds(), int64(ts.GetNanos())).UTC(), nil
+}
+
+// txTime returns the transaction timestamp formatted as RFC 3339 UTC, the
+// format every timestamp recorded by the chaincode is stored in.
+func txTime(ctx contractapi.TransactionContextInterface) (string, error) {
+	now, err := txTimestamp(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return now.Format(time.RFC3339), nil
+}
+
+// normalizeTime converts a stored timestamp to RFC 3339 UTC. Values written in
+// the legacy layout are assumed to be UTC; anything unparseable is returned as is.
+func normalizeTime(value string) string {
+	if value == "" {
+		return value
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC().Format(time.RFC3339)
+	}
+	if t, err := time.Parse(legacyTimeLayout, value); err == nil {
+		return t.UTC().Format(time.RFC3339)
+	}
+
+	return value
+}
+
+// dateLayout is the calendar-date format grant start and end dates may be given in.
+const dateLayout = "2006-01-02"
+
+// parseDate parses a date given either as a calendar date or as an RFC 3339
+// timestamp. Calendar dates are taken as midnight UTC.
+func parseDate(value string) (time.Time, error) {
+	if t, err := time.Parse(dateLayout, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is not a YYYY-MM-DD date or an RFC 3339 timestamp", value)
+	}
+	return t.UTC(), nil
+}
@@ -0,0 +1,21 @@
+package chaincode
+
+import "testing"
+
+func TestNormalizeTime(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: "", want: ""},
+		{value: "2022-03-01T10:20:30Z", want: "2022-03-01T10:20:30Z"},
+		{value: "2022-03-01T12:20:30+02:00", want: "2022-03-01T10:20:30Z"},
+		{value: "03-01-2022 10:20:30", want: "2022-03-01T10:20:30Z"},
+		{value: "yesterday", want: "yesterday"},
+	}
+	for _, test := range tests {
+		if got := normalizeTime(test.value); got != test.want {
+			t.Errorf("normalizeTime(%q) = %q, want %q", test.value, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,387 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Inputs passed in the transient map are decoded by readTransientInput and
+// checked against the rules of their input type before a transaction acts on
+// them. Each input type declares its rules in a validate method; the checks
+// collect every violation instead of stopping at the first one, so a client
+// can fix all of them from a single response.
+
+// FieldError is a rule violated by one field of an input. Field is the JSON
+// path of the field, e.g. "awardee[1].contact".
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// validatable is an input type with validation rules.
+type validatable interface {
+	validate(v *validator)
+}
+
+// validator collects the violations found in an input.
+type validator struct {
+	input  string
+	fields []FieldError
+}
+
+func (v *validator) add(field string, format string, args ...interface{}) {
+	v.fields = append(v.fields, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// err returns a VALIDATION error listing the violations in its message and,
+// as FieldErrors, in its fields detail, or nil if there are none.
+func (v *validator) err() error {
+	if len(v.fields) == 0 {
+		return nil
+	}
+	violations := make([]string, len(v.fields))
+	for i, field := range v.fields {
+		violations[i] = field.Field + ": " + field.Message
+	}
+	return errValidation("invalid %s input: %s", v.input, strings.Join(violations, "; ")).
+		with("input", v.input).
+		with("fields", v.fields)
+}
+
+func (v *validator) required(field string, value string) {
+	if strings.TrimSpace(value) == "" {
+		v.add(field, "must be a non-empty string")
+	}
+}
+
+func (v *validator) oneOf(field string, value string, allowed ...string) {
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+	v.add(field, "must be one of %s", strings.Join(allowed, ", "))
+}
+
+// amount checks that m is given and not negative, or with positive that it is
+// greater than zero. Whether it fits the currency is left to Money.minor.
+func (v *validator) amount(field string, m Money, positive bool) {
+	if m == "" {
+		v.add(field, "must be given")
+		return
+	}
+	value, ok := new(big.Rat).SetString(string(m))
+	if !ok {
+		v.add(field, "%q is not a decimal number", m)
+		return
+	}
+	if positive && value.Sign() <= 0 {
+		v.add(field, "must be greater than zero")
+	} else if value.Sign() < 0 {
+		v.add(field, "must not be negative")
+	}
+}
+
+func (v *validator) currency(field string, currency string) {
+	if _, err := currencyExponent(currency); err != nil {
+		v.add(field, "%v", err)
+	}
+}
+
+// date checks that an optional date parses with parseDate.
+func (v *validator) date(field string, value string) {
+	if value == "" {
+		return
+	}
+	if _, err := parseDate(value); err != nil {
+		v.add(field, "%v", err)
+	}
+}
+
+// dateOrder checks that the date in field end isn't before the one in start.
+// Unparseable dates are reported by date.
+func (v *validator) dateOrder(start string, startValue string, end string, endValue string) {
+	if startValue == "" || endValue == "" {
+		return
+	}
+	startDate, err := parseDate(startValue)
+	if err != nil {
+		return
+	}
+	endDate, err := parseDate(endValue)
+	if err != nil {
+		return
+	}
+	if endDate.Before(startDate) {
+		v.add(end, "must not be before %s", start)
+	}
+}
+
+// notGiven reports a field the contract maintains itself if the input gives it.
+func (v *validator) notGiven(field string, given bool) {
+	if given {
+		v.add(field, "is maintained by the contract and can't be given")
+	}
+}
+
+func (v *validator) percentage(field string, value float64) {
+	if value < 0 || value > 100 {
+		v.add(field, "must be between 0 and 100")
+	}
+}
+
+// benefits checks a list of benefit lines: every line is named, no name is
+// used twice, and the amounts are valid.
+func (v *validator) benefits(field string, benefits []Benefit, positive bool) {
+	seen := map[string]bool{}
+	for i, benefit := range benefits {
+		line := fmt.Sprintf("%s[%d]", field, i)
+		v.required(line+".benefit", benefit.Benefit)
+		if benefit.Benefit != "" {
+			if seen[benefit.Benefit] {
+				v.add(line+".benefit", "duplicate benefit %q", benefit.Benefit)
+			}
+			seen[benefit.Benefit] = true
+		}
+		v.amount(line+".amount", benefit.Amount, positive)
+	}
+}
+
+// awardee checks the details every awardee must be given.
+func (v *validator) awardee(field string, awardee *Awardee) {
+	v.required(field+".id", awardee.ID)
+	v.required(field+".name", awardee.Name)
+	v.required(field+".principal_investigator", awardee.Principal_Investigator)
+	v.required(field+".organization", awardee.Organization)
+	v.required(field+".contact", awardee.Contact)
+	v.required(field+".account_number", awardee.Account_Number)
+}
+
+// budget checks the amount, benefit lines and funder shares of a grant.
+func (v *validator) budget(grant *Grant) {
+	v.amount("amount", grant.Amount, false)
+	v.benefits("benefit", grant.Benefit, false)
+
+	seen := map[string]bool{}
+	for i, funder := range grant.Funder {
+		field := fmt.Sprintf("funder[%d]", i)
+		v.required(field+".funder_id", funder.Funder_ID)
+		if funder.Funder_ID != "" {
+			if seen[funder.Funder_ID] {
+				v.add(field+".funder_id", "duplicate funder %q", funder.Funder_ID)
+			}
+			seen[funder.Funder_ID] = true
+		}
+		v.required(field+".msp", funder.MSP)
+		v.amount(field+".amount", funder.Amount, false)
+		v.benefits(field+".benefit", funder.Benefit, false)
+	}
+}
+
+// period checks the start and end dates and closeout days of a grant.
+func (v *validator) period(grant *Grant) {
+	v.date("start_date", grant.Start_Date)
+	v.date("end_date", grant.End_Date)
+	v.dateOrder("start_date", grant.Start_Date, "end_date", grant.End_Date)
+	if grant.Closeout_Days < 0 {
+		v.add("closeout_days", "must not be negative")
+	}
+}
+
+// readTransientInput decodes the JSON value of key in the transient map into
+// input and checks it against the rules of its type.
+func readTransientInput(transientMap map[string][]byte, key string, input validatable) error {
+	value, ok := transientMap[key]
+	if !ok {
+		return errValidation("%s not found in the transient map input", key).with("input", key)
+	}
+	err := json.Unmarshal(value, input)
+	if err != nil {
+		return errValidation("failed to unmarshal JSON: %v", err)
+	}
+
+	v := validator{input: key}
+	input.validate(&v)
+	return v.err()
+}
+
+// validate checks a new grant. The currency defaults to USD when not given.
+// Awardees, payments, progress, amendments and the approval policy are added
+// by their own transactions once the grant exists, so a new grant can't carry
+// them.
+func (grant *Grant) validate(v *validator) {
+	v.required("ID", grant.ID)
+	v.notGiven("awardee", len(grant.Awardee) != 0)
+	v.notGiven("payment", len(grant.Payment) != 0)
+	v.notGiven("progress", len(grant.Progress) != 0)
+	v.notGiven("amendments", len(grant.Amendments) != 0)
+	v.notGiven("budget_version", grant.Budget_Version != 0)
+	v.notGiven("redacted", grant.Redacted)
+	if grant.Approval_Policy != nil {
+		v.add("approval_policy", "is set with SetApprovalPolicy once the grant exists")
+	}
+	if grant.Currency != "" {
+		v.currency("currency", grant.Currency)
+	}
+	v.budget(grant)
+	v.period(grant)
+	v.required("start_date", grant.Start_Date)
+	v.required("end_date", grant.End_Date)
+	v.percentage("sub", grant.Sub)
+	v.percentage("rebudget_threshold", grant.Rebudget_Threshold)
+}
+
+// grantUpdateTransientInput is the new budget of a grant. Only its amount,
+// benefit lines and funders are taken over, and while it is a Draft its
+// period of performance.
+type grantUpdateTransientInput Grant
+
+func (input *grantUpdateTransientInput) validate(v *validator) {
+	v.required("ID", input.ID)
+	v.budget((*Grant)(input))
+	v.period((*Grant)(input))
+}
+
+type assignTransientInput struct {
+	Grant_ID string    `json:"grant_id"`
+	Awardee  []Awardee `json:"awardee"`
+}
+
+func (input *assignTransientInput) validate(v *validator) {
+	v.required("grant_id", input.Grant_ID)
+	if len(input.Awardee) == 0 {
+		v.add("awardee", "must list at least one awardee")
+	}
+	seen := map[string]bool{}
+	for i := range input.Awardee {
+		awardee := &input.Awardee[i]
+		field := fmt.Sprintf("awardee[%d]", i)
+		v.awardee(field, awardee)
+		if awardee.ID != "" {
+			if seen[awardee.ID] {
+				v.add(field+".id", "duplicate awardee %q", awardee.ID)
+			}
+			seen[awardee.ID] = true
+		}
+		v.oneOf(field+".awardee_type", awardee.Awardee_Type, "Main", "Sub")
+	}
+}
+
+type reimbursementTransientInput struct {
+	ID         string    `json:"ID"`
+	Grant_ID   string    `json:"grant_id"`
+	Awardee_ID string    `json:"awardee_id"`
+	Date       string    `json:"date"`
+	Funder_ID  string    `json:"funder_id"`
+	Notes      string    `json:"notes"`
+	Item       []Benefit `json:"item"`
+}
+
+func (input *reimbursementTransientInput) validate(v *validator) {
+	v.required("ID", input.ID)
+	v.required("grant_id", input.Grant_ID)
+	v.required("awardee_id", input.Awardee_ID)
+	v.date("date", input.Date)
+	if len(input.Item) == 0 {
+		v.add("item", "must list at least one item")
+	}
+	v.benefits("item", input.Item, true)
+}
+
+// awardeeTransientInput adds a main awardee; its awardee_type is set by AddAwardee.
+type awardeeTransientInput struct {
+	Grant_ID string  `json:"grant_id"`
+	Awardee  Awardee `json:"awardee"`
+}
+
+func (input *awardeeTransientInput) validate(v *validator) {
+	v.required("grant_id", input.Grant_ID)
+	v.awardee("awardee", &input.Awardee)
+}
+
+// subAwardeeTransientInput adds a subawardee; its awardee_type is set by AddSubawardee.
+type subAwardeeTransientInput struct {
+	Grant_ID   string  `json:"grant_id"`
+	Awardee_ID string  `json:"awardee_id"`
+	Awardee    Awardee `json:"awardee"`
+}
+
+func (input *subAwardeeTransientInput) validate(v *validator) {
+	v.required("grant_id", input.Grant_ID)
+	v.awardee("awardee", &input.Awardee)
+}
+
+type extensionTransientInput struct {
+	Grant_ID      string `json:"grant_id"`
+	End_Date      string `json:"end_date"`
+	Justification string `json:"justification"`
+}
+
+func (input *extensionTransientInput) validate(v *validator) {
+	v.required("grant_id", input.Grant_ID)
+	v.required("end_date", input.End_Date)
+	v.date("end_date", input.End_Date)
+	v.required("justification", input.Justification)
+}
+
+// amendmentTransientInput proposes a new budget for a grant. Funder shares
+// are kept unless new ones are given.
+type amendmentTransientInput struct {
+	Grant_ID string    `json:"grant_id"`
+	Reason   string    `json:"reason"`
+	Amount   Money     `json:"amount"`
+	Benefit  []Benefit `json:"benefit"`
+	Funder   []Funder  `json:"funder"`
+}
+
+func (input *amendmentTransientInput) validate(v *validator) {
+	v.required("grant_id", input.Grant_ID)
+	v.required("reason", input.Reason)
+	v.budget(&Grant{Amount: input.Amount, Benefit: input.Benefit, Funder: input.Funder})
+}
+
+type rebudgetTransientInput struct {
+	Grant_ID      string     `json:"grant_id"`
+	Transfer      []Transfer `json:"transfer"`
+	Justification string     `json:"justification"`
+}
+
+func (input *rebudgetTransientInput) validate(v *validator) {
+	v.required("grant_id", input.Grant_ID)
+	v.required("justification", input.Justification)
+	if len(input.Transfer) == 0 {
+		v.add("transfer", "must list at least one transfer")
+	}
+	for i, transfer := range input.Transfer {
+		field := fmt.Sprintf("transfer[%d]", i)
+		v.required(field+".from", transfer.From)
+		v.required(field+".to", transfer.To)
+		if transfer.From != "" && transfer.From == transfer.To {
+			v.add(field+".to", "must differ from from")
+		}
+		v.amount(field+".amount", transfer.Amount, true)
+	}
+}
+
+type progressTransientInput struct {
+	Grant_ID string   `json:"grant_id"`
+	Progress Progress `json:"progress"`
+}
+
+func (input *progressTransientInput) validate(v *validator) {
+	v.required("grant_id", input.Grant_ID)
+	v.required("progress.notes", input.Progress.Notes)
+
+	// The percentage complete is optional and may carry a % sign
+	if input.Progress.Percentage != "" {
+		value, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(input.Progress.Percentage), "%"), 64)
+		if err != nil {
+			v.add("progress.percentage", "%q is not a number", input.Progress.Percentage)
+		} else {
+			v.percentage("progress.percentage", value)
+		}
+	}
+}
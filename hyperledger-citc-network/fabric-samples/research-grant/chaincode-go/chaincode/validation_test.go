@@ -0,0 +1,120 @@
+package chaincode
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// wantFields fails the test unless err is a validation error naming every field.
+func wantFields(t *testing.T, err error, fields ...string) {
+	t.Helper()
+	wantCode(t, err, CodeValidation)
+	violations, _ := err.(*ContractError).Details["fields"].([]FieldError)
+	got := map[string]bool{}
+	for _, field := range violations {
+		got[field.Field] = true
+	}
+	for _, field := range fields {
+		if !got[field] {
+			t.Errorf("no violation of %s in %v", field, violations)
+		}
+	}
+}
+
+func TestInitiateGrantValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(grant map[string]interface{})
+		fields []string
+	}{
+		{name: "valid", change: func(grant map[string]interface{}) {}},
+		{name: "missing ID and dates", change: func(grant map[string]interface{}) {
+			delete(grant, "ID")
+			delete(grant, "start_date")
+			delete(grant, "end_date")
+		}, fields: []string{"ID", "start_date", "end_date"}},
+		{name: "negative amount", change: func(grant map[string]interface{}) {
+			grant["amount"] = "-5"
+		}, fields: []string{"amount"}},
+		{name: "unknown currency", change: func(grant map[string]interface{}) {
+			grant["currency"] = "XXX"
+		}, fields: []string{"currency"}},
+		{name: "duplicate benefit", change: func(grant map[string]interface{}) {
+			grant["benefit"] = []map[string]interface{}{item("Travel", "500"), item("Travel", "500")}
+		}, fields: []string{"benefit[1].benefit"}},
+		{name: "end before start", change: func(grant map[string]interface{}) {
+			grant["end_date"] = "2021-12-31"
+		}, fields: []string{"end_date"}},
+		{name: "server-owned fields", change: func(grant map[string]interface{}) {
+			grant["awardee"] = []map[string]interface{}{testAwardee("bob", "Main", AwardeeMSP)}
+			grant["payment"] = []map[string]interface{}{{"ID": "P1", "status": "Accept_redeem", "total": "1000000"}}
+			grant["progress"] = []map[string]interface{}{{"notes": "done"}}
+			grant["amendments"] = []map[string]interface{}{{"number": 1}}
+			grant["budget_version"] = 3
+			grant["approval_policy"] = map[string]interface{}{"threshold": "0", "approvals": 1}
+			grant["redacted"] = true
+		}, fields: []string{"awardee", "payment", "progress", "amendments", "budget_version", "redacted", "approval_policy"}},
+		{name: "several violations", change: func(grant map[string]interface{}) {
+			grant["sub"] = 120
+			grant["start_date"] = "someday"
+			grant["benefit"] = []map[string]interface{}{item("", "1000")}
+		}, fields: []string{"sub", "start_date", "benefit[0].benefit"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			l := newLedger(t)
+			grant := newGrant("G1")
+			test.change(grant)
+			err := l.initiate(grant)
+			if len(test.fields) == 0 {
+				l.must(err)
+				return
+			}
+			wantFields(t, err, test.fields...)
+			if _, err := l.read(grantor, "G1"); err == nil {
+				t.Errorf("the invalid grant was stored")
+			}
+		})
+	}
+}
+
+func TestAssignGrantValidation(t *testing.T) {
+	noContact := testAwardee("bob", "Main", AwardeeMSP)
+	delete(noContact, "contact")
+
+	tests := []struct {
+		name     string
+		awardees []map[string]interface{}
+		fields   []string
+	}{
+		{name: "no awardees", fields: []string{"awardee"}},
+		{name: "missing contact", awardees: []map[string]interface{}{noContact}, fields: []string{"awardee[0].contact"}},
+		{name: "unknown type", awardees: []map[string]interface{}{testAwardee("bob", "Lead", AwardeeMSP)}, fields: []string{"awardee[0].awardee_type"}},
+		{name: "listed twice", awardees: []map[string]interface{}{testAwardee("bob", "Main", AwardeeMSP), testAwardee("bob", "Main", AwardeeMSP)}, fields: []string{"awardee[1].id"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			l := newLedger(t)
+			l.must(l.initiate(newGrant("G1")))
+			wantFields(t, l.assign("G1", test.awardees...), test.fields...)
+		})
+	}
+}
+
+func TestReimbursementValidation(t *testing.T) {
+	l := newLedger(t)
+	l.activeGrant("G1")
+
+	_, err := l.reimburse(awardee, "G1", "", "AwardeeMSP/bob", item("Personnel", "0"), item("", "-1"))
+	wantFields(t, err, "ID", "item[0].amount", "item[1].benefit", "item[1].amount")
+}
+
+func TestInitiateGrantRejectsMalformedJSON(t *testing.T) {
+	l := newLedger(t)
+	err := l.run(grantor, map[string]interface{}{"grant": "{not json"}, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.InitiateGrant(ctx)
+		return err
+	})
+	wantCode(t, err, CodeValidation)
+}
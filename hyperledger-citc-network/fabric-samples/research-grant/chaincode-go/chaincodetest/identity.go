@@ -0,0 +1,89 @@
+package chaincodetest
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/hyperledger/fabric-chaincode-go/pkg/cid"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Identity is a fake cid.ClientIdentity. Its certificate is not signed; it
+// only carries the subject and issuer the contract reads.
+type Identity struct {
+	MSPID       string
+	Certificate *x509.Certificate
+	Attributes  map[string]string
+}
+
+// NewIdentity returns an identity of mspID whose certificate has the given
+// common name and organizational units. Like Fabric CA, it carries the
+// hf.EnrollmentID attribute set to the common name.
+func NewIdentity(mspID string, commonName string, organizationalUnits ...string) *Identity {
+	return &Identity{
+		MSPID: mspID,
+		Certificate: &x509.Certificate{
+			Subject: pkix.Name{
+				CommonName:         commonName,
+				OrganizationalUnit: organizationalUnits,
+			},
+			Issuer: pkix.Name{
+				CommonName: "ca." + mspID,
+			},
+		},
+		Attributes: map[string]string{
+			"hf.EnrollmentID": commonName,
+		},
+	}
+}
+
+// WithAttribute sets a certificate attribute and returns the identity.
+func (i *Identity) WithAttribute(name string, value string) *Identity {
+	i.Attributes[name] = value
+	return i
+}
+
+// GetID returns the identity in the format cid uses, base64 of x509::subject::issuer.
+func (i *Identity) GetID() (string, error) {
+	if i.Certificate == nil {
+		return "", fmt.Errorf("identity has no certificate")
+	}
+	id := fmt.Sprintf("x509::%s::%s", i.Certificate.Subject.String(), i.Certificate.Issuer.String())
+	return base64.StdEncoding.EncodeToString([]byte(id)), nil
+}
+
+func (i *Identity) GetMSPID() (string, error) {
+	return i.MSPID, nil
+}
+
+func (i *Identity) GetAttributeValue(attrName string) (string, bool, error) {
+	value, found := i.Attributes[attrName]
+	return value, found, nil
+}
+
+func (i *Identity) AssertAttributeValue(attrName string, attrValue string) error {
+	value, found := i.Attributes[attrName]
+	if !found {
+		return fmt.Errorf("attribute '%s' was not found", attrName)
+	}
+	if value != attrValue {
+		return fmt.Errorf("attribute '%s' equals '%s', not '%s'", attrName, value, attrValue)
+	}
+	return nil
+}
+
+func (i *Identity) GetX509Certificate() (*x509.Certificate, error) {
+	return i.Certificate, nil
+}
+
+var _ cid.ClientIdentity = (*Identity)(nil)
+
+// NewContext returns a transaction context that calls the contract as identity on stub.
+func NewContext(stub *Stub, identity *Identity) *contractapi.TransactionContext {
+	ctx := new(contractapi.TransactionContext)
+	ctx.SetStub(stub)
+	ctx.SetClientIdentity(identity)
+	return ctx
+}
@@ -0,0 +1,434 @@
+// Package chaincodetest provides an in-memory fake of the Fabric transaction
+// context, chaincode stub and client identity, for exercising the research
+// grant contract without a network.
+//
+// A Stub behaves like a peer backed by LevelDB: reads see committed state
+// only, writes are buffered until the transaction commits, every committed
+// write is kept for GetHistoryForKey and rich queries are not supported.
+//
+//	stub := chaincodetest.NewStub()
+//	grantor := chaincodetest.NewIdentity("GrantorMSP", "grantor1")
+//	ctx := chaincodetest.NewContext(stub, grantor)
+//	err := stub.Run(map[string][]byte{"grant": grantJSON}, func() error {
+//		_, err := contract.InitiateGrant(ctx)
+//		return err
+//	})
+package chaincodetest
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+	"github.com/hyperledger/fabric-protos-go/peer"
+)
+
+// ErrNoTransaction is returned by calls that need a transaction outside of one.
+var ErrNoTransaction = errors.New("no transaction in progress, call Begin or Run first")
+
+// ErrRichQueryUnsupported is returned by the CouchDB rich query calls, as on a LevelDB peer.
+var ErrRichQueryUnsupported = errors.New("ExecuteQuery not supported for leveldb")
+
+// Stub is an in-memory shim.ChaincodeStubInterface. Calls it does not
+// implement panic through the embedded nil interface.
+type Stub struct {
+	shim.ChaincodeStubInterface
+
+	ChannelID string
+
+	// Clock is the timestamp of the last transaction; each new transaction is one second later.
+	Clock time.Time
+
+	// Events holds the event of every committed transaction that set one, in commit order.
+	Events []*peer.ChaincodeEvent
+
+	// Queries holds every rich query the chaincode ran, in call order.
+	Queries []string
+
+	state      map[string][]byte
+	private    map[string]map[string][]byte
+	validation map[string][]byte
+	history    map[string][]*queryresult.KeyModification
+	txCount    int
+	tx         *transaction
+}
+
+// transaction buffers what a transaction writes until it commits. A nil value is a delete.
+type transaction struct {
+	id         string
+	timestamp  time.Time
+	transient  map[string][]byte
+	writes     map[string][]byte
+	private    map[string]map[string][]byte
+	validation map[string][]byte
+	event      *peer.ChaincodeEvent
+}
+
+// NewStub returns an empty ledger on the channel researchchannel.
+func NewStub() *Stub {
+	return &Stub{
+		ChannelID:  "researchchannel",
+		Clock:      time.Date(2022, time.January, 1, 0, 0, 0, 0, time.UTC),
+		state:      map[string][]byte{},
+		private:    map[string]map[string][]byte{},
+		validation: map[string][]byte{},
+		history:    map[string][]*queryresult.KeyModification{},
+	}
+}
+
+// Begin starts a transaction with the given transient map.
+func (s *Stub) Begin(transient map[string][]byte) {
+	s.txCount++
+	s.Clock = s.Clock.Add(time.Second)
+	s.tx = &transaction{
+		id:         fmt.Sprintf("tx%d", s.txCount),
+		timestamp:  s.Clock,
+		transient:  transient,
+		writes:     map[string][]byte{},
+		private:    map[string]map[string][]byte{},
+		validation: map[string][]byte{},
+	}
+}
+
+// Commit applies the writes of the current transaction and records them in the key history.
+func (s *Stub) Commit() error {
+	if s.tx == nil {
+		return ErrNoTransaction
+	}
+
+	ts := &timestamp.Timestamp{Seconds: s.tx.timestamp.Unix(), Nanos: int32(s.tx.timestamp.Nanosecond())}
+	for _, key := range sortedKeys(s.tx.writes) {
+		value := s.tx.writes[key]
+		if value == nil {
+			delete(s.state, key)
+		} else {
+			s.state[key] = value
+		}
+		s.history[key] = append(s.history[key], &queryresult.KeyModification{
+			TxId:      s.tx.id,
+			Value:     value,
+			Timestamp: ts,
+			IsDelete:  value == nil,
+		})
+	}
+	for collection, writes := range s.tx.private {
+		if s.private[collection] == nil {
+			s.private[collection] = map[string][]byte{}
+		}
+		for key, value := range writes {
+			if value == nil {
+				delete(s.private[collection], key)
+			} else {
+				s.private[collection][key] = value
+			}
+		}
+	}
+	for key, policy := range s.tx.validation {
+		s.validation[key] = policy
+	}
+	if s.tx.event != nil {
+		s.Events = append(s.Events, s.tx.event)
+	}
+
+	s.tx = nil
+	return nil
+}
+
+// Rollback discards the current transaction.
+func (s *Stub) Rollback() {
+	s.tx = nil
+}
+
+// Run executes fn as one transaction and commits it unless fn fails.
+func (s *Stub) Run(transient map[string][]byte, fn func() error) error {
+	s.Begin(transient)
+	if err := fn(); err != nil {
+		s.Rollback()
+		return err
+	}
+	return s.Commit()
+}
+
+// LastEvent returns the event of the latest committed transaction that set one.
+func (s *Stub) LastEvent() *peer.ChaincodeEvent {
+	if len(s.Events) == 0 {
+		return nil
+	}
+	return s.Events[len(s.Events)-1]
+}
+
+// State returns the committed value of key.
+func (s *Stub) State(key string) []byte {
+	return s.state[key]
+}
+
+// PrivateState returns the committed value of key in collection.
+func (s *Stub) PrivateState(collection string, key string) []byte {
+	return s.private[collection][key]
+}
+
+func (s *Stub) GetTxID() string {
+	if s.tx == nil {
+		return ""
+	}
+	return s.tx.id
+}
+
+func (s *Stub) GetChannelID() string {
+	return s.ChannelID
+}
+
+func (s *Stub) GetTxTimestamp() (*timestamp.Timestamp, error) {
+	if s.tx == nil {
+		return nil, ErrNoTransaction
+	}
+	return &timestamp.Timestamp{Seconds: s.tx.timestamp.Unix(), Nanos: int32(s.tx.timestamp.Nanosecond())}, nil
+}
+
+func (s *Stub) GetTransient() (map[string][]byte, error) {
+	if s.tx == nil {
+		return nil, ErrNoTransaction
+	}
+	return s.tx.transient, nil
+}
+
+func (s *Stub) GetState(key string) ([]byte, error) {
+	return s.state[key], nil
+}
+
+func (s *Stub) PutState(key string, value []byte) error {
+	if s.tx == nil {
+		return ErrNoTransaction
+	}
+	if key == "" {
+		return errors.New("key must not be an empty string")
+	}
+	if value == nil {
+		value = []byte{}
+	}
+	s.tx.writes[key] = value
+	return nil
+}
+
+func (s *Stub) DelState(key string) error {
+	if s.tx == nil {
+		return ErrNoTransaction
+	}
+	s.tx.writes[key] = nil
+	return nil
+}
+
+func (s *Stub) CreateCompositeKey(objectType string, attributes []string) (string, error) {
+	return shim.CreateCompositeKey(objectType, attributes)
+}
+
+func (s *Stub) SplitCompositeKey(compositeKey string) (string, []string, error) {
+	if !strings.HasPrefix(compositeKey, "\x00") {
+		return "", nil, fmt.Errorf("%q is not a composite key", compositeKey)
+	}
+	components := strings.Split(strings.TrimSuffix(compositeKey[1:], "\x00"), "\x00")
+	return components[0], components[1:], nil
+}
+
+func (s *Stub) GetStateByRange(startKey string, endKey string) (shim.StateQueryIteratorInterface, error) {
+	return &stateIterator{results: s.rangeKVs(startKey, endKey, "", 0)}, nil
+}
+
+func (s *Stub) GetStateByPartialCompositeKey(objectType string, attributes []string) (shim.StateQueryIteratorInterface, error) {
+	startKey, err := shim.CreateCompositeKey(objectType, attributes)
+	if err != nil {
+		return nil, err
+	}
+	return &stateIterator{results: s.rangeKVs(startKey, startKey+string(utf8.MaxRune), "", 0)}, nil
+}
+
+func (s *Stub) GetStateByRangeWithPagination(startKey string, endKey string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *peer.QueryResponseMetadata, error) {
+	return s.paginate(startKey, endKey, pageSize, bookmark)
+}
+
+func (s *Stub) GetStateByPartialCompositeKeyWithPagination(objectType string, keys []string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *peer.QueryResponseMetadata, error) {
+	startKey, err := shim.CreateCompositeKey(objectType, keys)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.paginate(startKey, startKey+string(utf8.MaxRune), pageSize, bookmark)
+}
+
+// paginate returns up to pageSize records from bookmark on. As on a peer, the
+// returned bookmark is the key the next page starts at, empty after the last page.
+func (s *Stub) paginate(startKey string, endKey string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *peer.QueryResponseMetadata, error) {
+	if pageSize <= 0 {
+		return nil, nil, fmt.Errorf("page size must be positive, got %d", pageSize)
+	}
+
+	results := s.rangeKVs(startKey, endKey, bookmark, int(pageSize)+1)
+	metadata := &peer.QueryResponseMetadata{}
+	if len(results) > int(pageSize) {
+		metadata.Bookmark = results[pageSize].Key
+		results = results[:pageSize]
+	}
+	metadata.FetchedRecordsCount = int32(len(results))
+	return &stateIterator{results: results}, metadata, nil
+}
+
+// rangeKVs returns the committed records in [startKey, endKey), starting at
+// from when it is set, at most limit of them when limit is positive.
+func (s *Stub) rangeKVs(startKey string, endKey string, from string, limit int) []*queryresult.KV {
+	if from > startKey {
+		startKey = from
+	}
+
+	var results []*queryresult.KV
+	for _, key := range sortedKeys(s.state) {
+		if key < startKey || (endKey != "" && key >= endKey) {
+			continue
+		}
+		results = append(results, &queryresult.KV{Key: key, Value: s.state[key]})
+		if limit > 0 && len(results) == limit {
+			break
+		}
+	}
+	return results
+}
+
+func (s *Stub) GetQueryResult(query string) (shim.StateQueryIteratorInterface, error) {
+	s.Queries = append(s.Queries, query)
+	return nil, ErrRichQueryUnsupported
+}
+
+func (s *Stub) GetQueryResultWithPagination(query string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *peer.QueryResponseMetadata, error) {
+	s.Queries = append(s.Queries, query)
+	return nil, nil, ErrRichQueryUnsupported
+}
+
+func (s *Stub) GetHistoryForKey(key string) (shim.HistoryQueryIteratorInterface, error) {
+	// Peers return the newest modification first
+	modifications := s.history[key]
+	results := make([]*queryresult.KeyModification, 0, len(modifications))
+	for i := len(modifications) - 1; i >= 0; i-- {
+		results = append(results, modifications[i])
+	}
+	return &historyIterator{results: results}, nil
+}
+
+func (s *Stub) GetPrivateData(collection string, key string) ([]byte, error) {
+	return s.private[collection][key], nil
+}
+
+func (s *Stub) GetPrivateDataHash(collection string, key string) ([]byte, error) {
+	value := s.private[collection][key]
+	if value == nil {
+		return nil, nil
+	}
+	hash := sha256.Sum256(value)
+	return hash[:], nil
+}
+
+func (s *Stub) PutPrivateData(collection string, key string, value []byte) error {
+	if s.tx == nil {
+		return ErrNoTransaction
+	}
+	if key == "" {
+		return errors.New("key must not be an empty string")
+	}
+	if value == nil {
+		value = []byte{}
+	}
+	if s.tx.private[collection] == nil {
+		s.tx.private[collection] = map[string][]byte{}
+	}
+	s.tx.private[collection][key] = value
+	return nil
+}
+
+func (s *Stub) DelPrivateData(collection string, key string) error {
+	if s.tx == nil {
+		return ErrNoTransaction
+	}
+	if s.tx.private[collection] == nil {
+		s.tx.private[collection] = map[string][]byte{}
+	}
+	s.tx.private[collection][key] = nil
+	return nil
+}
+
+func (s *Stub) SetStateValidationParameter(key string, ep []byte) error {
+	if s.tx == nil {
+		return ErrNoTransaction
+	}
+	s.tx.validation[key] = ep
+	return nil
+}
+
+func (s *Stub) GetStateValidationParameter(key string) ([]byte, error) {
+	return s.validation[key], nil
+}
+
+func (s *Stub) SetEvent(name string, payload []byte) error {
+	if s.tx == nil {
+		return ErrNoTransaction
+	}
+	if name == "" {
+		return errors.New("event name can not be empty string")
+	}
+	s.tx.event = &peer.ChaincodeEvent{EventName: name, Payload: payload, TxId: s.tx.id}
+	return nil
+}
+
+func sortedKeys(values map[string][]byte) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+type stateIterator struct {
+	results []*queryresult.KV
+	next    int
+}
+
+func (it *stateIterator) HasNext() bool {
+	return it.next < len(it.results)
+}
+
+func (it *stateIterator) Next() (*queryresult.KV, error) {
+	if !it.HasNext() {
+		return nil, errors.New("no more results")
+	}
+	it.next++
+	return it.results[it.next-1], nil
+}
+
+func (it *stateIterator) Close() error {
+	return nil
+}
+
+type historyIterator struct {
+	results []*queryresult.KeyModification
+	next    int
+}
+
+func (it *historyIterator) HasNext() bool {
+	return it.next < len(it.results)
+}
+
+func (it *historyIterator) Next() (*queryresult.KeyModification, error) {
+	if !it.HasNext() {
+		return nil, errors.New("no more results")
+	}
+	it.next++
+	return it.results[it.next-1], nil
+}
+
+func (it *historyIterator) Close() error {
+	return nil
+}
@@ -3,7 +3,8 @@ module chaincode-go
 go 1.14
 
 require (
-	github.com/golang/protobuf v1.3.2 // indirect
-	github.com/hyperledger/fabric-chaincode-go v0.0.0-20200128192331-2d899240a7ed // indirect
+	github.com/golang/protobuf v1.3.2
+	github.com/hyperledger/fabric-chaincode-go v0.0.0-20200128192331-2d899240a7ed
 	github.com/hyperledger/fabric-contract-api-go v1.0.0
+	github.com/hyperledger/fabric-protos-go v0.0.0-20200124220212-e9cfc186ba7b
 )